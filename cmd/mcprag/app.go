@@ -3,11 +3,13 @@ package main
 import (
 	"context"
 	"fmt"
+	"path/filepath"
 
 	"github.com/PerceptivePenguin/MCPRAG-Go/internal/agent"
 	"github.com/PerceptivePenguin/MCPRAG-Go/internal/chat"
 	"github.com/PerceptivePenguin/MCPRAG-Go/internal/mcp"
 	"github.com/PerceptivePenguin/MCPRAG-Go/internal/rag"
+	"github.com/PerceptivePenguin/MCPRAG-Go/internal/vector"
 )
 
 // App 应用主结构
@@ -58,14 +60,27 @@ func NewApp(config *Config) (*App, error) {
 func (app *App) Run(ctx context.Context) error {
 	fmt.Printf("正在启动 %s v%s...\n", appName, appVersion)
 	
-	// 启动Agent
+	// 启动Agent；启动失败时同样需要 Stop 关闭索引
+	defer func() {
+		if err := app.agent.Stop(); err != nil {
+			fmt.Printf("关闭 Agent 失败: %v\n", err)
+		}
+	}()
 	if err := app.agent.Start(ctx); err != nil {
 		return fmt.Errorf("failed to start agent: %w", err)
 	}
-	defer app.agent.Stop()
 	
 	fmt.Println("Agent 启动成功")
 	
+	// 监视知识库目录，编辑文档时保持索引最新
+	if len(app.config.WatchDirs) > 0 {
+		watcher, err := app.startWatcher(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to watch directories: %w", err)
+		}
+		defer watcher.Stop()
+	}
+	
 	if app.config.Interactive {
 		return app.runInteractive(ctx)
 	}
@@ -81,6 +96,51 @@ func (app *App) runInteractive(ctx context.Context) error {
 	return interactive.Run(ctx)
 }
 
+// startWatcher 导入监视目录并在后台跟踪文件的创建、修改和删除
+func (app *App) startWatcher(ctx context.Context) (*rag.Watcher, error) {
+	retriever, err := app.agent.GetIndexManager().Retriever(rag.DefaultIndexName)
+	if err != nil {
+		return nil, err
+	}
+	
+	// 文件向量存储与 ingest 子命令共用清单，内存存储每次启动全部导入
+	ingestConfig := rag.DefaultIngestConfig()
+	if app.config.VectorBackend == "file" {
+		ingestConfig.ManifestPath = filepath.Join(app.config.VectorPath, ingestManifestName)
+	}
+	ingester, err := rag.NewIngester(retriever, ingestConfig)
+	if err != nil {
+		return nil, err
+	}
+	
+	watchConfig := rag.DefaultWatchConfig()
+	watchConfig.Interval = app.config.WatchInterval
+	watcher, err := rag.NewWatcher(ingester, app.config.WatchDirs, watchConfig)
+	if err != nil {
+		return nil, err
+	}
+	
+	watcher.OnChange(func(root string, report *rag.IngestReport, err error) {
+		if err != nil {
+			fmt.Printf("\n[watch] %s: 索引更新失败: %v\n", root, err)
+			return
+		}
+		for _, failure := range report.Failed {
+			fmt.Printf("\n[watch] 无法导入 %v\n", failure)
+		}
+		if changed := len(report.Added) + len(report.Updated) + len(report.Deleted); changed > 0 || app.config.Verbose {
+			fmt.Printf("\n[watch] %s: 新增 %d，更新 %d，删除 %d\n", root, len(report.Added), len(report.Updated), len(report.Deleted))
+		}
+	})
+	
+	if err := watcher.Start(ctx); err != nil {
+		return nil, err
+	}
+	
+	fmt.Printf("正在监视 %d 个目录\n", len(app.config.WatchDirs))
+	return watcher, nil
+}
+
 // createChatConfig 创建Chat配置
 func createChatConfig(config *Config) chat.ClientConfig {
 	chatConfig := chat.DefaultClientConfig()
@@ -127,11 +187,43 @@ func configureMCPManager(manager *mcp.Manager, config *Config) error {
 // createRAGConfig 创建RAG配置
 func createRAGConfig(config *Config) rag.RetrieverConfig {
 	ragConfig := *rag.DefaultRetrieverConfig()
+	ragConfig.Embedding.Provider = rag.EmbeddingProvider(config.EmbeddingProvider)
 	ragConfig.Embedding.APIKey = config.OpenAIAPIKey
+	if config.EmbeddingAPIKey != "" {
+		ragConfig.Embedding.APIKey = config.EmbeddingAPIKey
+	}
 	
-	if config.BaseURL != "" {
+	// 聊天接口的地址只用于 OpenAI 嵌入
+	if config.EmbeddingURL != "" {
+		ragConfig.Embedding.BaseURL = config.EmbeddingURL
+	} else if config.BaseURL != "" && config.EmbeddingProvider == "openai" {
 		ragConfig.Embedding.BaseURL = config.BaseURL
 	}
+	if config.EmbeddingModel != "" {
+		ragConfig.Embedding.Model = config.EmbeddingModel
+	}
+	ragConfig.Embedding.Dimensions = config.EmbeddingDimensions
+	
+	// 维度已知时让向量存储与之一致
+	if config.EmbeddingDimensions > 0 {
+		ragConfig.VectorStore.Dimension = config.EmbeddingDimensions
+	}
+	
+	// 向量存储后端
+	ragConfig.VectorStore.Backend = vector.StoreBackend(config.VectorBackend)
+	ragConfig.VectorStore.Path = config.VectorPath
+	ragConfig.VectorStore.Metric = vector.Metric(config.VectorMetric)
+	ragConfig.VectorStore.Quantization.Type = vector.QuantizationType(config.VectorQuantize)
+	
+	// 磁盘嵌入缓存，重启后无需重新计算嵌入
+	if config.EmbeddingCacheDir != "" {
+		ragConfig.Cache.DiskPath = config.EmbeddingCacheDir
+	}
+	
+	// 分词器词表目录
+	if config.TokenizerDir != "" {
+		ragConfig.Tokenizer.Dir = config.TokenizerDir
+	}
 	
 	return ragConfig
 }
\ No newline at end of file
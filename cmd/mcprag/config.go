@@ -4,7 +4,9 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"time"
 
+	"github.com/PerceptivePenguin/MCPRAG-Go/internal/rag"
 	"github.com/PerceptivePenguin/MCPRAG-Go/pkg/errors"
 )
 
@@ -28,6 +30,23 @@ type Config struct {
 	// RAG 配置
 	EnableRAG        bool
 	RAGContextLength int
+	VectorBackend    string
+	VectorPath       string
+	VectorMetric     string
+	VectorQuantize   string
+	TokenizerDir     string
+	
+	// 嵌入模型配置
+	EmbeddingProvider   string
+	EmbeddingURL        string
+	EmbeddingModel      string
+	EmbeddingAPIKey     string
+	EmbeddingDimensions int
+	EmbeddingCacheDir   string
+	
+	// 知识库目录监视
+	WatchDirs     []string
+	WatchInterval time.Duration
 	
 	// 服务配置
 	Interactive bool
@@ -52,6 +71,15 @@ func DefaultConfig() *Config {
 		// RAG 默认配置
 		EnableRAG:        true,
 		RAGContextLength: 2048,
+		VectorBackend:    "memory",
+		VectorMetric:     "cosine",
+		VectorQuantize:   "none",
+		
+		// 嵌入模型默认配置
+		EmbeddingProvider: "openai",
+		
+		// 目录监视默认配置
+		WatchInterval: rag.DefaultWatchInterval,
 		
 		// 服务默认配置
 		Interactive: true,
@@ -71,7 +99,7 @@ func ParseFlags() (*Config, error) {
 	// Agent 配置
 	flag.StringVar(&config.SystemPrompt, "system-prompt", "", "Custom system prompt")
 	flag.IntVar(&config.MaxToolCalls, "max-tool-calls", config.MaxToolCalls, "Maximum tool calls per conversation")
-	flag.IntVar(&config.MaxContextLength, "max-context", config.MaxContextLength, "Maximum context length")
+	flag.IntVar(&config.MaxContextLength, "max-context", config.MaxContextLength, "Maximum context length in tokens")
 	
 	// MCP 配置
 	flag.BoolVar(&config.EnableSequentialThinking, "enable-sequential-thinking", config.EnableSequentialThinking, "Enable sequential thinking MCP server")
@@ -80,7 +108,12 @@ func ParseFlags() (*Config, error) {
 	
 	// RAG 配置
 	flag.BoolVar(&config.EnableRAG, "enable-rag", config.EnableRAG, "Enable RAG retrieval")
-	flag.IntVar(&config.RAGContextLength, "rag-context", config.RAGContextLength, "RAG context length")
+	flag.IntVar(&config.RAGContextLength, "rag-context", config.RAGContextLength, "RAG context length in tokens")
+	registerStoreFlags(flag.CommandLine, config)
+	
+	// 知识库目录监视
+	flag.Var((*listFlag)(&config.WatchDirs), "watch", "Directory to ingest and keep indexed while running, repeatable")
+	flag.DurationVar(&config.WatchInterval, "watch-interval", config.WatchInterval, "How often watched directories are checked for changes")
 	
 	// 服务配置
 	flag.BoolVar(&config.Interactive, "interactive", config.Interactive, "Run in interactive mode")
@@ -111,6 +144,23 @@ func ParseFlags() (*Config, error) {
 	return config, nil
 }
 
+// registerStoreFlags 注册向量存储和嵌入模型参数，主程序和 ingest 子命令共用
+func registerStoreFlags(fs *flag.FlagSet, config *Config) {
+	fs.StringVar(&config.VectorBackend, "vector-store", config.VectorBackend, "Vector store backend (memory, file)")
+	fs.StringVar(&config.VectorPath, "vector-path", config.VectorPath, "Directory for the file vector store")
+	fs.StringVar(&config.VectorMetric, "vector-metric", config.VectorMetric, "Vector similarity metric (cosine, dot, l2)")
+	fs.StringVar(&config.VectorQuantize, "vector-quantization", config.VectorQuantize, "Vector compression (none, int8, pq)")
+	fs.StringVar(&config.TokenizerDir, "tokenizer-dir", "", "Directory of BPE rank files such as cl100k_base.tiktoken")
+	
+	// 嵌入模型配置
+	fs.StringVar(&config.EmbeddingProvider, "embedding-provider", config.EmbeddingProvider, "Embedding provider (openai, openai_compatible, tei, ollama, cohere, hashing)")
+	fs.StringVar(&config.EmbeddingURL, "embedding-url", "", "Base URL of the embedding server (defaults to -base-url for openai)")
+	fs.StringVar(&config.EmbeddingModel, "embedding-model", "", "Embedding model to use")
+	fs.StringVar(&config.EmbeddingAPIKey, "embedding-api-key", os.Getenv("EMBEDDING_API_KEY"), "API key of the embedding provider (defaults to -api-key)")
+	fs.IntVar(&config.EmbeddingDimensions, "embedding-dimensions", 0, "Embedding vector dimension (0 to detect)")
+	fs.StringVar(&config.EmbeddingCacheDir, "embedding-cache-dir", "", "Directory of the on-disk embedding cache, kept across runs")
+}
+
 // Validate 验证配置
 func (c *Config) Validate() error {
 	// 验证必需的配置
@@ -131,6 +181,61 @@ func (c *Config) Validate() error {
 		return errors.ValidationError("rag_context_length", "RAG context length must be between 256 and 8192")
 	}
 	
+	if len(c.WatchDirs) > 0 {
+		if !c.EnableRAG {
+			return errors.ValidationError("watch", "watching directories requires RAG to be enabled")
+		}
+		if c.WatchInterval <= 0 {
+			return errors.ValidationError("watch_interval", "watch interval must be positive")
+		}
+		for _, dir := range c.WatchDirs {
+			if _, err := os.Stat(dir); err != nil {
+				return errors.ValidationError("watch", fmt.Sprintf("cannot watch %s: %v", dir, err))
+			}
+		}
+	}
+	
+	return c.validateStore()
+}
+
+// validateStore 验证向量存储和嵌入模型配置
+func (c *Config) validateStore() error {
+	switch c.VectorBackend {
+	case "memory":
+	case "file":
+		if c.VectorPath == "" {
+			return errors.ValidationError("vector_path", "vector path is required when using the file vector store")
+		}
+	default:
+		return errors.ValidationError("vector_store", "vector store must be one of: memory, file")
+	}
+	
+	switch c.VectorMetric {
+	case "cosine", "dot", "l2":
+	default:
+		return errors.ValidationError("vector_metric", "vector metric must be one of: cosine, dot, l2")
+	}
+	
+	switch c.VectorQuantize {
+	case "none", "int8", "pq":
+	default:
+		return errors.ValidationError("vector_quantization", "vector quantization must be one of: none, int8, pq")
+	}
+	
+	switch c.EmbeddingProvider {
+	case "openai", "openai_compatible", "ollama", "cohere", "hashing":
+	case "tei":
+		if c.EmbeddingURL == "" {
+			return errors.ValidationError("embedding_url", "embedding URL is required when using the tei embedding provider")
+		}
+	default:
+		return errors.ValidationError("embedding_provider", "embedding provider must be one of: openai, openai_compatible, tei, ollama, cohere, hashing")
+	}
+	
+	if c.EmbeddingDimensions < 0 {
+		return errors.ValidationError("embedding_dimensions", "embedding dimensions must not be negative")
+	}
+	
 	return nil
 }
 
@@ -141,7 +246,9 @@ func showVersion() {
 
 // showHelp 显示帮助信息
 func showHelp() {
-	fmt.Printf("Usage: %s [options]\n\n", appName)
+	fmt.Printf("Usage: %s [options]\n", appName)
+	fmt.Printf("       %s snapshot <dump|load|info> [options]\n", appName)
+	fmt.Printf("       %s ingest [options] <path>...\n\n", appName)
 	fmt.Println("MCPRAG - A high-performance LLM system with MCP and RAG capabilities")
 	fmt.Println()
 	fmt.Println("Options:")
@@ -151,7 +258,12 @@ func showHelp() {
 	fmt.Printf("  %s -api-key YOUR_API_KEY\n", appName)
 	fmt.Printf("  %s -model gpt-4o-mini -verbose\n", appName)
 	fmt.Printf("  %s -enable-rag=false -interactive=false\n", appName)
+	fmt.Printf("  %s -vector-store file -vector-path ./data/vectors\n", appName)
+	fmt.Printf("  %s -vector-store file -vector-path ./data/vectors -watch ./docs\n", appName)
+	fmt.Printf("  %s -embedding-provider tei -embedding-url http://localhost:8080 -embedding-dimensions 768\n", appName)
 	fmt.Println()
 	fmt.Println("Environment Variables:")
-	fmt.Println("  OPENAI_API_KEY    OpenAI API key (alternative to -api-key)")
+	fmt.Println("  OPENAI_API_KEY        OpenAI API key (alternative to -api-key)")
+	fmt.Println("  EMBEDDING_API_KEY     Embedding provider API key (alternative to -embedding-api-key)")
+	fmt.Println("  MCPRAG_TOKENIZER_DIR  Directory of BPE rank files (alternative to -tokenizer-dir)")
 }
\ No newline at end of file
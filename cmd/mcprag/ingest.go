@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/PerceptivePenguin/MCPRAG-Go/internal/rag"
+)
+
+// ingestManifestName 是 ingest 清单在向量存储目录中的文件名
+const ingestManifestName = "ingest-manifest.json"
+
+// listFlag 收集可重复的参数，也接受逗号分隔的列表
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+// runIngestCommand 处理 ingest 子命令: 将目录中的文件增量导入文件向量存储
+func runIngestCommand(args []string) error {
+	config := DefaultConfig()
+	config.VectorBackend = "file"
+	ingestConfig := rag.DefaultIngestConfig()
+
+	fs := flag.NewFlagSet("ingest", flag.ContinueOnError)
+	fs.Usage = func() { printIngestUsage(fs) }
+	fs.StringVar(&config.OpenAIAPIKey, "api-key", os.Getenv("OPENAI_API_KEY"), "OpenAI API key")
+	fs.StringVar(&config.BaseURL, "base-url", "", "OpenAI API base URL")
+	registerStoreFlags(fs, config)
+
+	var include, exclude listFlag
+	fs.Var(&include, "include", "Glob of files to ingest, repeatable (default: every file of a known format)")
+	fs.Var(&exclude, "exclude", "Glob of files or directories to skip, repeatable (hidden files, node_modules and vendor are always skipped)")
+	manifest := fs.String("manifest", "", "Manifest of ingested content hashes (default: "+ingestManifestName+" in -vector-path)")
+	fs.BoolVar(&ingestConfig.ChunkCode, "chunk-code", ingestConfig.ChunkCode, "Chunk source files on declarations")
+	fs.BoolVar(&config.Verbose, "verbose", false, "List every added, updated and deleted file")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	if fs.NArg() == 0 {
+		printIngestUsage(fs)
+		return fmt.Errorf("missing path to ingest")
+	}
+	if config.VectorBackend != "file" {
+		return fmt.Errorf("ingest requires the file vector store")
+	}
+	if err := config.validateStore(); err != nil {
+		return err
+	}
+	if config.EmbeddingProvider == "openai" && config.OpenAIAPIKey == "" && config.EmbeddingAPIKey == "" {
+		return fmt.Errorf("OpenAI API key is required. Set OPENAI_API_KEY environment variable or use -api-key flag")
+	}
+
+	ingestConfig.Include = include
+	ingestConfig.Exclude = append(ingestConfig.Exclude, exclude...)
+	ingestConfig.ManifestPath = *manifest
+	if ingestConfig.ManifestPath == "" {
+		ingestConfig.ManifestPath = filepath.Join(config.VectorPath, ingestManifestName)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	setupSignalHandler(cancel)
+
+	ragConfig := createRAGConfig(config)
+	retriever, err := rag.NewRetriever(&ragConfig)
+	if err != nil {
+		return err
+	}
+
+	ingester, err := rag.NewIngester(retriever, ingestConfig)
+	if err != nil {
+		retriever.Close()
+		return err
+	}
+
+	var failed int
+	for _, root := range fs.Args() {
+		report, err := ingester.Ingest(ctx, root)
+		if report != nil {
+			printIngestReport(root, report, config.Verbose)
+			failed += len(report.Failed)
+		}
+		if err != nil {
+			retriever.Close()
+			return err
+		}
+	}
+
+	// 关闭检索器时写回向量存储
+	if err := retriever.Close(); err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d files could not be ingested", failed)
+	}
+	return nil
+}
+
+// printIngestReport 显示一次导入的结果
+func printIngestReport(root string, report *rag.IngestReport, verbose bool) {
+	if verbose {
+		for _, id := range report.Added {
+			fmt.Printf("  + %s\n", id)
+		}
+		for _, id := range report.Updated {
+			fmt.Printf("  ~ %s\n", id)
+		}
+		for _, id := range report.Deleted {
+			fmt.Printf("  - %s\n", id)
+		}
+	}
+	for _, failure := range report.Failed {
+		fmt.Fprintf(os.Stderr, "  ! %v\n", failure)
+	}
+
+	fmt.Printf("%s: 新增 %d，更新 %d，删除 %d，未变 %d，失败 %d\n",
+		root, len(report.Added), len(report.Updated), len(report.Deleted), report.Unchanged, len(report.Failed))
+}
+
+// printIngestUsage 显示 ingest 子命令帮助
+func printIngestUsage(fs *flag.FlagSet) {
+	fmt.Printf("Usage: %s ingest [options] <path>...\n\n", appName)
+	fmt.Println("Ingest files and directories into the file vector store. A manifest of")
+	fmt.Println("content hashes makes re-runs embed only new or modified files and delete")
+	fmt.Println("the chunks of removed files.")
+	fmt.Println()
+	fmt.Println("Options:")
+	fs.PrintDefaults()
+	fmt.Println()
+	fmt.Println("Examples:")
+	fmt.Printf("  %s ingest -vector-path ./data/vectors ./docs\n", appName)
+	fmt.Printf("  %s ingest -vector-path ./data/vectors -include '*.md' -include 'src/**/*.go' .\n", appName)
+	fmt.Printf("  %s ingest -vector-path ./data/vectors -embedding-provider hashing -exclude 'docs/drafts' ./docs\n", appName)
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/PerceptivePenguin/MCPRAG-Go/internal/agent"
@@ -95,8 +96,9 @@ func (im *InteractiveMode) processInput(ctx context.Context, input string) (bool
 func (im *InteractiveMode) processQuery(ctx context.Context, query string) error {
 	// 创建请求
 	req := agent.Request{
-		ID:    fmt.Sprintf("req-%d", time.Now().Unix()),
-		Query: query,
+		ID:        fmt.Sprintf("req-%d", time.Now().Unix()),
+		Query:     query,
+		EnableRAG: true, // 是否实际检索由 -enable-rag 决定
 	}
 	
 	fmt.Println("\n正在处理您的问题...")
@@ -121,9 +123,49 @@ func (im *InteractiveMode) processQuery(ctx context.Context, query string) error
 		
 		if response.Finished {
 			fmt.Println()
+			im.showCitations(response.Citations, response.UnknownCitations)
 			break
 		}
 	}
 	
 	return nil
+}
+
+// showCitations 显示回答引用的知识库片段
+func (im *InteractiveMode) showCitations(citations []agent.Citation, unknown []string) {
+	header := false
+	for _, citation := range citations {
+		if !citation.Cited {
+			continue
+		}
+		if !header {
+			fmt.Println("\n来源:")
+			header = true
+		}
+		fmt.Printf("  [%s] %s (相关度 %.2f)\n", citation.Marker, formatCitation(citation), citation.Score)
+	}
+	
+	if len(unknown) > 0 {
+		fmt.Printf("警告: 回答引用了不存在的来源 %s\n", strings.Join(unknown, ", "))
+	}
+}
+
+// formatCitation 返回片段来源的简短描述：标题、路径和行号
+func formatCitation(citation agent.Citation) string {
+	var parts []string
+	if citation.Title != "" {
+		parts = append(parts, citation.Title)
+	}
+	if citation.Source != "" && citation.Source != citation.Title {
+		parts = append(parts, citation.Source)
+	}
+	if len(parts) == 0 {
+		parts = append(parts, citation.DocumentID)
+	}
+	
+	description := strings.Join(parts, " - ")
+	if span := citation.Span; span != nil && span.StartLine > 0 {
+		description += fmt.Sprintf(":%d-%d", span.StartLine, span.EndLine)
+	}
+	return description
 }
\ No newline at end of file
@@ -15,6 +15,22 @@ const (
 )
 
 func main() {
+	// 子命令在解析应用参数之前处理
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "snapshot":
+			if err := runSnapshotCommand(os.Args[2:]); err != nil {
+				log.Fatalf("快照命令失败: %v", err)
+			}
+			return
+		case "ingest":
+			if err := runIngestCommand(os.Args[2:]); err != nil {
+				log.Fatalf("导入命令失败: %v", err)
+			}
+			return
+		}
+	}
+	
 	// 解析命令行参数
 	config, err := ParseFlags()
 	if err != nil {
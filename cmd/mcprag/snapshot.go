@@ -0,0 +1,176 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/PerceptivePenguin/MCPRAG-Go/internal/vector"
+)
+
+// runSnapshotCommand 处理 snapshot 子命令: dump、load 和 info
+func runSnapshotCommand(args []string) error {
+	if len(args) == 0 {
+		printSnapshotUsage()
+		return fmt.Errorf("missing snapshot command")
+	}
+
+	switch args[0] {
+	case "dump":
+		return snapshotDump(args[1:])
+	case "load":
+		return snapshotLoad(args[1:])
+	case "info":
+		return snapshotInfo(args[1:])
+	case "help", "-h", "-help":
+		printSnapshotUsage()
+		return nil
+	default:
+		printSnapshotUsage()
+		return fmt.Errorf("unknown snapshot command %q", args[0])
+	}
+}
+
+// printSnapshotUsage 显示 snapshot 子命令帮助
+func printSnapshotUsage() {
+	fmt.Printf("Usage: %s snapshot <command> [options]\n\n", appName)
+	fmt.Println("Commands:")
+	fmt.Println("  dump   Write the file vector store at -vector-path to a snapshot")
+	fmt.Println("  load   Replace the file vector store at -vector-path with a snapshot")
+	fmt.Println("  info   Show the header of a snapshot file")
+	fmt.Println()
+	fmt.Println("Examples:")
+	fmt.Printf("  %s snapshot dump -vector-path ./data/vectors -o vectors.snap\n", appName)
+	fmt.Printf("  %s snapshot load -vector-path ./data/vectors -i vectors.snap\n", appName)
+	fmt.Printf("  %s snapshot info vectors.snap\n", appName)
+}
+
+// snapshotDump 将文件向量存储导出为快照
+func snapshotDump(args []string) error {
+	defaults := vector.DefaultConfig()
+
+	fs := flag.NewFlagSet("snapshot dump", flag.ContinueOnError)
+	path := fs.String("vector-path", "", "Directory of the file vector store")
+	dimension := fs.Int("dimension", defaults.Dimension, "Vector dimension of the store")
+	metric := fs.String("vector-metric", string(defaults.Metric), "Vector similarity metric (cosine, dot, l2)")
+	output := fs.String("o", "-", "Snapshot file to write, - for stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *path == "" {
+		return fmt.Errorf("-vector-path is required")
+	}
+
+	if _, err := os.Stat(*path); err != nil {
+		return fmt.Errorf("vector store not found: %w", err)
+	}
+
+	store, err := openSnapshotStore(*path, *dimension, vector.Metric(*metric))
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	var w io.Writer = os.Stdout
+	if *output != "-" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if err := vector.SaveSnapshot(store, w); err != nil {
+		return err
+	}
+
+	if f, ok := w.(*os.File); ok && f != os.Stdout {
+		if err := f.Sync(); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "已导出 %d 个文档到 %s\n", store.Size(), *output)
+	}
+
+	return nil
+}
+
+// snapshotLoad 用快照替换文件向量存储的内容
+func snapshotLoad(args []string) error {
+	fs := flag.NewFlagSet("snapshot load", flag.ContinueOnError)
+	path := fs.String("vector-path", "", "Directory of the file vector store")
+	input := fs.String("i", "", "Snapshot file to read")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *path == "" || *input == "" {
+		return fmt.Errorf("-vector-path and -i are required")
+	}
+
+	f, err := os.Open(*input)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// 存储的维度和度量取自快照头
+	header, err := vector.ReadSnapshotHeader(f)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	store, err := openSnapshotStore(*path, header.Dimension, header.Metric)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	if err := vector.LoadSnapshot(store, f); err != nil {
+		return err
+	}
+
+	fmt.Printf("已从 %s 导入 %d 个文档\n", *input, store.Size())
+	return nil
+}
+
+// snapshotInfo 显示快照头信息
+func snapshotInfo(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: %s snapshot info <file>", appName)
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	header, err := vector.ReadSnapshotHeader(f)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Version:   %d\n", header.Version)
+	fmt.Printf("Dimension: %d\n", header.Dimension)
+	fmt.Printf("Metric:    %s\n", header.Metric)
+	fmt.Printf("Documents: %d\n", header.Count)
+	return nil
+}
+
+// openSnapshotStore 打开快照命令使用的文件向量存储
+func openSnapshotStore(path string, dimension int, metric vector.Metric) (vector.Store, error) {
+	config := vector.DefaultConfig()
+	config.Backend = vector.BackendFile
+	config.Path = path
+	config.Dimension = dimension
+	config.Metric = metric
+
+	return vector.NewStore(config)
+}
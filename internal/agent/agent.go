@@ -3,6 +3,7 @@ package agent
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/PerceptivePenguin/MCPRAG-Go/internal/chat"
@@ -29,8 +30,21 @@ func NewAgent(opts ...Option) (*Agent, error) {
 	
 	mcpManager := mcp.NewManager(options.MCPConfig)
 	
-	ragRetriever, err := rag.NewRetriever(&options.RAGConfig)
+	// 默认索引使用 Agent 的 RAG 配置，其他索引通过 GetIndexManager 创建
+	ragIndexes := rag.NewIndexManager(&options.RAGConfig, nil)
+	if err := ragIndexes.CreateIndex(context.Background(), rag.DefaultIndexName, &options.RAGConfig); err != nil {
+		return nil, WrapRAGError("newAgent", err)
+	}
+	
+	// 按聊天模型的编码计算 token，未安装词表文件时退回近似计数
+	tokenizerConfig := rag.DefaultTokenizerConfig()
+	if options.RAGConfig.Tokenizer != nil {
+		tokenizerConfig.Dir = options.RAGConfig.Tokenizer.Dir
+	}
+	tokenizerConfig.Model = options.ChatConfig.Model
+	tokenizer, err := rag.NewTokenizer(tokenizerConfig)
 	if err != nil {
+		ragIndexes.Close()
 		return nil, WrapRAGError("newAgent", err)
 	}
 	
@@ -40,7 +54,8 @@ func NewAgent(opts ...Option) (*Agent, error) {
 		options:      options,
 		chatClient:   chatClient,
 		mcpManager:   mcpManager,
-		ragRetriever: ragRetriever,
+		ragIndexes:   ragIndexes,
+		tokenizer:    tokenizer,
 		stats:        NewAgentStats(),
 		errorStats:   NewErrorStats(),
 		ctx:          ctx,
@@ -84,25 +99,31 @@ func (a *Agent) Start(ctx context.Context) error {
 	return nil
 }
 
-// Stop 停止 Agent
+// Stop 停止 Agent，并关闭 RAG 索引，使文件存储和磁盘缓存写回磁盘；
+// 索引在 NewAgent 中创建，因此未启动的 Agent 也需要调用 Stop
 func (a *Agent) Stop() error {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 	
-	if !a.started {
-		return nil
+	var stopErr error
+	if a.started {
+		// 取消上下文
+		a.cancel()
+		
+		// 停止 MCP 管理器
+		if err := a.mcpManager.Stop(); err != nil {
+			stopErr = WrapMCPError("stop", err)
+		}
+		
+		a.started = false
 	}
 	
-	// 取消上下文
-	a.cancel()
-	
-	// 停止 MCP 管理器
-	if err := a.mcpManager.Stop(); err != nil {
-		return WrapMCPError("stop", err)
+	// 关闭所有索引，重复关闭不会出错
+	if err := a.ragIndexes.Close(); err != nil && stopErr == nil {
+		stopErr = WrapRAGError("stop", err)
 	}
 	
-	a.started = false
-	return nil
+	return stopErr
 }
 
 // IsStarted 检查 Agent 是否已启动
@@ -203,7 +224,7 @@ func (a *Agent) ProcessStream(ctx context.Context, req Request) (<-chan StreamRe
 // executeProcess 执行处理请求
 func (a *Agent) executeProcess(ctx context.Context, req Request) (*Response, error) {
 	// 准备消息
-	messages, err := a.prepareMessages(ctx, req)
+	messages, sources, err := a.prepareMessages(ctx, req)
 	if err != nil {
 		return nil, WrapAgentError("executeProcess", "failed to prepare messages", err, true)
 	}
@@ -214,17 +235,26 @@ func (a *Agent) executeProcess(ctx context.Context, req Request) (*Response, err
 	a.mu.Unlock()
 	
 	// 执行聊天循环
+	var response *Response
 	if req.EnableTools {
-		return a.executeWithTools(ctx, req, messages)
+		response, err = a.executeWithTools(ctx, req, messages)
+	} else {
+		response, err = a.executeWithoutTools(ctx, req, messages)
+	}
+	if err != nil {
+		return nil, err
 	}
 	
-	return a.executeWithoutTools(ctx, req, messages)
+	// 附加注入的知识库片段并校验回答中的引用
+	sources.apply(response)
+	
+	return response, nil
 }
 
 // executeProcessStream 执行流式处理请求
 func (a *Agent) executeProcessStream(ctx context.Context, req Request, respChan chan<- StreamResponse) error {
 	// 准备消息
-	messages, err := a.prepareMessages(ctx, req)
+	messages, sources, err := a.prepareMessages(ctx, req)
 	if err != nil {
 		return WrapAgentError("executeProcessStream", "failed to prepare messages", err, true)
 	}
@@ -236,15 +266,16 @@ func (a *Agent) executeProcessStream(ctx context.Context, req Request, respChan
 	
 	// 执行流式聊天
 	if req.EnableTools {
-		return a.executeStreamWithTools(ctx, req, messages, respChan)
+		return a.executeStreamWithTools(ctx, req, messages, sources, respChan)
 	}
 	
-	return a.executeStreamWithoutTools(ctx, req, messages, respChan)
+	return a.executeStreamWithoutTools(ctx, req, messages, sources, respChan)
 }
 
-// prepareMessages 准备消息
-func (a *Agent) prepareMessages(ctx context.Context, req Request) ([]chat.Message, error) {
+// prepareMessages 准备消息，同时返回注入的知识库片段（未注入时为 nil）
+func (a *Agent) prepareMessages(ctx context.Context, req Request) ([]chat.Message, *ragSources, error) {
 	var messages []chat.Message
+	var sources *ragSources
 	
 	// 添加用户查询
 	userMsg := chat.Message{
@@ -260,9 +291,10 @@ func (a *Agent) prepareMessages(ctx context.Context, req Request) ([]chat.Messag
 			Text:      req.Query,
 			TopK:      5,
 			Threshold: 0.5,
+			Index:     req.Index,
 		}
 		
-		result, err := a.ragRetriever.Retrieve(ctx, ragQuery)
+		result, err := a.ragIndexes.Retrieve(ctx, ragQuery)
 		if err != nil {
 			// RAG 失败不应该中断整个流程，记录错误但继续
 			a.errorStats.RecordError(WrapRAGError("prepareMessages", err))
@@ -273,7 +305,8 @@ func (a *Agent) prepareMessages(ctx context.Context, req Request) ([]chat.Messag
 			
 			// 构建 RAG 上下文
 			if len(result.Documents) > 0 {
-				ragContext := a.buildRAGContext(result.Documents)
+				var ragContext string
+				ragContext, sources = a.buildRAGContext(result.Documents, result.Scores)
 				userMsg.Content = ragContext + "\n\n" + req.Query
 			}
 		}
@@ -292,41 +325,47 @@ func (a *Agent) prepareMessages(ctx context.Context, req Request) ([]chat.Messag
 	
 	// 检查上下文长度
 	if err := a.checkContextLength(messages); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	
-	return messages, nil
+	return messages, sources, nil
 }
 
-// buildRAGContext 构建 RAG 上下文
-func (a *Agent) buildRAGContext(docs []rag.Document) string {
-	context := "Relevant information from knowledge base:\n\n"
+// buildRAGContext 构建 RAG 上下文，每个片段以引用标记（如 [S1]）开头，
+// 返回上下文和实际注入的片段
+func (a *Agent) buildRAGContext(docs []rag.Document, scores []float32) (string, *ragSources) {
+	context := citationInstructions
+	sources := &ragSources{}
 	
 	for i, doc := range docs {
-		if len(context) > a.options.RAGContextLength {
+		if a.tokenizer.CountTokens(context) > a.options.RAGContextLength {
 			break
 		}
 		
-		context += fmt.Sprintf("%d. %s\n", i+1, doc.Content)
-		if doc.Metadata != nil {
-			if source, ok := doc.Metadata["source"]; ok {
-				context += fmt.Sprintf("   Source: %v\n", source)
-			}
+		var score float32
+		if i < len(scores) {
+			score = scores[i]
+		}
+		citation := sources.add(doc, score)
+		
+		context += fmt.Sprintf("[%s] %s\n", citation.Marker, doc.Content)
+		if citation.Source != "" {
+			context += fmt.Sprintf("   Source: %v\n", citation.Source)
 		}
 		context += "\n"
 	}
 	
-	return context
+	return context, sources
 }
 
-// checkContextLength 检查上下文长度
+// checkContextLength 检查上下文长度（以 token 计）
 func (a *Agent) checkContextLength(messages []chat.Message) error {
-	totalLength := 0
+	totalTokens := 0
 	for _, msg := range messages {
-		totalLength += len(msg.Content)
+		totalTokens += a.tokenizer.CountTokens(msg.Content)
 	}
 	
-	if totalLength > a.options.MaxContextLength {
+	if totalTokens > a.options.MaxContextLength {
 		return WrapAgentError("checkContextLength", "context too long", ErrContextTooLong, false)
 	}
 	
@@ -394,12 +433,14 @@ func (a *Agent) executeWithoutTools(ctx context.Context, req Request, messages [
 }
 
 // executeStreamWithTools 执行带工具的流式处理
-func (a *Agent) executeStreamWithTools(ctx context.Context, req Request, messages []chat.Message, respChan chan<- StreamResponse) error {
+func (a *Agent) executeStreamWithTools(ctx context.Context, req Request, messages []chat.Message, sources *ragSources, respChan chan<- StreamResponse) error {
 	streamChan, err := a.chatClient.ChatStreamWithTools(ctx, messages)
 	if err != nil {
 		return WrapChatError("executeStreamWithTools", err)
 	}
 	
+	// 累积回答内容，结束时校验其中的引用
+	var content strings.Builder
 	for streamResp := range streamChan {
 		if streamResp.Error != nil {
 			return WrapChatError("executeStreamWithTools", streamResp.Error)
@@ -412,6 +453,11 @@ func (a *Agent) executeStreamWithTools(ctx context.Context, req Request, message
 			Timestamp: time.Now(),
 		}
 		
+		content.WriteString(streamResp.Content)
+		if streamResp.Finished {
+			agentStreamResp.Citations, agentStreamResp.UnknownCitations = sources.resolve(content.String())
+		}
+		
 		// 处理工具调用
 		for _, toolCall := range streamResp.ToolCalls {
 			agentToolCall := ToolCall{
@@ -436,12 +482,14 @@ func (a *Agent) executeStreamWithTools(ctx context.Context, req Request, message
 }
 
 // executeStreamWithoutTools 执行不带工具的流式处理
-func (a *Agent) executeStreamWithoutTools(ctx context.Context, req Request, messages []chat.Message, respChan chan<- StreamResponse) error {
+func (a *Agent) executeStreamWithoutTools(ctx context.Context, req Request, messages []chat.Message, sources *ragSources, respChan chan<- StreamResponse) error {
 	streamChan, err := a.chatClient.ChatStream(ctx, messages)
 	if err != nil {
 		return WrapChatError("executeStreamWithoutTools", err)
 	}
 	
+	// 累积回答内容，结束时校验其中的引用
+	var content strings.Builder
 	for streamResp := range streamChan {
 		if streamResp.Error != nil {
 			return WrapChatError("executeStreamWithoutTools", streamResp.Error)
@@ -454,6 +502,11 @@ func (a *Agent) executeStreamWithoutTools(ctx context.Context, req Request, mess
 			Timestamp: time.Now(),
 		}
 		
+		content.WriteString(streamResp.Content)
+		if streamResp.Finished {
+			agentStreamResp.Citations, agentStreamResp.UnknownCitations = sources.resolve(content.String())
+		}
+		
 		select {
 		case <-ctx.Done():
 			return WrapAgentError("executeStreamWithoutTools", "context canceled", ctx.Err(), false)
@@ -594,6 +647,11 @@ func (a *Agent) GetOptions() Options {
 	return a.options.Clone()
 }
 
+// GetIndexManager 获取 RAG 索引管理器，用于创建和管理命名索引
+func (a *Agent) GetIndexManager() *rag.BasicIndexManager {
+	return a.ragIndexes
+}
+
 // SetSystemPrompt 更新系统提示
 func (a *Agent) SetSystemPrompt(prompt string) {
 	a.mu.Lock()
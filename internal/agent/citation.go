@@ -0,0 +1,136 @@
+package agent
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/PerceptivePenguin/MCPRAG-Go/internal/rag"
+)
+
+// citationInstructions 放在知识库片段之前，要求模型用标记引用片段
+const citationInstructions = "Relevant information from knowledge base. Each source is tagged with a marker such as [S1]. " +
+	"When your answer uses a source, cite its marker in square brackets right after the statement, e.g. [S1] or [S1, S2]. " +
+	"Only cite the markers listed below.\n\n"
+
+// citationPattern 匹配回答中的引用，如 [S1]、[S1, S3]
+var citationPattern = regexp.MustCompile(`\[\s*(S\d+(?:\s*[,，;]\s*S\d+)*)\s*\]`)
+
+// citationMarkerPattern 匹配引用中的单个标记
+var citationMarkerPattern = regexp.MustCompile(`S\d+`)
+
+// ragSources 注入到提示中的知识库片段，按注入顺序排列
+type ragSources struct {
+	citations []Citation
+	contents  []string
+}
+
+// citationMarker 返回第 index 个（从 0 开始）注入片段的标记
+func citationMarker(index int) string {
+	return fmt.Sprintf("S%d", index+1)
+}
+
+// add 记录一个注入的片段并返回其引用信息
+func (s *ragSources) add(doc rag.Document, score float32) Citation {
+	marker := citationMarker(len(s.citations))
+
+	documentID := doc.ParentID
+	if documentID == "" {
+		documentID = doc.ID
+	}
+	source := doc.Source
+	if source == "" {
+		source = doc.Metadata[rag.MetadataSource]
+	}
+
+	citation := Citation{
+		Marker:     marker,
+		DocumentID: documentID,
+		Source:     source,
+		Title:      doc.Title,
+		Span:       spanFromMetadata(doc.Metadata),
+		Score:      score,
+	}
+	if doc.ID != documentID {
+		citation.ChunkID = doc.ID
+	}
+
+	s.citations = append(s.citations, citation)
+	s.contents = append(s.contents, doc.Content)
+	return citation
+}
+
+// resolve 将回答中的引用与注入的片段对照，返回标记了是否被引用的片段列表，
+// 以及回答中引用了但并未注入的标记（按首次出现的顺序）
+func (s *ragSources) resolve(content string) ([]Citation, []string) {
+	if s == nil {
+		return nil, nil
+	}
+
+	citations := make([]Citation, len(s.citations))
+	copy(citations, s.citations)
+
+	index := make(map[string]int, len(citations))
+	for i, citation := range citations {
+		index[citation.Marker] = i
+	}
+
+	var unknown []string
+	seen := make(map[string]bool)
+	for _, match := range citationPattern.FindAllStringSubmatch(content, -1) {
+		for _, marker := range citationMarkerPattern.FindAllString(match[1], -1) {
+			if i, ok := index[marker]; ok {
+				citations[i].Cited = true
+				continue
+			}
+			if !seen[marker] {
+				seen[marker] = true
+				unknown = append(unknown, marker)
+			}
+		}
+	}
+
+	return citations, unknown
+}
+
+// apply 将注入的片段和引用设置到响应中
+func (s *ragSources) apply(resp *Response) {
+	if s == nil || resp == nil {
+		return
+	}
+
+	resp.RAGContext = s.contents
+	resp.Citations, resp.UnknownCitations = s.resolve(resp.Content)
+}
+
+// spanFromMetadata 从片段元数据中读取其在源文档中的位置，没有位置信息时返回 nil
+func spanFromMetadata(metadata map[string]string) *Span {
+	atoi := func(key string) (int, bool) {
+		value, err := strconv.Atoi(metadata[key])
+		return value, err == nil
+	}
+
+	var span Span
+	found := false
+	if start, ok := atoi(rag.MetadataStartPos); ok {
+		span.Start = start
+		found = true
+	}
+	if end, ok := atoi(rag.MetadataEndPos); ok {
+		span.End = end
+		found = true
+	}
+	if line, ok := atoi(rag.MetadataStartLine); ok {
+		span.StartLine = line
+		found = true
+	}
+	if line, ok := atoi(rag.MetadataEndLine); ok {
+		span.EndLine = line
+		found = true
+	}
+
+	if !found {
+		return nil
+	}
+	return &span
+}
@@ -22,10 +22,10 @@ type Options struct {
 	EnableParallelCalls  bool          `json:"enableParallelCalls"`
 	
 	// 上下文配置
-	MaxContextLength     int    `json:"maxContextLength"`
+	MaxContextLength     int    `json:"maxContextLength"` // 以 token 计
 	SystemPrompt         string `json:"systemPrompt"`
 	EnableRAGContext     bool   `json:"enableRAGContext"`
-	RAGContextLength     int    `json:"ragContextLength"`
+	RAGContextLength     int    `json:"ragContextLength"` // 以 token 计
 	
 	// 性能配置
 	EnableMetrics        bool          `json:"enableMetrics"`
@@ -19,7 +19,8 @@ type Agent struct {
 	// 组件
 	chatClient *chat.ClientWithTools
 	mcpManager *mcp.Manager
-	ragRetriever rag.Retriever
+	ragIndexes *rag.BasicIndexManager
+	tokenizer  rag.Tokenizer
 	
 	// 状态
 	mu         sync.RWMutex
@@ -61,19 +62,44 @@ type Request struct {
 	Context   []string  `json:"context,omitempty"`
 	EnableRAG bool      `json:"enableRAG"`
 	EnableTools bool    `json:"enableTools"`
+	Index     string    `json:"index,omitempty"` // RAG 索引名称，为空时使用默认索引
 	Timestamp time.Time `json:"timestamp"`
 }
 
 // Response 处理响应
 type Response struct {
-	ID           string        `json:"id"`
-	Content      string        `json:"content"`
-	ToolCalls    []ToolCall    `json:"toolCalls,omitempty"`
-	RAGContext   []string      `json:"ragContext,omitempty"`
-	ResponseTime time.Duration `json:"responseTime"`
-	TokenUsage   TokenUsage    `json:"tokenUsage"`
-	Timestamp    time.Time     `json:"timestamp"`
-	Error        string        `json:"error,omitempty"`
+	ID               string        `json:"id"`
+	Content          string        `json:"content"`
+	ToolCalls        []ToolCall    `json:"toolCalls,omitempty"`
+	RAGContext       []string      `json:"ragContext,omitempty"`
+	Citations        []Citation    `json:"citations,omitempty"`
+	UnknownCitations []string      `json:"unknownCitations,omitempty"` // 回答中引用但未注入的标记
+	ResponseTime     time.Duration `json:"responseTime"`
+	TokenUsage       TokenUsage    `json:"tokenUsage"`
+	Timestamp        time.Time     `json:"timestamp"`
+	Error            string        `json:"error,omitempty"`
+}
+
+// Citation 注入到提示中的知识库片段及其引用标记
+type Citation struct {
+	Marker     string  `json:"marker"` // 如 "S1"，回答中以 [S1] 引用
+	DocumentID string  `json:"documentId"`
+	ChunkID    string  `json:"chunkId,omitempty"`
+	Source     string  `json:"source,omitempty"`
+	Title      string  `json:"title,omitempty"`
+	Span       *Span   `json:"span,omitempty"`
+	Score      float32 `json:"score"`
+	Cited      bool    `json:"cited"` // 回答是否引用了该片段
+}
+
+// Span 片段在源文档中的位置，未知的字段为零。字节偏移只在片段是源文档原文
+// 的切片时设置（纯文本和代码按 token、定长或代码结构切分），Markdown、HTML
+// 和 JSON 的片段来自抽取后的文本，没有字节偏移
+type Span struct {
+	Start     int `json:"start,omitempty"`     // 字节偏移，含
+	End       int `json:"end,omitempty"`       // 字节偏移，不含
+	StartLine int `json:"startLine,omitempty"` // 从 1 开始，含
+	EndLine   int `json:"endLine,omitempty"`
 }
 
 // ToolCall Agent模块的工具调用结构，扩展了通用ToolCall
@@ -89,12 +115,14 @@ type TokenUsage = types.TokenUsage
 
 // StreamResponse Agent模块的流式响应结构，使用Agent专用的ToolCall类型
 type StreamResponse struct {
-	ID        string     `json:"id"`
-	Content   string     `json:"content"`
-	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
-	Finished  bool       `json:"finished"`
-	Error     error      `json:"error,omitempty"`
-	Timestamp time.Time  `json:"timestamp"`
+	ID               string     `json:"id"`
+	Content          string     `json:"content"`
+	ToolCalls        []ToolCall `json:"tool_calls,omitempty"`
+	Finished         bool       `json:"finished"`
+	Error            error      `json:"error,omitempty"`
+	Citations        []Citation `json:"citations,omitempty"` // 仅在最后一条响应中设置
+	UnknownCitations []string   `json:"unknownCitations,omitempty"`
+	Timestamp        time.Time  `json:"timestamp"`
 }
 
 // NewAgentStats 创建新的Agent统计信息
@@ -0,0 +1,430 @@
+package rag
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/PerceptivePenguin/MCPRAG-Go/internal/vector"
+)
+
+// BM25Config configures the BM25 keyword index
+type BM25Config struct {
+	// K1 controls term frequency saturation
+	K1 float64 `json:"k1"`
+
+	// B controls document length normalization, between 0 and 1
+	B float64 `json:"b"`
+
+	// CaseSensitive keeps the case of terms; by default terms are lowercased
+	CaseSensitive bool `json:"case_sensitive"`
+
+	// SplitIdentifiers also indexes the parts of camelCase and snake_case
+	// identifiers, so "IndexManager" matches "index" as well as itself
+	SplitIdentifiers bool `json:"split_identifiers"`
+
+	// MinTokenLength drops shorter terms
+	MinTokenLength int `json:"min_token_length"`
+
+	// StopWords are never indexed or searched
+	StopWords []string `json:"stop_words,omitempty"`
+
+	// Tokenize replaces the built-in tokenization when set
+	Tokenize func(text string) []string `json:"-"`
+}
+
+// DefaultBM25Config returns the usual BM25 parameters with identifier
+// splitting enabled
+func DefaultBM25Config() *BM25Config {
+	return &BM25Config{
+		K1:               1.2,
+		B:                0.75,
+		SplitIdentifiers: true,
+		MinTokenLength:   1,
+	}
+}
+
+// BM25Strategy is a keyword SearchStrategy ranking chunks with Okapi BM25
+// over an inverted index. It keeps itself in sync with a BasicRetriever when
+// registered through AddListener, and can be combined with semantic search
+// in a HybridRetriever. Query thresholds apply to vector similarity and are
+// ignored; query filters are honored.
+type BM25Strategy struct {
+	mu          sync.RWMutex
+	config      BM25Config
+	stopWords   map[string]bool
+	postings    map[string]map[string]int // term -> chunk ID -> term frequency
+	docs        map[string]*bm25Doc
+	totalLength int
+}
+
+type bm25Doc struct {
+	length   int
+	terms    []string // distinct terms, for removal
+	metadata map[string]string
+}
+
+// NewBM25Strategy creates an empty BM25 index
+func NewBM25Strategy(config *BM25Config) (*BM25Strategy, error) {
+	if config == nil {
+		config = DefaultBM25Config()
+	}
+
+	if config.K1 < 0 || math.IsNaN(config.K1) {
+		return nil, ValidationError("k1", "k1 must not be negative")
+	}
+	if config.B < 0 || config.B > 1 || math.IsNaN(config.B) {
+		return nil, ValidationError("b", "b must be between 0 and 1")
+	}
+
+	s := &BM25Strategy{
+		config:    *config,
+		stopWords: make(map[string]bool, len(config.StopWords)),
+		postings:  make(map[string]map[string]int),
+		docs:      make(map[string]*bm25Doc),
+	}
+	for _, word := range config.StopWords {
+		if !config.CaseSensitive {
+			word = strings.ToLower(word)
+		}
+		s.stopWords[word] = true
+	}
+
+	return s, nil
+}
+
+// Search ranks the indexed chunks against the query text and keywords and
+// loads the top query.TopK of them from store
+func (s *BM25Strategy) Search(ctx context.Context, query Query, store vector.Store) (*RetrievalResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if query.Text == "" {
+		return nil, ErrQueryEmpty.WithOperation("bm25_search")
+	}
+	if query.TopK <= 0 {
+		return nil, ErrInvalidTopK.WithOperation("bm25_search")
+	}
+
+	filter, err := buildFilter(query)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	text := query.Text
+	if len(query.Keywords) > 0 {
+		text += "\n" + strings.Join(query.Keywords, "\n")
+	}
+	ids, scores, total := s.rank(text, query.TopK, filter)
+	searchTime := time.Since(start).Milliseconds()
+
+	result := &RetrievalResult{
+		Query:      query,
+		Documents:  make([]Document, 0, len(ids)),
+		Scores:     make([]float32, 0, len(ids)),
+		TotalFound: total,
+		SearchTime: searchTime,
+	}
+
+	for i, id := range ids {
+		vd, err := store.Get(id)
+		if err != nil {
+			// Deleted from the store behind the index's back
+			continue
+		}
+		doc := documentFromVector(*vd)
+		if !query.IncludeVector {
+			doc.Vector = nil
+		}
+		result.Documents = append(result.Documents, doc)
+		result.Scores = append(result.Scores, scores[i])
+	}
+
+	result.QueryTime = time.Since(start).Milliseconds()
+	return result, nil
+}
+
+// rank returns the best topK chunk IDs with their scores, and the number of
+// chunks matching at least one query term
+func (s *BM25Strategy) rank(text string, topK int, filter vector.Filter) ([]string, []float32, int) {
+	terms := s.tokenize(text)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	n := float64(len(s.docs))
+	if n == 0 || len(terms) == 0 {
+		return nil, nil, 0
+	}
+	avgLength := float64(s.totalLength) / n
+	k1, b := s.config.K1, s.config.B
+
+	scores := make(map[string]float64)
+	seen := make(map[string]bool, len(terms))
+	for _, term := range terms {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+
+		postings := s.postings[term]
+		if len(postings) == 0 {
+			continue
+		}
+
+		df := float64(len(postings))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+
+		for id, tf := range postings {
+			doc := s.docs[id]
+			if filter != nil && !filter.Match(doc.metadata) {
+				continue
+			}
+			freq := float64(tf)
+			norm := freq * (k1 + 1) / (freq + k1*(1-b+b*float64(doc.length)/avgLength))
+			scores[id] += idf * norm
+		}
+	}
+
+	ids := make([]string, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if scores[ids[i]] != scores[ids[j]] {
+			return scores[ids[i]] > scores[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+
+	total := len(ids)
+	if len(ids) > topK {
+		ids = ids[:topK]
+	}
+
+	ranked := make([]float32, len(ids))
+	for i, id := range ids {
+		ranked[i] = float32(scores[id])
+	}
+
+	return ids, ranked, total
+}
+
+// GetName returns the strategy name
+func (s *BM25Strategy) GetName() string {
+	return string(SearchKeyword)
+}
+
+// GetDescription returns strategy description
+func (s *BM25Strategy) GetDescription() string {
+	return "BM25 keyword search over an inverted index"
+}
+
+// Add indexes a chunk, replacing any earlier version with the same ID
+func (s *BM25Strategy) Add(id, content string, metadata map[string]string) {
+	terms := s.tokenize(content)
+
+	frequencies := make(map[string]int)
+	for _, term := range terms {
+		frequencies[term]++
+	}
+
+	doc := &bm25Doc{
+		length:   len(terms),
+		terms:    make([]string, 0, len(frequencies)),
+		metadata: metadata,
+	}
+	for term := range frequencies {
+		doc.terms = append(doc.terms, term)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.remove(id)
+
+	for term, tf := range frequencies {
+		postings, ok := s.postings[term]
+		if !ok {
+			postings = make(map[string]int)
+			s.postings[term] = postings
+		}
+		postings[id] = tf
+	}
+	s.docs[id] = doc
+	s.totalLength += doc.length
+}
+
+// Remove drops a chunk from the index
+func (s *BM25Strategy) Remove(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.remove(id)
+}
+
+func (s *BM25Strategy) remove(id string) {
+	doc, ok := s.docs[id]
+	if !ok {
+		return
+	}
+
+	for _, term := range doc.terms {
+		postings := s.postings[term]
+		delete(postings, id)
+		if len(postings) == 0 {
+			delete(s.postings, term)
+		}
+	}
+	delete(s.docs, id)
+	s.totalLength -= doc.length
+}
+
+// Size returns the number of indexed chunks
+func (s *BM25Strategy) Size() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.docs)
+}
+
+// SyncStore rebuilds the index from every chunk in store
+func (s *BM25Strategy) SyncStore(store vector.Store) error {
+	lister, ok := store.(interface{ ListIDs() []string })
+	if !ok {
+		return NewRAGErrorWithOp("bm25_sync", "vector store cannot list its documents", ErrorTypeNotImplemented)
+	}
+
+	s.mu.Lock()
+	s.postings = make(map[string]map[string]int)
+	s.docs = make(map[string]*bm25Doc)
+	s.totalLength = 0
+	s.mu.Unlock()
+
+	for _, id := range lister.ListIDs() {
+		vd, err := store.Get(id)
+		if err != nil {
+			continue
+		}
+		s.Add(vd.ID, vd.Content, vd.Metadata)
+	}
+
+	return nil
+}
+
+// OnDocumentAdded indexes a stored chunk
+func (s *BM25Strategy) OnDocumentAdded(ctx context.Context, doc Document) {
+	s.Add(doc.ID, doc.Content, doc.Metadata)
+}
+
+// OnDocumentUpdated re-indexes a stored chunk
+func (s *BM25Strategy) OnDocumentUpdated(ctx context.Context, doc Document) {
+	s.Add(doc.ID, doc.Content, doc.Metadata)
+}
+
+// OnDocumentDeleted removes a chunk from the index
+func (s *BM25Strategy) OnDocumentDeleted(ctx context.Context, docID string) {
+	s.Remove(docID)
+}
+
+// OnQueryExecuted is a no-op
+func (s *BM25Strategy) OnQueryExecuted(ctx context.Context, query Query, result *RetrievalResult) {}
+
+// OnError is a no-op
+func (s *BM25Strategy) OnError(ctx context.Context, err error) {}
+
+// tokenize splits text into index terms, lowercased unless the index is
+// case sensitive, without stop words and short terms
+func (s *BM25Strategy) tokenize(text string) []string {
+	var raw []string
+	if s.config.Tokenize != nil {
+		raw = s.config.Tokenize(text)
+	} else {
+		raw = keywordTokens(text, s.config.SplitIdentifiers)
+	}
+
+	terms := raw[:0]
+	for _, term := range raw {
+		if !s.config.CaseSensitive {
+			term = strings.ToLower(term)
+		}
+		if term == "" || len([]rune(term)) < s.config.MinTokenLength || s.stopWords[term] {
+			continue
+		}
+		terms = append(terms, term)
+	}
+
+	return terms
+}
+
+// keywordTokens keeps runs of letters, digits and underscores together, so
+// identifiers and error codes survive intact, and optionally adds the
+// camelCase and snake_case parts of identifiers
+func keywordTokens(text string, splitIdentifiers bool) []string {
+	var tokens []string
+	for _, word := range strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	}) {
+		tokens = append(tokens, word)
+		if splitIdentifiers {
+			if parts := splitIdentifier(word); len(parts) > 1 {
+				tokens = append(tokens, parts...)
+			}
+		}
+	}
+
+	return tokens
+}
+
+// splitIdentifier splits an identifier at underscores, lower-to-upper case
+// changes, the end of an acronym ("HTTPServer" -> "HTTP", "Server") and
+// letter-digit boundaries
+func splitIdentifier(word string) []string {
+	var parts []string
+	runes := []rune(word)
+	start := 0
+
+	flush := func(end int) {
+		if end > start {
+			parts = append(parts, string(runes[start:end]))
+		}
+		start = end
+	}
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if r == '_' {
+			flush(i)
+			start = i + 1
+			continue
+		}
+		if i == start {
+			continue
+		}
+
+		prev := runes[i-1]
+		switch {
+		case unicode.IsUpper(r) && unicode.IsLower(prev):
+			flush(i)
+		case unicode.IsUpper(r) && unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1]):
+			flush(i)
+		case unicode.IsDigit(r) != unicode.IsDigit(prev) && prev != '_':
+			flush(i)
+		}
+	}
+	flush(len(runes))
+
+	return parts
+}
+
+// Ensure BM25Strategy implements SearchStrategy and EventListener
+var (
+	_ SearchStrategy = (*BM25Strategy)(nil)
+	_ EventListener  = (*BM25Strategy)(nil)
+)
@@ -0,0 +1,612 @@
+package rag
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Byte-pair encodings used by OpenAI models
+const (
+	EncodingCL100K = "cl100k_base"
+	EncodingO200K  = "o200k_base"
+)
+
+// TokenizerDirEnv overrides the default directory of BPE rank files
+const TokenizerDirEnv = "MCPRAG_TOKENIZER_DIR"
+
+// bpeCacheSize bounds the number of encoded pieces cached per encoding
+const bpeCacheSize = 1 << 16
+
+// TokenizerConfig selects the tokenizer used for chunking and context
+// budgeting
+type TokenizerConfig struct {
+	// Model selects the encoding, see EncodingForModel; empty means the
+	// embedding model
+	Model string `json:"model,omitempty"`
+
+	// Dir holds rank files named after their encoding, such as
+	// cl100k_base.tiktoken, in the tiktoken format
+	Dir string `json:"dir,omitempty"`
+}
+
+// DefaultTokenizerConfig returns a configuration reading rank files from
+// DefaultTokenizerDir
+func DefaultTokenizerConfig() *TokenizerConfig {
+	return &TokenizerConfig{
+		Dir: DefaultTokenizerDir(),
+	}
+}
+
+// DefaultTokenizerDir returns $MCPRAG_TOKENIZER_DIR, or mcprag/tokenizers
+// in the user cache directory
+func DefaultTokenizerDir() string {
+	if dir := os.Getenv(TokenizerDirEnv); dir != "" {
+		return dir
+	}
+	return tokenizerCacheDir()
+}
+
+// tokenizerCacheDir returns mcprag/tokenizers in the user cache directory,
+// the directory rank files are looked up in unless one is chosen
+func tokenizerCacheDir() string {
+	if cacheDir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(cacheDir, "mcprag", "tokenizers")
+	}
+	return ""
+}
+
+// EncodingForModel returns the encoding used by an OpenAI model. Encoding
+// names are returned as is; unknown models get cl100k_base.
+func EncodingForModel(model string) string {
+	model = strings.ToLower(model)
+
+	switch model {
+	case EncodingCL100K, EncodingO200K:
+		return model
+	}
+
+	for _, prefix := range []string{"gpt-4o", "chatgpt-4o", "gpt-4.1", "gpt-4.5", "gpt-5", "o1", "o3", "o4"} {
+		if strings.HasPrefix(model, prefix) {
+			return EncodingO200K
+		}
+	}
+
+	return EncodingCL100K
+}
+
+// NewTokenizer returns a BPETokenizer for the configured model, or a
+// SimpleTokenizer when no rank file for its encoding is installed in the
+// default directory. A directory that was chosen, through Dir or
+// $MCPRAG_TOKENIZER_DIR, must hold the rank file.
+func NewTokenizer(config *TokenizerConfig) (Tokenizer, error) {
+	if config == nil {
+		config = DefaultTokenizerConfig()
+	}
+
+	tokenizer, err := NewBPETokenizer(config.Model, config.Dir)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		if config.Dir == "" || config.Dir == tokenizerCacheDir() {
+			return NewSimpleTokenizer(config.Model), nil
+		}
+		return nil, NewRAGErrorWithCause("no rank file for "+EncodingForModel(config.Model)+" in tokenizer directory "+config.Dir,
+			ErrorTypeNotFound, err).WithOperation("new_tokenizer")
+	}
+
+	return tokenizer, nil
+}
+
+// BPETokenizer counts and splits text into the byte-pair-encoding tokens of
+// an OpenAI model, loaded from a local tiktoken rank file
+type BPETokenizer struct {
+	model    string
+	encoding *bpeEncoding
+}
+
+// NewBPETokenizer loads the encoding of model from dir. Encodings are
+// loaded once per file and shared between tokenizers.
+func NewBPETokenizer(model, dir string) (*BPETokenizer, error) {
+	name := EncodingForModel(model)
+	encoding, err := loadBPEEncoding(name, filepath.Join(dir, name+".tiktoken"))
+	if err != nil {
+		return nil, err
+	}
+
+	if model == "" {
+		model = name
+	}
+
+	return &BPETokenizer{
+		model:    model,
+		encoding: encoding,
+	}, nil
+}
+
+// CountTokens counts the number of tokens in text
+func (t *BPETokenizer) CountTokens(text string) int {
+	count := 0
+	t.encoding.eachPiece(text, func(piece string) bool {
+		count += len(t.encoding.encodePiece(piece))
+		return true
+	})
+	return count
+}
+
+// Tokenize splits text into tokens. Tokens are byte sequences and may split
+// multi-byte characters.
+func (t *BPETokenizer) Tokenize(text string) []string {
+	var tokens []string
+	t.encoding.eachPiece(text, func(piece string) bool {
+		for _, id := range t.encoding.encodePiece(piece) {
+			tokens = append(tokens, t.encoding.decoder[id])
+		}
+		return true
+	})
+	return tokens
+}
+
+// Encode returns the token IDs of text
+func (t *BPETokenizer) Encode(text string) []int {
+	var ids []int
+	t.encoding.eachPiece(text, func(piece string) bool {
+		ids = append(ids, t.encoding.encodePiece(piece)...)
+		return true
+	})
+	return ids
+}
+
+// TruncateToTokens truncates text to specified token count, backing off to
+// a character boundary
+func (t *BPETokenizer) TruncateToTokens(text string, maxTokens int) string {
+	if maxTokens <= 0 {
+		return ""
+	}
+
+	end, remaining := 0, maxTokens
+	truncated := false
+	t.encoding.eachPiece(text, func(piece string) bool {
+		ids := t.encoding.encodePiece(piece)
+		if len(ids) <= remaining {
+			end += len(piece)
+			remaining -= len(ids)
+			return true
+		}
+		for _, id := range ids[:remaining] {
+			end += len(t.encoding.decoder[id])
+		}
+		truncated = true
+		return false
+	})
+
+	if !truncated {
+		return text
+	}
+	for end > 0 && end < len(text) && !utf8.RuneStart(text[end]) {
+		end--
+	}
+	return text[:end]
+}
+
+// GetModel returns the tokenizer model name
+func (t *BPETokenizer) GetModel() string {
+	return t.model
+}
+
+// Encoding returns the name of the encoding in use
+func (t *BPETokenizer) Encoding() string {
+	return t.encoding.name
+}
+
+// bpeEncoding holds the merge ranks of an encoding and caches the tokens of
+// pieces already encoded
+type bpeEncoding struct {
+	name    string
+	ranks   map[string]int
+	decoder []string
+	scan    func(text string, i int) int
+
+	mu    sync.RWMutex
+	cache map[string][]int
+}
+
+var (
+	bpeEncodingsMu sync.Mutex
+	bpeEncodings   = make(map[string]*bpeEncoding)
+)
+
+func loadBPEEncoding(name, path string) (*bpeEncoding, error) {
+	bpeEncodingsMu.Lock()
+	defer bpeEncodingsMu.Unlock()
+
+	if encoding, ok := bpeEncodings[path]; ok {
+		return encoding, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	ranks, err := parseBPERanks(file)
+	if err != nil {
+		return nil, NewRAGErrorWithCause("failed to load tokenizer ranks from "+path, ErrorTypeInternal, err).WithOperation("load_tokenizer")
+	}
+
+	encoding := &bpeEncoding{
+		name:  name,
+		ranks: ranks,
+		scan:  scanCL100KPiece,
+		cache: make(map[string][]int),
+	}
+	if name == EncodingO200K {
+		encoding.scan = scanO200KPiece
+	}
+
+	maxRank := -1
+	for _, rank := range ranks {
+		maxRank = max(maxRank, rank)
+	}
+	encoding.decoder = make([]string, maxRank+1)
+	for token, rank := range ranks {
+		encoding.decoder[rank] = token
+	}
+
+	bpeEncodings[path] = encoding
+	return encoding, nil
+}
+
+// parseBPERanks reads "<base64 token> <rank>" lines
+func parseBPERanks(r io.Reader) (map[string]int, error) {
+	ranks := make(map[string]int)
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, errors.New("malformed rank line " + strconv.Itoa(line))
+		}
+
+		token, err := base64.StdEncoding.DecodeString(fields[0])
+		if err != nil {
+			return nil, errors.New("malformed token on line " + strconv.Itoa(line))
+		}
+		rank, err := strconv.Atoi(fields[1])
+		if err != nil || rank < 0 {
+			return nil, errors.New("malformed rank on line " + strconv.Itoa(line))
+		}
+		ranks[string(token)] = rank
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(ranks) == 0 {
+		return nil, errors.New("rank file is empty")
+	}
+	for b := 0; b < 256; b++ {
+		if _, ok := ranks[string([]byte{byte(b)})]; !ok {
+			return nil, errors.New("rank file does not cover every byte")
+		}
+	}
+
+	return ranks, nil
+}
+
+// eachPiece calls fn with the pre-tokenized pieces of text until it returns
+// false
+func (e *bpeEncoding) eachPiece(text string, fn func(piece string) bool) {
+	for i := 0; i < len(text); {
+		end := e.scan(text, i)
+		if !fn(text[i:end]) {
+			return
+		}
+		i = end
+	}
+}
+
+// encodePiece returns the tokens of a pre-tokenized piece
+func (e *bpeEncoding) encodePiece(piece string) []int {
+	if rank, ok := e.ranks[piece]; ok {
+		return []int{rank}
+	}
+
+	e.mu.RLock()
+	ids, ok := e.cache[piece]
+	e.mu.RUnlock()
+	if ok {
+		return ids
+	}
+
+	ids = e.bytePairMerge(piece)
+
+	e.mu.Lock()
+	if len(e.cache) >= bpeCacheSize {
+		e.cache = make(map[string][]int)
+	}
+	e.cache[piece] = ids
+	e.mu.Unlock()
+
+	return ids
+}
+
+// bytePairMerge repeatedly merges the adjacent pair of parts with the lowest
+// rank, starting from single bytes, until no pair has a rank
+func (e *bpeEncoding) bytePairMerge(piece string) []int {
+	type part struct {
+		start int
+		rank  int
+	}
+
+	// parts[i].rank is the rank of parts i and i+1 merged
+	parts := make([]part, len(piece)+1)
+	for i := range parts {
+		parts[i] = part{start: i, rank: math.MaxInt}
+	}
+	for i := 0; i+1 < len(piece); i++ {
+		if rank, ok := e.ranks[piece[i:i+2]]; ok {
+			parts[i].rank = rank
+		}
+	}
+
+	// rankAfterMerge is the rank of parts i and i+1 once i+1 and i+2 merge
+	rankAfterMerge := func(i int) int {
+		if i+3 < len(parts) {
+			if rank, ok := e.ranks[piece[parts[i].start:parts[i+3].start]]; ok {
+				return rank
+			}
+		}
+		return math.MaxInt
+	}
+
+	for {
+		best := -1
+		for i := 0; i < len(parts)-1; i++ {
+			if parts[i].rank != math.MaxInt && (best < 0 || parts[i].rank < parts[best].rank) {
+				best = i
+			}
+		}
+		if best < 0 {
+			break
+		}
+
+		if best > 0 {
+			parts[best-1].rank = rankAfterMerge(best - 1)
+		}
+		parts[best].rank = rankAfterMerge(best)
+		parts = append(parts[:best+1], parts[best+2:]...)
+	}
+
+	ids := make([]int, 0, len(parts)-1)
+	for i := 0; i+1 < len(parts); i++ {
+		ids = append(ids, e.ranks[piece[parts[i].start:parts[i+1].start]])
+	}
+	return ids
+}
+
+// The scanners below split text the way the pre-tokenization patterns of
+// the encodings do, which Go's regexp cannot express for lack of
+// lookahead. Each returns the end of the piece starting at i.
+//
+// cl100k_base:
+//
+//	(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}|
+//	 ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+
+//
+// o200k_base:
+//
+//	[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|'t|'re|'ve|'m|'ll|'d)?|
+//	[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|'t|'re|'ve|'m|'ll|'d)?|
+//	\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n/]*|\s*[\r\n]+|\s+(?!\S)|\s+
+
+func scanCL100KPiece(text string, i int) int {
+	r, size := utf8.DecodeRuneInString(text[i:])
+
+	if r == '\'' {
+		if n := contractionLength(text[i:]); n > 0 {
+			return i + n
+		}
+	}
+
+	if unicode.IsLetter(r) {
+		return scanRunes(text, i, unicode.IsLetter)
+	}
+	if canPrefixWord(r) && i+size < len(text) {
+		if next, _ := utf8.DecodeRuneInString(text[i+size:]); unicode.IsLetter(next) {
+			return scanRunes(text, i+size, unicode.IsLetter)
+		}
+	}
+
+	if end, ok := scanPunctuationOrNumber(text, i, "\r\n"); ok {
+		return end
+	}
+
+	return scanWhitespace(text, i)
+}
+
+func scanO200KPiece(text string, i int) int {
+	r, size := utf8.DecodeRuneInString(text[i:])
+
+	// Words with an optional leading character, lowercase-ending words first
+	for _, word := range []func(string, int) int{o200kLowerWord, o200kUpperWord} {
+		if canPrefixWord(r) && i+size < len(text) {
+			if end := word(text, i+size); end > 0 {
+				return end + contractionLength(text[end:])
+			}
+		}
+		if end := word(text, i); end > 0 {
+			return end + contractionLength(text[end:])
+		}
+	}
+
+	if end, ok := scanPunctuationOrNumber(text, i, "\r\n/"); ok {
+		return end
+	}
+
+	return scanWhitespace(text, i)
+}
+
+// o200kLowerWord matches [upper]*[lower]+ at i and returns its end, or -1
+func o200kLowerWord(text string, i int) int {
+	// Positions where the lowercase run may start: after the whole
+	// uppercase run first, then backtracking into it
+	starts := []int{i}
+	for j := i; j < len(text); {
+		r, size := utf8.DecodeRuneInString(text[j:])
+		if !isUpperClass(r) {
+			break
+		}
+		j += size
+		starts = append(starts, j)
+	}
+
+	for k := len(starts) - 1; k >= 0; k-- {
+		start := starts[k]
+		if start >= len(text) {
+			continue
+		}
+		if r, _ := utf8.DecodeRuneInString(text[start:]); isLowerClass(r) {
+			return scanRunes(text, start, isLowerClass)
+		}
+	}
+
+	return -1
+}
+
+// o200kUpperWord matches [upper]+[lower]* at i and returns its end, or -1
+func o200kUpperWord(text string, i int) int {
+	end := scanRunes(text, i, isUpperClass)
+	if end == i {
+		return -1
+	}
+	return scanRunes(text, end, isLowerClass)
+}
+
+func isUpperClass(r rune) bool {
+	return unicode.In(r, unicode.Lu, unicode.Lt, unicode.Lm, unicode.Lo, unicode.M)
+}
+
+func isLowerClass(r rune) bool {
+	return unicode.In(r, unicode.Ll, unicode.Lm, unicode.Lo, unicode.M)
+}
+
+// canPrefixWord reports whether r matches [^\r\n\p{L}\p{N}]
+func canPrefixWord(r rune) bool {
+	return r != '\r' && r != '\n' && !unicode.IsLetter(r) && !unicode.IsNumber(r)
+}
+
+func isPunctuationClass(r rune) bool {
+	return !unicode.IsSpace(r) && !unicode.IsLetter(r) && !unicode.IsNumber(r)
+}
+
+// scanPunctuationOrNumber matches \p{N}{1,3} or ' ?[^\s\p{L}\p{N}]+' followed
+// by any of trailing
+func scanPunctuationOrNumber(text string, i int, trailing string) (int, bool) {
+	r, _ := utf8.DecodeRuneInString(text[i:])
+
+	if unicode.IsNumber(r) {
+		end := i
+		for n := 0; n < 3 && end < len(text); n++ {
+			r, size := utf8.DecodeRuneInString(text[end:])
+			if !unicode.IsNumber(r) {
+				break
+			}
+			end += size
+		}
+		return end, true
+	}
+
+	start := i
+	if r == ' ' {
+		start++
+	}
+	if start < len(text) {
+		if r, _ := utf8.DecodeRuneInString(text[start:]); isPunctuationClass(r) {
+			end := scanRunes(text, start, isPunctuationClass)
+			for end < len(text) && strings.IndexByte(trailing, text[end]) >= 0 {
+				end++
+			}
+			return end, true
+		}
+	}
+
+	return 0, false
+}
+
+// scanWhitespace matches \s*[\r\n]+, \s+(?!\S) or \s+ at i
+func scanWhitespace(text string, i int) int {
+	end := scanRunes(text, i, unicode.IsSpace)
+	if end == i {
+		// Not whitespace either; emit the character on its own
+		_, size := utf8.DecodeRuneInString(text[i:])
+		return i + size
+	}
+
+	// Up to the last line break of the run
+	if last := strings.LastIndexAny(text[i:end], "\r\n"); last >= 0 {
+		return i + last + 1
+	}
+
+	// Leave the last space to the word that follows
+	if end < len(text) {
+		_, size := utf8.DecodeLastRuneInString(text[i:end])
+		if end-size > i {
+			return end - size
+		}
+	}
+
+	return end
+}
+
+// contractionLength returns the length of a leading 's, 't, 're, 've, 'm,
+// 'll or 'd in any case, or 0
+func contractionLength(text string) int {
+	if len(text) < 2 || text[0] != '\'' {
+		return 0
+	}
+
+	switch unicode.ToLower(rune(text[1])) {
+	case 's', 't', 'm', 'd':
+		return 2
+	}
+	if len(text) >= 3 {
+		switch strings.ToLower(text[1:3]) {
+		case "re", "ve", "ll":
+			return 3
+		}
+	}
+	return 0
+}
+
+// scanRunes returns the end of the run of runes from i satisfying match
+func scanRunes(text string, i int, match func(rune) bool) int {
+	for i < len(text) {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if !match(r) {
+			break
+		}
+		i += size
+	}
+	return i
+}
+
+// Ensure BPETokenizer implements Tokenizer
+var _ Tokenizer = (*BPETokenizer)(nil)
@@ -72,6 +72,52 @@ func NewLRUCache(config *CacheConfig) (*LRUCache, error) {
 	return cache, nil
 }
 
+// NewCache creates the cache of config.Strategy, backed by a DiskCache when
+// config.DiskPath is set; it returns a nil Cache when caching is disabled
+func NewCache(config *CacheConfig) (Cache, error) {
+	if config == nil {
+		config = DefaultCacheConfig()
+	}
+
+	if !config.Enabled {
+		return nil, nil
+	}
+
+	// Constructors return typed nil pointers on error, which must not end
+	// up in the interface
+	var cache Cache
+	var err error
+	switch config.Strategy {
+	case "", CacheLRU:
+		cache, err = NewLRUCache(config)
+	case CacheLFU:
+		cache, err = NewLFUCache(config)
+	case CacheFIFO:
+		cache, err = NewFIFOCache(config)
+	default:
+		return nil, ValidationError("strategy", "unsupported cache strategy: "+string(config.Strategy))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if config.DiskPath == "" {
+		return cache, nil
+	}
+
+	disk, err := NewDiskCache(config)
+	if err != nil {
+		cache.Close()
+		return nil, err
+	}
+
+	tiered, err := NewTieredCache(cache, disk)
+	if err != nil {
+		return nil, err
+	}
+	return tiered, nil
+}
+
 // Get retrieves a cached embedding by key
 func (c *LRUCache) Get(ctx context.Context, key string) (*CacheEntry, error) {
 	c.mu.Lock()
@@ -146,7 +192,7 @@ func (c *LRUCache) Set(ctx context.Context, key string, vector vector.Vector, me
 		CreatedAt:   now,
 		AccessedAt:  now,
 		AccessCount: 1,
-		Size:        c.calculateEntrySize(key, vector, metadata),
+		Size:        calculateCacheEntrySize(key, vector, metadata),
 	}
 
 	node := &cacheNode{
@@ -287,7 +333,8 @@ func (c *LRUCache) updateHitRate() {
 	}
 }
 
-func (c *LRUCache) calculateEntrySize(key string, vector vector.Vector, metadata map[string]string) int {
+// calculateCacheEntrySize estimates the memory held by a cache entry
+func calculateCacheEntrySize(key string, vector vector.Vector, metadata map[string]string) int {
 	size := len(key) + len(vector)*4 // float32 is 4 bytes
 	for k, v := range metadata {
 		size += len(k) + len(v)
@@ -310,42 +357,29 @@ func (c *LRUCache) saveToDisk() error {
 		}
 	}
 
-	jsonData, err := json.Marshal(data)
-	if err != nil {
-		return fmt.Errorf("failed to marshal cache data: %w", err)
-	}
-
-	return ioutil.WriteFile(c.config.PersistPath, jsonData, 0644)
+	return writeCacheFile(c.config.PersistPath, data)
 }
 
 func (c *LRUCache) loadFromDisk() error {
-	if c.config.PersistPath == "" {
-		return nil
-	}
-
-	if _, err := os.Stat(c.config.PersistPath); os.IsNotExist(err) {
-		return nil // File doesn't exist, that's OK
-	}
-
-	jsonData, err := ioutil.ReadFile(c.config.PersistPath)
+	data, err := readCacheFile(c.config.PersistPath)
 	if err != nil {
-		return fmt.Errorf("failed to read cache file: %w", err)
+		return err
 	}
 
-	var data map[string]*CacheEntry
-	if err := json.Unmarshal(jsonData, &data); err != nil {
-		return fmt.Errorf("failed to unmarshal cache data: %w", err)
+	// Restore entries to cache; they get a fresh TTL
+	var expireAt time.Time
+	if c.config.TTL > 0 {
+		expireAt = time.Now().Add(c.config.TTL)
 	}
-
-	// Restore entries to cache
 	for key, entry := range data {
 		if len(c.items) >= c.maxSize {
 			break // Don't exceed max size when loading
 		}
 
 		node := &cacheNode{
-			key:   key,
-			entry: entry,
+			key:      key,
+			entry:    entry,
+			expireAt: expireAt,
 		}
 
 		c.addToHead(node)
@@ -358,6 +392,40 @@ func (c *LRUCache) loadFromDisk() error {
 	return nil
 }
 
+// writeCacheFile stores cache entries as JSON at path
+func writeCacheFile(path string, data map[string]*CacheEntry) error {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache data: %w", err)
+	}
+
+	return ioutil.WriteFile(path, jsonData, 0644)
+}
+
+// readCacheFile loads cache entries written by writeCacheFile; a missing
+// file holds no entries
+func readCacheFile(path string) (map[string]*CacheEntry, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, nil // File doesn't exist, that's OK
+	}
+
+	jsonData, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache file: %w", err)
+	}
+
+	var data map[string]*CacheEntry
+	if err := json.Unmarshal(jsonData, &data); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal cache data: %w", err)
+	}
+
+	return data, nil
+}
+
 // GenerateCacheKey generates a cache key from text content
 func GenerateCacheKey(text string, model string) string {
 	h := sha256.New()
@@ -12,6 +12,7 @@ import (
 // TextChunker implements document chunking functionality
 type TextChunker struct {
 	tokenizer Tokenizer
+	embedder  Embedder
 }
 
 // NewTextChunker creates a new text chunker
@@ -21,6 +22,13 @@ func NewTextChunker(tokenizer Tokenizer) *TextChunker {
 	}
 }
 
+// SetEmbedder sets the embedder ChunkBySemantic finds topic shifts with.
+// Without one it falls back to comparing the words of sentences. It must
+// be called before the chunker is used.
+func (c *TextChunker) SetEmbedder(embedder Embedder) {
+	c.embedder = embedder
+}
+
 // ChunkDocument splits a document into chunks based on the specified options
 func (c *TextChunker) ChunkDocument(ctx context.Context, doc Document, options ChunkingOptions) ([]Chunk, error) {
 	if doc.Content == "" {
@@ -48,7 +56,9 @@ func (c *TextChunker) ChunkDocument(ctx context.Context, doc Document, options C
 	case ChunkByFixedSize:
 		chunks, err = c.chunkByFixedSize(doc, options)
 	case ChunkBySemantic:
-		chunks, err = c.chunkBySemantic(doc, options)
+		chunks, err = c.chunkBySemantic(ctx, doc, options)
+	case ChunkByCode:
+		chunks, err = c.chunkByCode(doc, options)
 	default:
 		return nil, ErrInvalidStrategy.WithOperation("chunk_document").WithDetails(map[string]string{
 			"strategy": string(options.Strategy),
@@ -74,7 +84,7 @@ func (c *TextChunker) ChunkDocument(ctx context.Context, doc Document, options C
 			chunks[i].Metadata[k] = v
 		}
 		
-		chunks[i].Metadata["chunk_strategy"] = string(options.Strategy)
+		chunks[i].Metadata[MetadataChunkStrategy] = string(options.Strategy)
 		chunks[i].Metadata["source_document"] = doc.ID
 		
 		if c.tokenizer != nil {
@@ -85,7 +95,9 @@ func (c *TextChunker) ChunkDocument(ctx context.Context, doc Document, options C
 	return chunks, nil
 }
 
-// chunkByTokens splits text based on token count
+// chunkByTokens splits text based on token count. Chunk boundaries are
+// byte offsets into the original text, chosen so that every chunk holds at
+// most MaxChunkSize tokens and ends on whitespace where possible.
 func (c *TextChunker) chunkByTokens(doc Document, options ChunkingOptions) ([]Chunk, error) {
 	if c.tokenizer == nil {
 		return nil, NewRAGErrorWithOp("chunk_by_tokens", "tokenizer is required for token-based chunking", ErrorTypeValidation)
@@ -101,48 +113,91 @@ func (c *TextChunker) chunkByTokens(doc Document, options ChunkingOptions) ([]Ch
 	for startPos < len(text) {
 		// Find the end position for this chunk
 		endPos := len(text)
-		chunkText := text[startPos:]
-		
-		// If chunk is too large, truncate to max tokens
-		if c.tokenizer.CountTokens(chunkText) > maxTokens {
-			chunkText = c.tokenizer.TruncateToTokens(chunkText, maxTokens)
-			endPos = startPos + len(chunkText)
-		}
-		
-		if chunkText == "" {
-			break
+		if c.tokenizer.CountTokens(text[startPos:]) > maxTokens {
+			endPos = c.tokenBoundary(text, startPos, maxTokens)
 		}
 		
-		chunk := Chunk{
-			Content:  strings.TrimSpace(chunkText),
-			StartPos: startPos,
-			EndPos:   endPos,
+		if content := strings.TrimSpace(text[startPos:endPos]); content != "" {
+			chunks = append(chunks, Chunk{
+				Content:  content,
+				StartPos: startPos,
+				EndPos:   endPos,
+			})
 		}
 		
-		chunks = append(chunks, chunk)
-		
-		// Calculate next start position with overlap
 		if endPos >= len(text) {
 			break
 		}
 		
-		// Find overlap start position
-		if overlapTokens > 0 && len(chunks) > 0 {
-			overlapText := c.tokenizer.TruncateToTokens(chunkText, overlapTokens)
-			overlapStart := strings.LastIndex(chunkText, overlapText)
-			if overlapStart > 0 {
-				startPos = startPos + overlapStart
-			} else {
-				startPos = endPos
-			}
-		} else {
-			startPos = endPos
+		// Start the next chunk overlapTokens before the end of this one
+		nextPos := endPos
+		if overlapTokens > 0 {
+			nextPos = c.overlapStart(text, startPos, endPos, overlapTokens)
 		}
+		if nextPos <= startPos {
+			nextPos = endPos
+		}
+		startPos = nextPos
 	}
 	
 	return chunks, nil
 }
 
+// tokenBoundary returns the largest end offset after start for which
+// text[start:end] fits in maxTokens, moved back to a whitespace boundary
+// when one exists in the second half of the chunk
+func (c *TextChunker) tokenBoundary(text string, start, maxTokens int) int {
+	lo, hi := start+1, len(text)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if c.tokenizer.CountTokens(text[start:mid]) <= maxTokens {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	end := lo
+	
+	if space := strings.LastIndexFunc(text[start:end], unicode.IsSpace); space > (end-start)/2 {
+		end = start + space + 1
+	}
+	
+	for end > start+1 && end < len(text) && !utf8.RuneStart(text[end]) {
+		end--
+	}
+	for end < len(text) && !utf8.RuneStart(text[end]) {
+		end++
+	}
+	
+	return end
+}
+
+// overlapStart returns the smallest offset in text[start:end] from which the
+// rest of the chunk fits in overlapTokens, moved forward to the next word
+func (c *TextChunker) overlapStart(text string, start, end, overlapTokens int) int {
+	lo, hi := start, end
+	for lo < hi {
+		mid := (lo + hi) / 2
+		if c.tokenizer.CountTokens(text[mid:end]) <= overlapTokens {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	pos := lo
+	
+	if pos > start && !unicode.IsSpace(rune(text[pos-1])) {
+		if space := strings.IndexFunc(text[pos:end], unicode.IsSpace); space >= 0 {
+			pos += space
+		}
+	}
+	for pos < end && !utf8.RuneStart(text[pos]) {
+		pos++
+	}
+	
+	return pos
+}
+
 // chunkBySentences splits text by sentences
 func (c *TextChunker) chunkBySentences(doc Document, options ChunkingOptions) ([]Chunk, error) {
 	sentences := c.splitIntoSentences(doc.Content)
@@ -343,8 +398,10 @@ func (c *TextChunker) chunkByFixedSize(doc Document, options ChunkingOptions) ([
 	return chunks, nil
 }
 
-// chunkBySemantic splits text based on semantic boundaries (simplified implementation)
-func (c *TextChunker) chunkBySemantic(doc Document, options ChunkingOptions) ([]Chunk, error) {
+// chunkByWordOverlap splits text where the words of a sentence stop
+// overlapping with the chunk so far; ChunkBySemantic uses it when no
+// embedder is set
+func (c *TextChunker) chunkByWordOverlap(doc Document, options ChunkingOptions) ([]Chunk, error) {
 	// This is a simplified implementation
 	// A full semantic chunking would use NLP models to detect topic boundaries
 	
@@ -511,14 +568,10 @@ func (c *TextChunker) getOverlapContent(content string, overlapSize int) string
 		return ""
 	}
 	
+	// Take a suffix of the original text: sub-word tokens cannot be joined
+	// back into it
 	if c.tokenizer != nil {
-		tokens := c.tokenizer.Tokenize(content)
-		if len(tokens) <= overlapSize {
-			return content
-		}
-		
-		overlapTokens := tokens[len(tokens)-overlapSize:]
-		return strings.Join(overlapTokens, " ")
+		return content[c.overlapStart(content, 0, len(content), overlapSize):]
 	}
 	
 	// Fallback to character-based overlap
@@ -0,0 +1,791 @@
+package rag
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Metadata keys set on chunks produced by the ChunkByCode strategy
+const (
+	MetadataFilePath   = "file_path"
+	MetadataLanguage   = "language"    // also read from document metadata to force a language
+	MetadataSymbol     = "symbol"      // e.g. "Parse" or "Store.Add"; empty for statements
+	MetadataSymbolKind = "symbol_kind" // function, method, type, class, import, ...
+	MetadataStartLine  = "start_line"  // 1-based, inclusive
+	MetadataEndLine    = "end_line"    // 1-based, inclusive
+)
+
+// codeLanguages maps source file extensions to language names
+var codeLanguages = map[string]string{
+	"go":    "go",
+	"ts":    "typescript",
+	"tsx":   "typescript",
+	"mts":   "typescript",
+	"cts":   "typescript",
+	"js":    "javascript",
+	"jsx":   "javascript",
+	"mjs":   "javascript",
+	"cjs":   "javascript",
+	"java":  "java",
+	"kt":    "kotlin",
+	"kts":   "kotlin",
+	"scala": "scala",
+	"swift": "swift",
+	"rs":    "rust",
+	"c":     "c",
+	"h":     "c",
+	"cc":    "cpp",
+	"cpp":   "cpp",
+	"cxx":   "cpp",
+	"hpp":   "cpp",
+	"hh":    "cpp",
+	"hxx":   "cpp",
+	"cs":    "csharp",
+	"php":   "php",
+	"sh":    "shell",
+	"bash":  "shell",
+	"py":    "python",
+	"pyi":   "python",
+	"rb":    "ruby",
+	"lua":   "lua",
+}
+
+// indentLanguages delimit blocks by indentation or keywords rather than braces
+var indentLanguages = map[string]bool{
+	"python": true,
+	"ruby":   true,
+	"lua":    true,
+}
+
+// singleQuoteStrings are languages where '...' is a string rather than a
+// character literal
+var singleQuoteStrings = map[string]bool{
+	"typescript": true,
+	"javascript": true,
+	"php":        true,
+	"shell":      true,
+}
+
+// codeLanguage returns the language of a source file path, or "" when the
+// extension is not a known source language
+func codeLanguage(path string) string {
+	return codeLanguages[strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))]
+}
+
+// codeUnit is a range of source lines holding one top-level construct
+type codeUnit struct {
+	start, end int // 0-based line range, end exclusive
+	symbol     string
+	kind       string
+}
+
+// containerKinds are constructs whose members are chunked separately when
+// the whole construct does not fit in one chunk
+var containerKinds = map[string]bool{
+	"class": true, "interface": true, "namespace": true, "impl": true,
+	"trait": true, "struct": true, "enum": true, "object": true,
+}
+
+// mergeableKinds are small constructs that adjacent units of the same kind
+// are merged for, as long as the result fits in one chunk
+var mergeableKinds = map[string]bool{
+	"import": true, "var": true, "const": true, "statement": true,
+}
+
+// chunkByCode splits source code on syntactic boundaries: one chunk per
+// top-level declaration, with oversized classes split into their members and
+// oversized functions split on line boundaries. Go is parsed with go/parser;
+// other languages use a brace or indentation heuristic. Documents in an
+// unknown language fall back to paragraph chunking.
+func (c *TextChunker) chunkByCode(doc Document, options ChunkingOptions) ([]Chunk, error) {
+	path := doc.Source
+	if path == "" {
+		path = doc.ID
+	}
+
+	language := doc.Metadata[MetadataLanguage]
+	if language == "" {
+		language = codeLanguage(path)
+	}
+	if language == "" {
+		return c.chunkByParagraphs(doc, options)
+	}
+
+	src := &sourceLines{content: doc.Content, lines: strings.SplitAfter(doc.Content, "\n")}
+	src.offsets = make([]int, len(src.lines)+1)
+	for i, line := range src.lines {
+		src.offsets[i+1] = src.offsets[i] + len(line)
+	}
+
+	var units []codeUnit
+	if language == "go" {
+		units = goCodeUnits(doc.Content, len(src.lines))
+	}
+	if units == nil {
+		units = c.heuristicUnits(src, language, 0, len(src.lines), "", options.MaxChunkSize)
+	}
+
+	units = c.mergeCodeUnits(src, units, options.MaxChunkSize)
+
+	var chunks []Chunk
+	for _, unit := range units {
+		for _, piece := range c.splitCodeUnit(src, unit, options.MaxChunkSize) {
+			chunks = append(chunks, piece.chunk(src, path, language))
+		}
+	}
+
+	if len(chunks) == 0 {
+		return nil, ErrDocumentEmpty.WithOperation("chunk_by_code")
+	}
+
+	return chunks, nil
+}
+
+// sourceLines holds a document split into lines, newlines included
+type sourceLines struct {
+	content string
+	lines   []string
+	offsets []int // byte offset of every line, plus the total length
+}
+
+func (s *sourceLines) text(start, end int) string {
+	return s.content[s.offsets[start]:s.offsets[end]]
+}
+
+// trim narrows a line range to exclude leading and trailing blank lines
+func (s *sourceLines) trim(start, end int) (int, int) {
+	for start < end && strings.TrimSpace(s.lines[start]) == "" {
+		start++
+	}
+	for end > start && strings.TrimSpace(s.lines[end-1]) == "" {
+		end--
+	}
+	return start, end
+}
+
+// codePiece is the final line range of one code chunk
+type codePiece struct {
+	codeUnit
+	cut              bool // a single line cut into several pieces
+	startPos, endPos int  // byte range of a cut piece
+}
+
+func (p codePiece) chunk(src *sourceLines, path, language string) Chunk {
+	startPos, endPos := src.offsets[p.start], src.offsets[p.end]
+	if p.cut {
+		startPos, endPos = p.startPos, p.endPos
+	}
+
+	metadata := map[string]string{
+		MetadataFilePath:   path,
+		MetadataLanguage:   language,
+		MetadataSymbolKind: p.kind,
+		MetadataStartLine:  strconv.Itoa(p.start + 1),
+		MetadataEndLine:    strconv.Itoa(p.end),
+	}
+	if p.symbol != "" {
+		metadata[MetadataSymbol] = p.symbol
+	}
+
+	return Chunk{
+		Content:  strings.TrimRight(src.content[startPos:endPos], " \t\n"),
+		StartPos: startPos,
+		EndPos:   endPos,
+		Metadata: metadata,
+	}
+}
+
+// size measures text in tokens when a tokenizer is set, in bytes otherwise
+func (c *TextChunker) size(text string) int {
+	if c.tokenizer != nil {
+		return c.tokenizer.CountTokens(text)
+	}
+	return len(text)
+}
+
+// mergeCodeUnits joins adjacent small units of the same mergeable kind and
+// attaches comment-only units, which only occur at the end, to the unit
+// before them
+func (c *TextChunker) mergeCodeUnits(src *sourceLines, units []codeUnit, maxSize int) []codeUnit {
+	var merged []codeUnit
+	for _, unit := range units {
+		if unit.start, unit.end = src.trim(unit.start, unit.end); unit.start >= unit.end {
+			continue
+		}
+
+		if n := len(merged); n > 0 {
+			last := &merged[n-1]
+			if unit.kind == "comment" {
+				last.end = unit.end
+				continue
+			}
+			if last.kind == unit.kind && mergeableKinds[unit.kind] && c.size(src.text(last.start, unit.end)) <= maxSize {
+				last.end = unit.end
+				last.symbol = joinSymbols(last.symbol, unit.symbol)
+				continue
+			}
+		}
+
+		merged = append(merged, unit)
+	}
+	return merged
+}
+
+func joinSymbols(a, b string) string {
+	switch {
+	case a == "":
+		return b
+	case b == "":
+		return a
+	default:
+		return a + ", " + b
+	}
+}
+
+// splitCodeUnit cuts a unit that does not fit in one chunk on line
+// boundaries, preferring blank lines; a single oversized line is cut by size
+func (c *TextChunker) splitCodeUnit(src *sourceLines, unit codeUnit, maxSize int) []codePiece {
+	if c.size(src.text(unit.start, unit.end)) <= maxSize {
+		return []codePiece{{codeUnit: unit}}
+	}
+
+	var pieces []codePiece
+	start := unit.start
+	for start < unit.end {
+		end := start + 1
+		lastBlank := -1
+		for end < unit.end && c.size(src.text(start, end+1)) <= maxSize {
+			if strings.TrimSpace(src.lines[end]) == "" {
+				lastBlank = end
+			}
+			end++
+		}
+		if end < unit.end && lastBlank > start+(end-start)/2 {
+			end = lastBlank
+		}
+
+		piece := unit
+		piece.start, piece.end = start, end
+		if end == start+1 && c.size(src.lines[start]) > maxSize {
+			pieces = append(pieces, c.splitCodeLine(src, piece, maxSize)...)
+		} else if s, e := src.trim(start, end); s < e {
+			piece.start, piece.end = s, e
+			pieces = append(pieces, codePiece{codeUnit: piece})
+		}
+		start = end
+	}
+
+	return pieces
+}
+
+// splitCodeLine cuts a single line, such as minified code, into pieces of at
+// most maxSize
+func (c *TextChunker) splitCodeLine(src *sourceLines, unit codeUnit, maxSize int) []codePiece {
+	line := src.lines[unit.start]
+	base := src.offsets[unit.start]
+
+	var pieces []codePiece
+	for pos := 0; pos < len(line); {
+		end := len(line)
+		if c.size(line[pos:]) > maxSize {
+			if c.tokenizer != nil {
+				end = c.tokenBoundary(line, pos, maxSize)
+			} else {
+				end = c.tokenBoundaryBytes(line, pos, maxSize)
+			}
+		}
+		pieces = append(pieces, codePiece{codeUnit: unit, cut: true, startPos: base + pos, endPos: base + end})
+		pos = end
+	}
+	return pieces
+}
+
+// tokenBoundaryBytes is tokenBoundary for byte sizes
+func (c *TextChunker) tokenBoundaryBytes(text string, start, maxBytes int) int {
+	end := min(start+maxBytes, len(text))
+	for end > start+1 && end < len(text) && text[end]&0xC0 == 0x80 {
+		end--
+	}
+	return end
+}
+
+// Go
+
+// goCodeUnits returns one unit per top-level Go declaration, with the package
+// clause and imports as a leading "package" unit. Comments between
+// declarations belong to the declaration that follows them. It returns nil
+// when the source does not parse.
+func goCodeUnits(src string, lineCount int) []codeUnit {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil
+	}
+
+	tf := fset.File(file.Pos())
+	endLine := func(pos token.Pos) int { return tf.Line(pos) } // exclusive 0-based end
+
+	header := codeUnit{start: 0, end: endLine(file.Name.End()), symbol: file.Name.Name, kind: "package"}
+	decls := file.Decls
+	for len(decls) > 0 {
+		gen, ok := decls[0].(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT {
+			break
+		}
+		header.end = endLine(gen.End())
+		decls = decls[1:]
+	}
+
+	units := []codeUnit{header}
+	for _, decl := range decls {
+		prev := &units[len(units)-1]
+		end := endLine(decl.End())
+		symbol, kind := goDeclSymbol(decl)
+
+		if end <= prev.end {
+			// Several declarations on one line
+			prev.symbol = joinSymbols(prev.symbol, symbol)
+			continue
+		}
+
+		units = append(units, codeUnit{start: prev.end, end: end, symbol: symbol, kind: kind})
+	}
+
+	// Trailing comments belong to the last declaration
+	units[len(units)-1].end = lineCount
+
+	return units
+}
+
+func goDeclSymbol(decl ast.Decl) (string, string) {
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		if d.Recv == nil || len(d.Recv.List) == 0 {
+			return d.Name.Name, "function"
+		}
+		return goReceiverName(d.Recv.List[0].Type) + "." + d.Name.Name, "method"
+	case *ast.GenDecl:
+		var names []string
+		for _, spec := range d.Specs {
+			switch s := spec.(type) {
+			case *ast.TypeSpec:
+				names = append(names, s.Name.Name)
+			case *ast.ValueSpec:
+				for _, name := range s.Names {
+					names = append(names, name.Name)
+				}
+			case *ast.ImportSpec:
+				names = append(names, strings.Trim(s.Path.Value, "\"`"))
+			}
+		}
+		return strings.Join(names, ", "), d.Tok.String()
+	}
+	return "", "statement"
+}
+
+// goReceiverName returns the type name of a method receiver, without
+// pointer or type parameters
+func goReceiverName(expr ast.Expr) string {
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// Heuristics for other languages
+
+var (
+	declModifiers = `(?:(?:export|default|declare|public|private|protected|internal|static|abstract|final|async|unsafe|inline|override|open|sealed|data|partial|readonly|pub(?:\([^)]*\))?|extern(?:\s+"[^"]*")?)\s+)*`
+
+	codeDeclStart = regexp.MustCompile(`^(?:@\w|#\[|` + declModifiers +
+		`(?:function|class|interface|type|enum|const|let|var|val|import|namespace|module|struct|trait|impl|fn|mod|use|fun|object|typedef|template|package|def)\b)`)
+
+	codeSymbolPatterns = []struct {
+		re   *regexp.Regexp
+		kind string
+	}{
+		{regexp.MustCompile(`\bclass\s+(\w+)`), "class"},
+		{regexp.MustCompile(`\binterface\s+(\w+)`), "interface"},
+		{regexp.MustCompile(`\benum\s+(?:class\s+)?(\w+)`), "enum"},
+		{regexp.MustCompile(`\btrait\s+(\w+)`), "trait"},
+		{regexp.MustCompile(`\bimpl(?:<[^>]*>)?\s+(?:[\w:<>, ]+\s+for\s+)?([\w:]+)`), "impl"},
+		{regexp.MustCompile(`\bstruct\s+(\w+)`), "struct"},
+		{regexp.MustCompile(`\bobject\s+(\w+)`), "object"},
+		{regexp.MustCompile(`^` + declModifiers + `(?:namespace|module|mod)\s+([\w.:]+)`), "namespace"},
+		{regexp.MustCompile(`^` + declModifiers + `type\s+(\w+)`), "type"},
+		{regexp.MustCompile(`\bfunction\s*\*?\s*(\w+)`), "function"},
+		{regexp.MustCompile(`\bfn\s+(\w+)`), "function"},
+		{regexp.MustCompile(`\bfun\s+(?:<[^>]*>\s*)?(?:[\w.]+\.)?(\w+)`), "function"},
+		{regexp.MustCompile(`^(?:async\s+)?def\s+(\w+)`), "function"},
+		{regexp.MustCompile(`^(?:local\s+)?function\s+([\w.:]+)`), "function"},
+		{regexp.MustCompile(`\b(?:const|let|var|val)\s+(\w+)\s*(?::[^=]*)?=\s*(?:async\s+)?(?:function\b|\([^)]*\)\s*(?::[^=]*)?=>|\w+\s*=>)`), "function"},
+		{regexp.MustCompile(`\b(?:const|let|var|val)\s+(\w+)`), "var"},
+		{regexp.MustCompile(`^(?:import|from|use|using|package|require|#include)\b()`), "import"},
+		{regexp.MustCompile(`^(\w+)\s*\(\)\s*\{`), "function"}, // shell
+		{regexp.MustCompile(`^(?:[\w<>\[\]?,.*&:]+\s+)+[*&]*(\w+)\s*\([^;]*$`), "function"},
+		{regexp.MustCompile(`^(\w+)\s*(?::[^=]*)?=[^=]`), "var"},
+	}
+
+	// codeMemberPattern matches method definitions inside a class body
+	codeMemberPattern = regexp.MustCompile(`^(?:(?:public|private|protected|internal|static|readonly|async|override|abstract|final|virtual|get|set|def|fn|fun|function|pub(?:\([^)]*\))?)\s+|\*)*(\w+)\s*(?:<[^>]*>)?\s*\(`)
+
+	codeKeywords = map[string]bool{
+		"if": true, "for": true, "while": true, "switch": true, "return": true, "catch": true,
+		"new": true, "else": true, "do": true, "try": true, "throw": true, "await": true,
+	}
+)
+
+// codeSymbol names the construct starting at a unit's first code line
+func codeSymbol(lines []string, parent string) (string, string) {
+	for _, raw := range lines {
+		line := strings.TrimSpace(raw)
+		if line == "" || isCodeComment(line) || strings.HasPrefix(line, "@") || strings.HasPrefix(line, "#[") {
+			continue
+		}
+
+		if parent != "" {
+			if m := codeMemberPattern.FindStringSubmatch(line); m != nil && !codeKeywords[m[1]] {
+				return parent + "." + m[1], "method"
+			}
+		}
+
+		for _, pattern := range codeSymbolPatterns {
+			m := pattern.re.FindStringSubmatch(line)
+			if m == nil || codeKeywords[m[1]] {
+				continue
+			}
+			kind := pattern.kind
+			if m[1] == "" {
+				return "", kind
+			}
+			if parent != "" {
+				if kind == "function" {
+					kind = "method"
+				}
+				return parent + "." + m[1], kind
+			}
+			return m[1], kind
+		}
+
+		return "", "statement"
+	}
+	return "", "comment"
+}
+
+func isCodeComment(line string) bool {
+	for _, prefix := range []string{"//", "/*", "*", "#", "--"} {
+		if strings.HasPrefix(line, prefix) && !strings.HasPrefix(line, "#[") && !strings.HasPrefix(line, "#include") {
+			return true
+		}
+	}
+	return false
+}
+
+// continuesStatement reports whether a code line leaves its statement open
+func continuesStatement(line string) bool {
+	if strings.HasPrefix(line, "@") || strings.HasPrefix(line, "#[") {
+		return true
+	}
+	for _, suffix := range []string{"=", ",", "(", "[", "&&", "||", "+", "-", "?", ":", "=>", ".", "\\"} {
+		if strings.HasSuffix(line, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// heuristicUnits splits lines [start, end) into top-level units. Oversized
+// containers such as classes are split again into their members, named
+// after the container.
+func (c *TextChunker) heuristicUnits(src *sourceLines, language string, start, end int, parent string, maxSize int) []codeUnit {
+	var units []codeUnit
+	if indentLanguages[language] {
+		units = indentUnits(src.lines, start, end)
+	} else {
+		units = braceUnits(src.lines, start, end, singleQuoteStrings[language])
+	}
+
+	var result []codeUnit
+	for _, unit := range units {
+		unit.symbol, unit.kind = codeSymbol(src.lines[unit.start:unit.end], parent)
+		if !containerKinds[unit.kind] || c.size(src.text(unit.start, unit.end)) <= maxSize {
+			result = append(result, unit)
+			continue
+		}
+
+		bodyStart, bodyEnd, ok := containerBody(src.lines, unit, indentLanguages[language], singleQuoteStrings[language])
+		if !ok {
+			result = append(result, unit)
+			continue
+		}
+
+		members := c.heuristicUnits(src, language, bodyStart, bodyEnd, unit.symbol, maxSize)
+		if len(members) == 0 {
+			result = append(result, unit)
+			continue
+		}
+
+		// The container header and closing line travel with the first and
+		// last member; a first member that is not a method is named after
+		// the container, since the header is what identifies it
+		members[0].start = unit.start
+		if members[0].kind != "method" {
+			members[0].symbol, members[0].kind = unit.symbol, unit.kind
+		}
+		members[len(members)-1].end = unit.end
+		result = append(result, members...)
+	}
+
+	return result
+}
+
+// containerBody returns the line range of a container's members: after the
+// line that opens its block and before the closing line
+func containerBody(lines []string, unit codeUnit, indented, singleQuotes bool) (int, int, bool) {
+	if indented {
+		for i := unit.start; i < unit.end; i++ {
+			line := strings.TrimSpace(lines[i])
+			if line != "" && !isCodeComment(line) && !strings.HasPrefix(line, "@") {
+				return i + 1, unit.end, i+1 < unit.end
+			}
+		}
+		return 0, 0, false
+	}
+
+	var scanner braceScanner
+	scanner.singleQuotes = singleQuotes
+	for i := unit.start; i < unit.end; i++ {
+		scanner.scan(lines[i])
+		if scanner.depth > 0 {
+			if scanner.depth != 1 {
+				return 0, 0, false
+			}
+			return i + 1, unit.end - 1, i+1 < unit.end-1
+		}
+	}
+	return 0, 0, false
+}
+
+// braceScanner tracks brace depth across lines, skipping strings and comments
+type braceScanner struct {
+	depth        int
+	quote        byte
+	blockComment bool
+	singleQuotes bool
+}
+
+func (s *braceScanner) scan(line string) {
+	for i := 0; i < len(line); i++ {
+		ch := line[i]
+		next := byte(0)
+		if i+1 < len(line) {
+			next = line[i+1]
+		}
+
+		switch {
+		case s.blockComment:
+			if ch == '*' && next == '/' {
+				s.blockComment = false
+				i++
+			}
+		case s.quote != 0:
+			if ch == '\\' {
+				i++
+			} else if ch == s.quote {
+				s.quote = 0
+			}
+		case ch == '/' && next == '/':
+			return
+		case ch == '/' && next == '*':
+			s.blockComment = true
+			i++
+		case ch == '"' || ch == '`':
+			s.quote = ch
+		case ch == '\'':
+			// Outside single-quote string languages only character
+			// literals such as 'x' or '\n' are quoted; lifetimes are not
+			if s.singleQuotes || (i+2 < len(line) && line[i+2] == '\'') || (next == '\\' && strings.IndexByte(line[i+2:min(i+8, len(line))], '\'') >= 0) {
+				s.quote = ch
+			}
+		case ch == '{':
+			s.depth++
+		case ch == '}':
+			if s.depth > 0 {
+				s.depth--
+			}
+		}
+	}
+
+	// Only template literals and raw strings span lines
+	if s.quote != '`' {
+		s.quote = 0
+	}
+}
+
+func (s *braceScanner) atTopLevel() bool {
+	return s.depth == 0 && s.quote == 0 && !s.blockComment
+}
+
+// braceUnits splits lines of a brace language into top-level units. A unit
+// ends when its braces close, at a semicolon or blank line at depth zero, or
+// before a new declaration starts. Leading comments and decorators stay with
+// the declaration they precede.
+func braceUnits(lines []string, start, end int, singleQuotes bool) []codeUnit {
+	var (
+		units    []codeUnit
+		scanner  = braceScanner{singleQuotes: singleQuotes}
+		current  = -1
+		hasCode  bool
+		opened   bool
+		prevCode string
+	)
+
+	closeUnit := func(at int) {
+		if current >= 0 && at > current {
+			units = append(units, codeUnit{start: current, end: at})
+		}
+		current, hasCode, opened, prevCode = -1, false, false, ""
+	}
+
+	for i := start; i < end; i++ {
+		line := strings.TrimSpace(lines[i])
+		topLevel := scanner.atTopLevel()
+
+		if topLevel {
+			if line == "" {
+				if hasCode {
+					closeUnit(i)
+				}
+				continue
+			}
+			if hasCode && codeDeclStart.MatchString(line) && !continuesStatement(prevCode) {
+				closeUnit(i)
+			}
+			if current < 0 {
+				current = i
+			}
+		}
+
+		scanner.scan(lines[i])
+
+		isCode := line != "" && !isCodeComment(line)
+		if isCode {
+			hasCode = true
+			prevCode = line
+		}
+		if scanner.depth > 0 {
+			opened = true
+		}
+
+		if isCode && scanner.atTopLevel() && !strings.HasPrefix(line, "@") {
+			if opened || strings.HasSuffix(line, ";") {
+				closeUnit(i + 1)
+			}
+		}
+	}
+
+	closeUnit(end)
+	return units
+}
+
+// indentUnits splits lines of an indentation language into units that each
+// start at the indentation of the first code line. Comments and decorators
+// stay with the declaration they precede; closing keywords such as "end"
+// stay with their block.
+func indentUnits(lines []string, start, end int) []codeUnit {
+	base := -1
+	for i := start; i < end; i++ {
+		if line := strings.TrimSpace(lines[i]); line != "" {
+			base = len(lines[i]) - len(strings.TrimLeft(lines[i], " \t"))
+			break
+		}
+	}
+	if base < 0 {
+		return nil
+	}
+
+	var (
+		units     []codeUnit
+		current   = -1
+		hasCode   bool
+		brackets  int
+		docstring string
+	)
+
+	for i := start; i < end; i++ {
+		raw := lines[i]
+		line := strings.TrimSpace(raw)
+		indent := len(raw) - len(strings.TrimLeft(raw, " \t"))
+
+		atBase := line != "" && indent <= base && brackets == 0 && docstring == ""
+		if atBase && !isBlockCloser(line) {
+			leading := isCodeComment(line) || strings.HasPrefix(line, "@")
+			if hasCode && current >= 0 {
+				units = append(units, codeUnit{start: current, end: i})
+				current, hasCode = -1, false
+			}
+			if current < 0 {
+				current = i
+			}
+			if !leading {
+				hasCode = true
+			}
+		}
+
+		// Track open brackets and triple-quoted strings so that
+		// continuation lines are not read as new statements
+		for _, quote := range []string{`"""`, `'''`} {
+			if n := strings.Count(line, quote); n%2 == 1 {
+				if docstring == "" {
+					docstring = quote
+				} else if docstring == quote {
+					docstring = ""
+				}
+			}
+		}
+		if docstring == "" && !strings.HasPrefix(line, "#") {
+			brackets += strings.Count(line, "(") + strings.Count(line, "[") + strings.Count(line, "{")
+			brackets -= strings.Count(line, ")") + strings.Count(line, "]") + strings.Count(line, "}")
+			if brackets < 0 {
+				brackets = 0
+			}
+		}
+		if strings.HasSuffix(line, "\\") && brackets == 0 {
+			brackets = 1
+		} else if brackets == 1 && i > start && strings.HasSuffix(strings.TrimSpace(lines[i-1]), "\\") && !strings.HasSuffix(line, "\\") {
+			brackets = 0
+		}
+	}
+
+	if current >= 0 {
+		units = append(units, codeUnit{start: current, end: end})
+	}
+
+	return units
+}
+
+// isBlockCloser reports whether a line only closes a block
+func isBlockCloser(line string) bool {
+	switch strings.TrimRight(line, ";,") {
+	case "end", "}", ")", "]", "})", "end)", "fi", "done", "esac":
+		return true
+	}
+	return false
+}
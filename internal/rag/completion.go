@@ -0,0 +1,35 @@
+package rag
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/PerceptivePenguin/MCPRAG-Go/internal/chat"
+	"github.com/PerceptivePenguin/MCPRAG-Go/pkg/types"
+)
+
+// chatCompleter sends single-prompt requests through a chat client. The
+// client keeps a message history and is not safe for concurrent use, so
+// requests are serialized and the history is cleared around each of them;
+// the client should not be shared with a conversation.
+type chatCompleter struct {
+	client chat.ChatClient
+	mu     sync.Mutex
+}
+
+// complete sends prompt as a user message and returns the reply text
+func (c *chatCompleter) complete(ctx context.Context, prompt string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.client.ClearMessages()
+	defer c.client.ClearMessages()
+
+	resp, err := c.client.Chat(ctx, []types.Message{{Role: types.RoleUser, Content: prompt}})
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(resp.Content), nil
+}
@@ -0,0 +1,650 @@
+package rag
+
+import (
+	"bufio"
+	"container/list"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/PerceptivePenguin/MCPRAG-Go/internal/vector"
+)
+
+// DefaultDiskCacheMaxSize bounds the disk cache when CacheConfig.DiskMaxSize
+// is zero
+const DefaultDiskCacheMaxSize = 1 << 30
+
+// diskCacheFile is the log file inside CacheConfig.DiskPath
+const diskCacheFile = "embeddings.log"
+
+// diskCacheMagic starts every log file and names its format version
+var diskCacheMagic = []byte("MCPRAGC1")
+
+// Log record operations
+const (
+	diskOpSet    byte = 1
+	diskOpDelete byte = 2
+)
+
+// diskRecordHeader is the size of the CRC-32 and length preceding each
+// record body
+const diskRecordHeader = 8
+
+// DiskCache implements the Cache interface on disk, as an append-only log of
+// binary records in a directory. Every record carries a CRC-32, so a write
+// cut short by a crash is detected and dropped when the log is opened
+// again; the log is rewritten to a temporary file and renamed into place, so
+// it is never left half written. An index of the live records is kept in
+// memory and rebuilt from the log on startup.
+//
+// Live records are bounded by DiskMaxSize bytes, evicting the least
+// recently used, and the log is compacted before dead records outgrow
+// them, so it takes at most twice that. Embeddings of a model do not go
+// stale, so the TTL of the configuration does not apply.
+type DiskCache struct {
+	mu        sync.Mutex
+	path      string
+	file      *os.File
+	end       int64                    // log length
+	items     map[string]*list.Element // of *diskRecord
+	recency   *list.List               // most recently used first
+	liveBytes int64
+	maxBytes  int64
+	metrics   CacheStats
+	closed    bool
+}
+
+// diskRecord locates a live record in the log
+type diskRecord struct {
+	key    string
+	offset int64
+	length int64 // including the record header
+	hits   int
+}
+
+// NewDiskCache opens or creates the disk cache in config.DiskPath and
+// indexes its contents
+func NewDiskCache(config *CacheConfig) (*DiskCache, error) {
+	if config == nil || config.DiskPath == "" {
+		return nil, ValidationError("disk_path", "disk cache path is required")
+	}
+	if config.DiskMaxSize < 0 {
+		return nil, ErrInvalidCacheSize
+	}
+
+	maxBytes := config.DiskMaxSize
+	if maxBytes == 0 {
+		maxBytes = DefaultDiskCacheMaxSize
+	}
+
+	if err := os.MkdirAll(config.DiskPath, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	cache := &DiskCache{
+		path:     filepath.Join(config.DiskPath, diskCacheFile),
+		items:    make(map[string]*list.Element),
+		recency:  list.New(),
+		maxBytes: maxBytes,
+	}
+
+	if err := cache.open(); err != nil {
+		return nil, err
+	}
+
+	// The limit may have shrunk since the log was written
+	if err := cache.evict(); err != nil {
+		cache.file.Close()
+		return nil, err
+	}
+
+	return cache, nil
+}
+
+// Get retrieves a cached embedding by key
+func (c *DiskCache) Get(ctx context.Context, key string) (*CacheEntry, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil, ErrCacheClosed
+	}
+
+	elem, exists := c.items[key]
+	if !exists {
+		c.metrics.Misses++
+		c.updateHitRate()
+		return nil, ErrCacheKeyNotFound
+	}
+
+	rec := elem.Value.(*diskRecord)
+	buf := make([]byte, rec.length)
+	if _, err := c.file.ReadAt(buf, rec.offset); err != nil {
+		return nil, fmt.Errorf("failed to read cache record: %w", err)
+	}
+	_, entry, err := decodeDiskRecord(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	c.recency.MoveToFront(elem)
+	rec.hits++
+
+	entry.AccessedAt = time.Now()
+	entry.AccessCount = rec.hits
+	entry.Size = int(rec.length)
+
+	c.metrics.Hits++
+	c.updateHitRate()
+
+	return entry, nil
+}
+
+// Set appends an embedding to the log
+func (c *DiskCache) Set(ctx context.Context, key string, vector vector.Vector, metadata map[string]string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return ErrCacheClosed
+	}
+
+	now := time.Now()
+	record := encodeDiskRecord(diskOpSet, key, now, vector, metadata)
+	offset, err := c.append(record)
+	if err != nil {
+		return err
+	}
+
+	hits := 0
+	if elem, exists := c.items[key]; exists {
+		hits = elem.Value.(*diskRecord).hits
+		c.forget(elem)
+	}
+	c.index(&diskRecord{
+		key:    key,
+		offset: offset,
+		length: int64(len(record)),
+		hits:   hits,
+	})
+
+	return c.evict()
+}
+
+// Delete removes an entry from the cache
+func (c *DiskCache) Delete(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return ErrCacheClosed
+	}
+
+	elem, exists := c.items[key]
+	if !exists {
+		return ErrCacheKeyNotFound
+	}
+
+	if _, err := c.append(encodeDiskRecord(diskOpDelete, key, time.Now(), nil, nil)); err != nil {
+		return err
+	}
+	c.forget(elem)
+
+	return c.compactIfSparse()
+}
+
+// Clear removes all entries from the cache
+func (c *DiskCache) Clear(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return ErrCacheClosed
+	}
+
+	c.items = make(map[string]*list.Element)
+	c.recency.Init()
+	c.liveBytes = 0
+	c.metrics.Size = 0
+
+	return c.compact()
+}
+
+// Size returns the number of cached entries
+func (c *DiskCache) Size() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.items)
+}
+
+// Stats returns cache statistics; DiskUsage is the length of the log
+func (c *DiskCache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.metrics
+	stats.DiskUsage = c.end
+	return stats
+}
+
+// Close flushes the log to stable storage and closes it
+func (c *DiskCache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+
+	if err := c.file.Sync(); err != nil {
+		c.file.Close()
+		return fmt.Errorf("failed to sync cache log: %w", err)
+	}
+	return c.file.Close()
+}
+
+// Recent returns up to n of the most recently used entries, least recent
+// first, for warming a faster cache tier
+func (c *DiskCache) Recent(n int) []*CacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil
+	}
+
+	var entries []*CacheEntry
+	for elem := c.recency.Front(); elem != nil && len(entries) < n; elem = elem.Next() {
+		rec := elem.Value.(*diskRecord)
+		buf := make([]byte, rec.length)
+		if _, err := c.file.ReadAt(buf, rec.offset); err != nil {
+			continue
+		}
+		if _, entry, err := decodeDiskRecord(buf); err == nil {
+			entries = append(entries, entry)
+		}
+	}
+
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries
+}
+
+// Index maintenance
+
+// index adds a live record as the most recently used
+func (c *DiskCache) index(rec *diskRecord) {
+	c.items[rec.key] = c.recency.PushFront(rec)
+	c.liveBytes += rec.length
+	c.metrics.Size = len(c.items)
+}
+
+// forget drops a record from the index; its bytes stay in the log until the
+// next compaction
+func (c *DiskCache) forget(elem *list.Element) {
+	rec := c.recency.Remove(elem).(*diskRecord)
+	delete(c.items, rec.key)
+	c.liveBytes -= rec.length
+	c.metrics.Size = len(c.items)
+}
+
+// evict forgets the least recently used records while the live records
+// exceed the size limit, compacting the log when that leaves it sparse
+func (c *DiskCache) evict() error {
+	for c.liveBytes > c.maxBytes && c.recency.Len() > 0 {
+		c.forget(c.recency.Back())
+	}
+	return c.compactIfSparse()
+}
+
+// compactIfSparse rewrites the log once more than half of it is dead
+func (c *DiskCache) compactIfSparse() error {
+	dead := c.end - int64(len(diskCacheMagic)) - c.liveBytes
+	if dead <= c.liveBytes {
+		return nil
+	}
+	return c.compact()
+}
+
+// Log file operations
+
+// open reads the log, indexing its live records, and truncates a torn
+// record left at its end
+func (c *DiskCache) open() error {
+	file, err := os.OpenFile(c.path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open cache log: %w", err)
+	}
+
+	end, err := c.load(file)
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Truncate(end); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to truncate cache log: %w", err)
+	}
+	if _, err := file.Seek(end, io.SeekStart); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to seek cache log: %w", err)
+	}
+
+	c.file = file
+	c.end = end
+	return nil
+}
+
+// load indexes the records of file and returns the length of its valid
+// prefix. An empty file gets the format header.
+func (c *DiskCache) load(file *os.File) (int64, error) {
+	reader := bufio.NewReader(file)
+
+	magic := make([]byte, len(diskCacheMagic))
+	n, err := io.ReadFull(reader, magic)
+	if n == 0 && err == io.EOF {
+		if _, err := file.WriteAt(diskCacheMagic, 0); err != nil {
+			return 0, fmt.Errorf("failed to write cache log header: %w", err)
+		}
+		return int64(len(diskCacheMagic)), nil
+	}
+	if err != nil || string(magic) != string(diskCacheMagic) {
+		return 0, fmt.Errorf("%s is not an embedding cache log", c.path)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat cache log: %w", err)
+	}
+
+	var records []*diskRecord
+	offset := int64(len(diskCacheMagic))
+	header := make([]byte, diskRecordHeader)
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			break // end of log, or a torn header
+		}
+		length := int64(binary.LittleEndian.Uint32(header[4:]))
+		if offset+diskRecordHeader+length > info.Size() {
+			break // torn body
+		}
+		body := make([]byte, length)
+		if _, err := io.ReadFull(reader, body); err != nil {
+			break // torn body
+		}
+		if crc32.ChecksumIEEE(body) != binary.LittleEndian.Uint32(header) {
+			break // corrupt from here on
+		}
+
+		op, key, err := decodeDiskRecordKey(body)
+		if err != nil {
+			break
+		}
+		if op == diskOpSet {
+			records = append(records, &diskRecord{
+				key:    key,
+				offset: offset,
+				length: diskRecordHeader + length,
+			})
+		} else {
+			records = append(records, &diskRecord{key: key, length: -1})
+		}
+		offset += diskRecordHeader + length
+	}
+
+	// Replay in write order; later records replace earlier ones and the
+	// last written is the most recently used
+	for _, rec := range records {
+		if elem, exists := c.items[rec.key]; exists {
+			c.forget(elem)
+		}
+		if rec.length > 0 {
+			c.index(rec)
+		}
+	}
+
+	return offset, nil
+}
+
+// append writes a record at the end of the log and returns its offset
+func (c *DiskCache) append(record []byte) (int64, error) {
+	offset := c.end
+	if _, err := c.file.Write(record); err != nil {
+		// Cut off whatever part was written, so the next record starts clean
+		c.file.Truncate(offset)
+		c.file.Seek(offset, io.SeekStart)
+		return 0, fmt.Errorf("failed to write cache record: %w", err)
+	}
+	c.end += int64(len(record))
+	return offset, nil
+}
+
+// compact writes the live records to a new log, in recency order so that
+// loading it restores the order, and atomically replaces the old one
+func (c *DiskCache) compact() error {
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), diskCacheFile+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create cache log: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // fails harmlessly once renamed
+
+	writer := bufio.NewWriter(tmp)
+	writer.Write(diskCacheMagic)
+	offset := int64(len(diskCacheMagic))
+
+	var records []*diskRecord
+	for elem := c.recency.Back(); elem != nil; elem = elem.Prev() {
+		records = append(records, elem.Value.(*diskRecord))
+	}
+	offsets := make([]int64, len(records))
+	for i, rec := range records {
+		buf := make([]byte, rec.length)
+		if _, err := c.file.ReadAt(buf, rec.offset); err != nil {
+			tmp.Close()
+			return fmt.Errorf("failed to read cache record: %w", err)
+		}
+		writer.Write(buf)
+		offsets[i] = offset
+		offset += rec.length
+	}
+
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write cache log: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync cache log: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to replace cache log: %w", err)
+	}
+	syncDir(filepath.Dir(c.path))
+
+	c.file.Close()
+	c.file = tmp
+	c.end = offset
+	for i, rec := range records {
+		rec.offset = offsets[i]
+	}
+
+	return nil
+}
+
+// syncDir makes a rename in dir durable where the platform allows it
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
+
+func (c *DiskCache) updateHitRate() {
+	total := c.metrics.Hits + c.metrics.Misses
+	if total > 0 {
+		c.metrics.HitRate = float64(c.metrics.Hits) / float64(total)
+	}
+}
+
+// Record encoding
+//
+// A record is a little-endian CRC-32 of the body, the body length as a
+// uint32 and the body: the operation byte, the write time in Unix
+// nanoseconds as a varint, then the uvarint-length-prefixed key; set records
+// continue with the uvarint dimension, the float32 components and the
+// uvarint-counted metadata pairs, sorted by key, each string
+// uvarint-length-prefixed.
+
+func encodeDiskRecord(op byte, key string, createdAt time.Time, vec vector.Vector, metadata map[string]string) []byte {
+	body := []byte{op}
+	body = binary.AppendVarint(body, createdAt.UnixNano())
+	body = appendDiskString(body, key)
+
+	if op == diskOpSet {
+		body = binary.AppendUvarint(body, uint64(len(vec)))
+		for _, v := range vec {
+			body = binary.LittleEndian.AppendUint32(body, math.Float32bits(v))
+		}
+
+		keys := make([]string, 0, len(metadata))
+		for k := range metadata {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		body = binary.AppendUvarint(body, uint64(len(keys)))
+		for _, k := range keys {
+			body = appendDiskString(body, k)
+			body = appendDiskString(body, metadata[k])
+		}
+	}
+
+	record := make([]byte, diskRecordHeader, diskRecordHeader+len(body))
+	binary.LittleEndian.PutUint32(record, crc32.ChecksumIEEE(body))
+	binary.LittleEndian.PutUint32(record[4:], uint32(len(body)))
+	return append(record, body...)
+}
+
+func appendDiskString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+// errCorruptDiskRecord reports a record that passed its checksum but does
+// not decode, which only a format mismatch can cause
+var errCorruptDiskRecord = errors.New("corrupt cache record")
+
+// diskReader decodes the fields of a record body
+type diskReader struct {
+	buf []byte
+	err error
+}
+
+func (r *diskReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		r.err = errCorruptDiskRecord
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *diskReader) varint() int64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(r.buf)
+	if n <= 0 {
+		r.err = errCorruptDiskRecord
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *diskReader) bytes(n uint64) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if uint64(len(r.buf)) < n {
+		r.err = errCorruptDiskRecord
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *diskReader) string() string {
+	return string(r.bytes(r.uvarint()))
+}
+
+// decodeDiskRecordKey decodes the operation and key of a body
+func decodeDiskRecordKey(body []byte) (byte, string, error) {
+	if len(body) == 0 {
+		return 0, "", errCorruptDiskRecord
+	}
+	r := &diskReader{buf: body[1:]}
+	r.varint() // write time
+	key := r.string()
+	return body[0], key, r.err
+}
+
+// decodeDiskRecord decodes a whole record read from the log
+func decodeDiskRecord(record []byte) (byte, *CacheEntry, error) {
+	if len(record) < diskRecordHeader {
+		return 0, nil, errCorruptDiskRecord
+	}
+	body := record[diskRecordHeader:]
+	if crc32.ChecksumIEEE(body) != binary.LittleEndian.Uint32(record) {
+		return 0, nil, errCorruptDiskRecord
+	}
+
+	r := &diskReader{buf: body[1:]}
+	entry := &CacheEntry{}
+	entry.CreatedAt = time.Unix(0, r.varint())
+	entry.Key = r.string()
+
+	dimension := r.uvarint()
+	raw := r.bytes(dimension * 4)
+	if r.err == nil {
+		entry.Vector = make(vector.Vector, dimension)
+		for i := range entry.Vector {
+			entry.Vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(raw[i*4:]))
+		}
+	}
+
+	if count := r.uvarint(); count > 0 && r.err == nil {
+		entry.Metadata = make(map[string]string, count)
+		for i := uint64(0); i < count && r.err == nil; i++ {
+			k := r.string()
+			entry.Metadata[k] = r.string()
+		}
+	}
+
+	if r.err != nil {
+		return 0, nil, r.err
+	}
+	entry.AccessedAt = entry.CreatedAt
+	entry.Size = len(record)
+	return body[0], entry, nil
+}
+
+// Ensure DiskCache implements Cache
+var _ Cache = (*DiskCache)(nil)
@@ -71,6 +71,30 @@ func NewOpenAIEmbedder(config *EmbeddingConfig, cache Cache) (*OpenAIEmbedder, e
 	return embedder, nil
 }
 
+// NewEmbedder creates the embedder of config.Provider. The cache is only
+// used by embedders calling a remote model; the hashing embedder computes
+// vectors faster than it could look them up and leaves it alone.
+func NewEmbedder(config *EmbeddingConfig, cache Cache) (Embedder, error) {
+	if config == nil {
+		config = DefaultEmbeddingConfig()
+	}
+
+	switch config.Provider {
+	case "", EmbeddingProviderOpenAI:
+		return NewOpenAIEmbedder(config, cache)
+	case EmbeddingProviderHashing:
+		hashing := DefaultHashingEmbedderConfig()
+		if config.Dimensions > 0 {
+			hashing.Dimension = config.Dimensions
+		}
+		return NewHashingEmbedder(hashing)
+	case EmbeddingProviderOpenAICompatible, EmbeddingProviderTEI, EmbeddingProviderOllama, EmbeddingProviderCohere:
+		return NewHTTPEmbedder(config, cache)
+	default:
+		return nil, ValidationError("provider", "unsupported embedding provider: "+string(config.Provider))
+	}
+}
+
 // Embed generates an embedding for a single text
 func (e *OpenAIEmbedder) Embed(ctx context.Context, text string) (*EmbeddingResponse, error) {
 	if strings.TrimSpace(text) == "" {
@@ -24,8 +24,9 @@ var (
 	ErrQueryEmpty           = NewRAGError("query text is empty", ErrorTypeValidation)
 	ErrQueryTooLong         = NewRAGError("query text is too long", ErrorTypeValidation)
 	ErrInvalidTopK          = NewRAGError("topK must be positive", ErrorTypeValidation)
-	ErrInvalidThreshold     = NewRAGError("threshold must be between 0 and 1", ErrorTypeValidation)
+	ErrInvalidThreshold     = NewRAGError("threshold is out of range for the similarity metric", ErrorTypeValidation)
 	ErrInvalidStrategy      = NewRAGError("invalid search strategy", ErrorTypeValidation)
+	ErrInvalidFilter        = NewRAGError("invalid metadata filter", ErrorTypeValidation)
 	
 	// Cache errors
 	ErrCacheKeyNotFound     = NewRAGError("cache key not found", ErrorTypeNotFound)
@@ -51,6 +52,10 @@ var (
 	ErrVectorStoreFull      = NewRAGError("vector store at capacity", ErrorTypeCapacity)
 	ErrVectorSearchFailed   = NewRAGError("vector search failed", ErrorTypeInternal)
 	
+	// Index errors
+	ErrIndexNotFound        = NewRAGError("index not found", ErrorTypeNotFound)
+	ErrIndexExists          = NewRAGError("index already exists", ErrorTypeConflict)
+	
 	// Configuration errors
 	ErrInvalidConfig        = NewRAGError("invalid configuration", ErrorTypeValidation)
 	ErrMissingConfig        = NewRAGError("missing required configuration", ErrorTypeValidation)
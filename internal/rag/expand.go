@@ -0,0 +1,286 @@
+package rag
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ExpandMode selects how matched chunks are widened before they are
+// returned: search runs over small chunks, which match precisely, and the
+// results carry the text around them, which is what answers need
+type ExpandMode string
+
+const (
+	// ExpandNone returns the matched chunks themselves
+	ExpandNone ExpandMode = ""
+
+	// ExpandNeighbors returns each matched chunk with up to ExpandWindow
+	// chunks of the same document before and after it
+	ExpandNeighbors ExpandMode = "neighbors"
+
+	// ExpandParent returns each matched chunk with the chunks of its
+	// Markdown section, or of its whole document when it has none
+	ExpandParent ExpandMode = "parent"
+)
+
+// DefaultExpandMaxTokens is the token budget of an expanded result when the
+// query sets none
+const DefaultExpandMaxTokens = 1024
+
+// defaultExpandWindow is the number of neighbors on each side when the
+// query sets none
+const defaultExpandWindow = 1
+
+// Metadata keys of expanded results: the first and last chunk index the
+// content was built from
+const (
+	MetadataExpandStart = "expand_start"
+	MetadataExpandEnd   = "expand_end"
+)
+
+// minChunkOverlap is the shortest overlap between consecutive chunks that is
+// taken for repeated text rather than coincidence when they are joined
+const minChunkOverlap = 8
+
+// expansion is a run of consecutive chunks of one document built around a
+// matched chunk
+type expansion struct {
+	parent string
+	lo, hi int // chunk indexes, inclusive
+	tokens int
+	result int // index of the result the run belongs to
+}
+
+// expand replaces the matched chunks in result by the runs of chunks around
+// them chosen by query.Expand. Runs grow outward from the matched chunk,
+// alternately backwards and forwards, until they hold ExpandMaxTokens
+// tokens. A chunk is returned at most once: results whose chunk is already
+// part of a better result's run are dropped, and runs stop at chunks taken
+// by better results. Adjacent runs of one document are merged when they fit
+// the budget together. Results that are not stored chunks are kept as is.
+func (r *BasicRetriever) expand(query Query, result *RetrievalResult) {
+	if query.Expand == ExpandNone || len(result.Documents) == 0 {
+		return
+	}
+
+	window := query.ExpandWindow
+	if window == 0 {
+		window = defaultExpandWindow
+	}
+	budget := query.ExpandMaxTokens
+	if budget == 0 {
+		budget = DefaultExpandMaxTokens
+	}
+
+	// Chunks fetched from the store by parent and index, nil when missing
+	chunks := make(map[string]map[int]*Document)
+	chunk := func(parent string, index int) *Document {
+		byIndex, ok := chunks[parent]
+		if !ok {
+			byIndex = make(map[int]*Document)
+			chunks[parent] = byIndex
+		}
+		if doc, ok := byIndex[index]; ok {
+			return doc
+		}
+
+		var doc *Document
+		if vd, err := r.vectorStore.Get(fmt.Sprintf("%s_chunk_%d", parent, index)); err == nil && vd.Metadata[MetadataDocumentID] == parent {
+			d := documentFromVector(*vd)
+			doc = &d
+		}
+		byIndex[index] = doc
+		return doc
+	}
+
+	// Chunk indexes taken by earlier runs, by parent
+	taken := make(map[string]map[int]bool)
+	var runs []*expansion
+	keep := make([]bool, len(result.Documents))
+
+	for i, doc := range result.Documents {
+		if doc.ParentID == "" || chunk(doc.ParentID, doc.ChunkIndex) == nil {
+			keep[i] = true
+			continue
+		}
+		parent, index := doc.ParentID, doc.ChunkIndex
+		if taken[parent][index] {
+			continue
+		}
+		keep[i] = true
+
+		matched := chunk(parent, index)
+		section := matched.Metadata[MetadataSection]
+		fits := func(next int) *Document {
+			if taken[parent][next] {
+				return nil
+			}
+			if query.Expand == ExpandNeighbors && (next < index-window || next > index+window) {
+				return nil
+			}
+			neighbor := chunk(parent, next)
+			if neighbor == nil {
+				return nil
+			}
+			if query.Expand == ExpandParent && neighbor.Metadata[MetadataSection] != section {
+				return nil
+			}
+			return neighbor
+		}
+
+		run := &expansion{parent: parent, lo: index, hi: index, tokens: r.countTokens(matched.Content), result: i}
+		for backward, forward := true, true; backward || forward; {
+			if backward {
+				backward = false
+				if neighbor := fits(run.lo - 1); neighbor != nil {
+					if tokens := r.countTokens(neighbor.Content); run.tokens+tokens <= budget {
+						run.lo--
+						run.tokens += tokens
+						backward = true
+					}
+				}
+			}
+			if forward {
+				forward = false
+				if neighbor := fits(run.hi + 1); neighbor != nil {
+					if tokens := r.countTokens(neighbor.Content); run.tokens+tokens <= budget {
+						run.hi++
+						run.tokens += tokens
+						forward = true
+					}
+				}
+			}
+		}
+
+		if taken[parent] == nil {
+			taken[parent] = make(map[int]bool)
+		}
+		for k := run.lo; k <= run.hi; k++ {
+			taken[parent][k] = true
+		}
+		runs = append(runs, run)
+	}
+
+	// Merge adjacent runs of a document into the better one
+	merged := make([]bool, len(runs))
+	for a, run := range runs {
+		if merged[a] {
+			continue
+		}
+		for b := a + 1; b < len(runs); b++ {
+			other := runs[b]
+			if merged[b] || other.parent != run.parent || run.tokens+other.tokens > budget {
+				continue
+			}
+			if other.hi+1 != run.lo && run.hi+1 != other.lo {
+				continue
+			}
+			run.lo = min(run.lo, other.lo)
+			run.hi = max(run.hi, other.hi)
+			run.tokens += other.tokens
+			merged[b] = true
+			keep[other.result] = false
+		}
+	}
+
+	for a, run := range runs {
+		if merged[a] {
+			continue
+		}
+
+		doc := &result.Documents[run.result]
+		parts := make([]string, 0, run.hi-run.lo+1)
+		for k := run.lo; k <= run.hi; k++ {
+			parts = append(parts, chunk(run.parent, k).Content)
+		}
+		doc.Content = joinChunks(parts)
+
+		metadata := make(map[string]string, len(doc.Metadata)+2)
+		for k, v := range doc.Metadata {
+			metadata[k] = v
+		}
+		metadata[MetadataExpandStart] = strconv.Itoa(run.lo)
+		metadata[MetadataExpandEnd] = strconv.Itoa(run.hi)
+
+		// The run spans from the start of its first chunk to the end of its last
+		first, last := chunk(run.parent, run.lo), chunk(run.parent, run.hi)
+		for _, key := range []string{MetadataStartPos, MetadataStartLine} {
+			if value, ok := first.Metadata[key]; ok {
+				metadata[key] = value
+			}
+		}
+		for _, key := range []string{MetadataEndPos, MetadataEndLine} {
+			if value, ok := last.Metadata[key]; ok {
+				metadata[key] = value
+			}
+		}
+		doc.Metadata = metadata
+	}
+
+	// Drop the results merged into others
+	documents := result.Documents[:0]
+	scores := result.Scores[:0]
+	var contributions [][]StrategyContribution
+	if len(result.Contributions) == len(result.Documents) {
+		contributions = result.Contributions[:0]
+	}
+	for i, doc := range result.Documents {
+		if !keep[i] {
+			continue
+		}
+		documents = append(documents, doc)
+		scores = append(scores, result.Scores[i])
+		if contributions != nil {
+			contributions = append(contributions, result.Contributions[i])
+		}
+	}
+	result.Documents = documents
+	result.Scores = scores
+	if contributions != nil {
+		result.Contributions = contributions
+	}
+	result.TotalFound = len(documents)
+}
+
+// countTokens counts tokens with the retriever's tokenizer, estimating four
+// bytes per token without one
+func (r *BasicRetriever) countTokens(text string) int {
+	r.mu.RLock()
+	tokenizer := r.tokenizer
+	r.mu.RUnlock()
+
+	if tokenizer != nil {
+		return tokenizer.CountTokens(text)
+	}
+	return (len(text) + 3) / 4
+}
+
+// joinChunks joins consecutive chunks of a document, dropping the text a
+// chunk repeats from the end of the one before when chunks overlap
+func joinChunks(parts []string) string {
+	var b strings.Builder
+	for i, part := range parts {
+		if i == 0 {
+			b.WriteString(part)
+			continue
+		}
+
+		prev := parts[i-1]
+		overlap := 0
+		for start := max(len(prev)-len(part), 0); start <= len(prev)-minChunkOverlap; start++ {
+			if strings.HasPrefix(part, prev[start:]) {
+				overlap = len(prev) - start
+				break
+			}
+		}
+
+		if overlap > 0 {
+			b.WriteString(part[overlap:])
+		} else {
+			b.WriteString("\n")
+			b.WriteString(part)
+		}
+	}
+	return b.String()
+}
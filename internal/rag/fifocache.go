@@ -0,0 +1,310 @@
+package rag
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/PerceptivePenguin/MCPRAG-Go/internal/vector"
+)
+
+// FIFOCache implements the Cache interface evicting the oldest entry,
+// however often it is used. Updating an entry does not make it younger.
+type FIFOCache struct {
+	mu      sync.RWMutex
+	items   map[string]*list.Element // of *fifoItem
+	order   *list.List               // oldest first
+	maxSize int
+	config  *CacheConfig
+	metrics CacheStats
+	closed  bool
+}
+
+// fifoItem is a cached entry in insertion order
+type fifoItem struct {
+	key      string
+	entry    *CacheEntry
+	expireAt time.Time
+}
+
+// NewFIFOCache creates a new FIFO cache with the given configuration
+func NewFIFOCache(config *CacheConfig) (*FIFOCache, error) {
+	if config == nil {
+		config = DefaultCacheConfig()
+	}
+
+	if config.MaxSize <= 0 {
+		return nil, ErrInvalidCacheSize
+	}
+
+	cache := &FIFOCache{
+		items:   make(map[string]*list.Element),
+		order:   list.New(),
+		maxSize: config.MaxSize,
+		config:  config,
+		metrics: CacheStats{
+			MaxSize: config.MaxSize,
+		},
+	}
+
+	// Load from persistence if configured
+	if config.PersistPath != "" {
+		if err := cache.loadFromDisk(); err != nil {
+			// Log error but don't fail initialization
+			fmt.Printf("Warning: failed to load cache from disk: %v\n", err)
+		}
+	}
+
+	return cache, nil
+}
+
+// Get retrieves a cached embedding by key
+func (c *FIFOCache) Get(ctx context.Context, key string) (*CacheEntry, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil, ErrCacheClosed
+	}
+
+	elem, exists := c.items[key]
+	if !exists {
+		c.metrics.Misses++
+		c.updateHitRate()
+		return nil, ErrCacheKeyNotFound
+	}
+
+	// Check if entry has expired
+	item := elem.Value.(*fifoItem)
+	if c.config.TTL > 0 && time.Now().After(item.expireAt) {
+		c.remove(elem)
+		c.metrics.Misses++
+		c.updateHitRate()
+		return nil, ErrCacheKeyNotFound
+	}
+
+	item.entry.AccessedAt = time.Now()
+	item.entry.AccessCount++
+
+	c.metrics.Hits++
+	c.updateHitRate()
+
+	return item.entry, nil
+}
+
+// Set stores an embedding in the cache
+func (c *FIFOCache) Set(ctx context.Context, key string, vector vector.Vector, metadata map[string]string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return ErrCacheClosed
+	}
+
+	now := time.Now()
+	var expireAt time.Time
+	if c.config.TTL > 0 {
+		expireAt = now.Add(c.config.TTL)
+	}
+
+	// Update in place, keeping the entry's position
+	if elem, exists := c.items[key]; exists {
+		item := elem.Value.(*fifoItem)
+		size := calculateCacheEntrySize(key, vector, metadata)
+		c.metrics.MemoryUsage += int64(size - item.entry.Size)
+		item.entry.Vector = vector
+		item.entry.Metadata = metadata
+		item.entry.AccessedAt = now
+		item.entry.AccessCount++
+		item.entry.Size = size
+		item.expireAt = expireAt
+		return nil
+	}
+
+	c.insert(&CacheEntry{
+		Key:         key,
+		Vector:      vector,
+		Metadata:    metadata,
+		CreatedAt:   now,
+		AccessedAt:  now,
+		AccessCount: 1,
+		Size:        calculateCacheEntrySize(key, vector, metadata),
+	}, expireAt)
+
+	// Evict the oldest entries if necessary
+	for len(c.items) > c.maxSize {
+		c.remove(c.order.Front())
+	}
+
+	return nil
+}
+
+// Delete removes an entry from the cache
+func (c *FIFOCache) Delete(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return ErrCacheClosed
+	}
+
+	elem, exists := c.items[key]
+	if !exists {
+		return ErrCacheKeyNotFound
+	}
+
+	c.remove(elem)
+	return nil
+}
+
+// Clear removes all entries from the cache
+func (c *FIFOCache) Clear(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return ErrCacheClosed
+	}
+
+	c.items = make(map[string]*list.Element)
+	c.order.Init()
+	c.metrics.Size = 0
+	c.metrics.MemoryUsage = 0
+
+	return nil
+}
+
+// Size returns the number of cached entries
+func (c *FIFOCache) Size() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.items)
+}
+
+// Stats returns cache statistics
+func (c *FIFOCache) Stats() CacheStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.metrics
+}
+
+// Close releases any resources held by the cache
+func (c *FIFOCache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil
+	}
+
+	// Persist to disk if configured
+	if c.config.PersistPath != "" {
+		if err := c.saveToDisk(); err != nil {
+			return fmt.Errorf("failed to persist cache: %w", err)
+		}
+	}
+
+	c.closed = true
+	return nil
+}
+
+// EvictExpiredEntries removes expired entries from the cache
+func (c *FIFOCache) EvictExpiredEntries(ctx context.Context) int {
+	if c.config.TTL == 0 {
+		return 0 // No TTL configured
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return 0
+	}
+
+	now := time.Now()
+	evicted := 0
+	for _, elem := range c.items {
+		if now.After(elem.Value.(*fifoItem).expireAt) {
+			c.remove(elem)
+			evicted++
+		}
+	}
+
+	return evicted
+}
+
+// insert appends an entry as the youngest
+func (c *FIFOCache) insert(entry *CacheEntry, expireAt time.Time) {
+	c.items[entry.Key] = c.order.PushBack(&fifoItem{key: entry.Key, entry: entry, expireAt: expireAt})
+	c.metrics.Size = len(c.items)
+	c.metrics.MemoryUsage += int64(entry.Size)
+}
+
+// remove deletes an entry from the queue and the index
+func (c *FIFOCache) remove(elem *list.Element) {
+	item := c.order.Remove(elem).(*fifoItem)
+	delete(c.items, item.key)
+	c.metrics.Size = len(c.items)
+	c.metrics.MemoryUsage -= int64(item.entry.Size)
+}
+
+func (c *FIFOCache) updateHitRate() {
+	total := c.metrics.Hits + c.metrics.Misses
+	if total > 0 {
+		c.metrics.HitRate = float64(c.metrics.Hits) / float64(total)
+	}
+}
+
+// Persistence methods
+
+func (c *FIFOCache) saveToDisk() error {
+	data := make(map[string]*CacheEntry)
+	for key, elem := range c.items {
+		item := elem.Value.(*fifoItem)
+		if c.config.TTL == 0 || time.Now().Before(item.expireAt) {
+			data[key] = item.entry
+		}
+	}
+
+	return writeCacheFile(c.config.PersistPath, data)
+}
+
+// loadFromDisk restores saved entries in the order they were created,
+// keeping the youngest ones when there are more than fit
+func (c *FIFOCache) loadFromDisk() error {
+	data, err := readCacheFile(c.config.PersistPath)
+	if err != nil {
+		return err
+	}
+
+	entries := make([]*CacheEntry, 0, len(data))
+	for key, entry := range data {
+		entry.Key = key
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].CreatedAt.Equal(entries[j].CreatedAt) {
+			return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+		}
+		return entries[i].Key < entries[j].Key
+	})
+	if len(entries) > c.maxSize {
+		entries = entries[len(entries)-c.maxSize:]
+	}
+
+	var expireAt time.Time
+	if c.config.TTL > 0 {
+		expireAt = time.Now().Add(c.config.TTL)
+	}
+	for _, entry := range entries {
+		c.insert(entry, expireAt)
+	}
+
+	return nil
+}
+
+// Ensure FIFOCache implements Cache
+var _ Cache = (*FIFOCache)(nil)
@@ -0,0 +1,208 @@
+package rag
+
+import (
+	"math"
+	"sort"
+)
+
+// FusionMethod defines how HybridRetriever merges the rankings of its
+// strategies
+type FusionMethod string
+
+const (
+	// FusionRRF is reciprocal rank fusion: each strategy contributes
+	// weight / (k + rank), so only ranks matter and raw scores of different
+	// scales never meet
+	FusionRRF FusionMethod = "rrf"
+
+	// FusionMinMax rescales each strategy's scores to [0, 1] with min-max
+	// normalization and sums them by weight
+	FusionMinMax FusionMethod = "min_max"
+
+	// FusionZScore standardizes each strategy's scores, maps the z-scores to
+	// [0, 1] through the normal CDF and sums them by weight
+	FusionZScore FusionMethod = "z_score"
+
+	// FusionCombMNZ is the min-max weighted sum multiplied by the number of
+	// strategies that found the document, favoring agreement
+	FusionCombMNZ FusionMethod = "comb_mnz"
+)
+
+// DefaultRRFK is the usual rank offset for reciprocal rank fusion
+const DefaultRRFK = 60
+
+// FusionConfig configures rank fusion in HybridRetriever
+type FusionConfig struct {
+	Method FusionMethod `json:"method"`
+
+	// RRFK is the rank offset of FusionRRF; zero means DefaultRRFK
+	RRFK int `json:"rrf_k,omitempty"`
+}
+
+// DefaultFusionConfig returns reciprocal rank fusion with the usual offset
+func DefaultFusionConfig() *FusionConfig {
+	return &FusionConfig{
+		Method: FusionRRF,
+		RRFK:   DefaultRRFK,
+	}
+}
+
+// Validate checks the fusion configuration
+func (c *FusionConfig) Validate() error {
+	switch c.Method {
+	case FusionRRF, FusionMinMax, FusionZScore, FusionCombMNZ:
+	default:
+		return ValidationError("method", "unsupported fusion method: "+string(c.Method))
+	}
+
+	if c.RRFK < 0 {
+		return ValidationError("rrf_k", "rrf_k must not be negative")
+	}
+
+	return nil
+}
+
+// fusedResult is a document with its fused score and the strategies that
+// found it
+type fusedResult struct {
+	doc           Document
+	score         float64
+	contributions []StrategyContribution
+}
+
+// fuseResults merges per-strategy results into a single ranking. Fused
+// scores are divided by the best attainable score, that of a document ranked
+// first by every strategy, so they lie in [0, 1] whatever the method. They
+// rank documents but are not similarities: under RRF a document ranked first
+// by one of two strategies scores 0.5, below one ranked tenth by both, so
+// thresholds are applied by the strategies before fusion. Nil results are
+// skipped.
+func fuseResults(results []*RetrievalResult, names []string, weights []float32, config FusionConfig) []*fusedResult {
+	rrfK := config.RRFK
+	if rrfK == 0 {
+		rrfK = DefaultRRFK
+	}
+
+	fused := make(map[string]*fusedResult)
+	var totalWeight float64
+
+	for i, result := range results {
+		if result == nil {
+			continue
+		}
+		weight := float64(weights[i])
+		totalWeight += weight
+
+		var normalize func(rank int) float64
+		switch config.Method {
+		case FusionRRF:
+			normalize = func(rank int) float64 {
+				return 1 / float64(rrfK+rank+1)
+			}
+		case FusionZScore:
+			normalize = zScoreNormalizer(result.Scores)
+		default:
+			normalize = minMaxNormalizer(result.Scores)
+		}
+
+		for rank, doc := range result.Documents {
+			part := weight * normalize(rank)
+
+			fr, ok := fused[doc.ID]
+			if !ok {
+				fr = &fusedResult{doc: doc}
+				fused[doc.ID] = fr
+			}
+			fr.score += part
+			fr.contributions = append(fr.contributions, StrategyContribution{
+				Strategy: names[i],
+				Rank:     rank + 1,
+				Score:    result.Scores[rank],
+				Fused:    float32(part),
+			})
+		}
+	}
+
+	best := totalWeight
+	switch config.Method {
+	case FusionRRF:
+		best = totalWeight / float64(rrfK+1)
+	case FusionCombMNZ:
+		best = totalWeight * float64(len(results))
+	}
+
+	ranked := make([]*fusedResult, 0, len(fused))
+	for _, fr := range fused {
+		scale := 1.0
+		if config.Method == FusionCombMNZ {
+			scale = float64(len(fr.contributions))
+		}
+		if best > 0 {
+			scale /= best
+		}
+
+		fr.score *= scale
+		for i := range fr.contributions {
+			fr.contributions[i].Fused = float32(float64(fr.contributions[i].Fused) * scale)
+		}
+		ranked = append(ranked, fr)
+	}
+
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].score != ranked[j].score {
+			return ranked[i].score > ranked[j].score
+		}
+		return ranked[i].doc.ID < ranked[j].doc.ID
+	})
+
+	return ranked
+}
+
+// minMaxNormalizer rescales scores to [0, 1]; when all scores are equal
+// every document gets 1
+func minMaxNormalizer(scores []float32) func(rank int) float64 {
+	if len(scores) == 0 {
+		return func(int) float64 { return 0 }
+	}
+
+	lo, hi := scores[0], scores[0]
+	for _, s := range scores[1:] {
+		lo = min(lo, s)
+		hi = max(hi, s)
+	}
+
+	return func(rank int) float64 {
+		if hi == lo {
+			return 1
+		}
+		return float64(scores[rank]-lo) / float64(hi-lo)
+	}
+}
+
+// zScoreNormalizer maps the z-score of each score to [0, 1] through the
+// standard normal CDF; when all scores are equal every document gets 0.5
+func zScoreNormalizer(scores []float32) func(rank int) float64 {
+	var mean, variance float64
+	for _, s := range scores {
+		mean += float64(s)
+	}
+	if len(scores) > 0 {
+		mean /= float64(len(scores))
+	}
+	for _, s := range scores {
+		d := float64(s) - mean
+		variance += d * d
+	}
+	if len(scores) > 0 {
+		variance /= float64(len(scores))
+	}
+	std := math.Sqrt(variance)
+
+	return func(rank int) float64 {
+		if std == 0 {
+			return 0.5
+		}
+		z := (float64(scores[rank]) - mean) / std
+		return 0.5 * math.Erfc(-z/math.Sqrt2)
+	}
+}
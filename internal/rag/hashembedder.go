@@ -0,0 +1,245 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/PerceptivePenguin/MCPRAG-Go/internal/vector"
+)
+
+// DefaultHashingDimension is the vector dimension of HashingEmbedder when
+// none is configured
+const DefaultHashingDimension = 384
+
+// HashingEmbedderConfig configures HashingEmbedder
+type HashingEmbedderConfig struct {
+	// Dimension is the length of the produced vectors
+	Dimension int `json:"dimension"`
+
+	// MinNGram and MaxNGram bound the length in runes of the character
+	// n-grams taken from every word; a zero MaxNGram disables n-grams
+	MinNGram int `json:"min_ngram"`
+	MaxNGram int `json:"max_ngram"`
+
+	// WordWeight and NGramWeight scale whole-word and n-gram features
+	WordWeight  float32 `json:"word_weight"`
+	NGramWeight float32 `json:"ngram_weight"`
+
+	// SplitIdentifiers also adds the camelCase and snake_case parts of
+	// identifiers as words
+	SplitIdentifiers bool `json:"split_identifiers"`
+}
+
+// DefaultHashingEmbedderConfig returns word and 3- to 5-gram features in
+// DefaultHashingDimension dimensions
+func DefaultHashingEmbedderConfig() *HashingEmbedderConfig {
+	return &HashingEmbedderConfig{
+		Dimension:        DefaultHashingDimension,
+		MinNGram:         3,
+		MaxNGram:         5,
+		WordWeight:       1,
+		NGramWeight:      0.5,
+		SplitIdentifiers: true,
+	}
+}
+
+// HashingEmbedder embeds text locally, without a model or network access,
+// by feature hashing: lowercased words and the character n-grams of each
+// word are hashed into a fixed number of dimensions with a hashed sign,
+// weighted by sublinear term frequency and L2 normalized. Texts sharing
+// words or word fragments get similar vectors, so it suits tests and
+// air-gapped setups rather than semantic search. The same text always yields
+// the same vector.
+type HashingEmbedder struct {
+	config HashingEmbedderConfig
+	model  string
+}
+
+// NewHashingEmbedder creates a hashing embedder
+func NewHashingEmbedder(config *HashingEmbedderConfig) (*HashingEmbedder, error) {
+	if config == nil {
+		config = DefaultHashingEmbedderConfig()
+	}
+
+	if config.Dimension <= 0 {
+		return nil, NewRAGErrorWithOp("new_hashing_embedder", "dimension must be positive", ErrorTypeValidation)
+	}
+	if config.MaxNGram > 0 && (config.MinNGram <= 0 || config.MinNGram > config.MaxNGram) {
+		return nil, NewRAGErrorWithOp("new_hashing_embedder", "min_ngram must be between 1 and max_ngram", ErrorTypeValidation)
+	}
+	if config.WordWeight < 0 || config.NGramWeight < 0 || config.WordWeight+config.NGramWeight == 0 {
+		return nil, NewRAGErrorWithOp("new_hashing_embedder", "feature weights must not be negative and not both zero", ErrorTypeValidation)
+	}
+
+	return &HashingEmbedder{
+		config: *config,
+		model:  fmt.Sprintf("hashing-%d", config.Dimension),
+	}, nil
+}
+
+// Embed generates an embedding for a single text
+func (e *HashingEmbedder) Embed(ctx context.Context, text string) (*EmbeddingResponse, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, ErrQueryEmpty.WithOperation("embed")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, NewRAGErrorWithCause("embedding cancelled", ErrorTypeInternal, err).WithOperation("embed")
+	}
+
+	return e.embed(text), nil
+}
+
+// EmbedBatch generates embeddings for multiple texts; blank texts get nil
+// entries like with OpenAIEmbedder
+func (e *HashingEmbedder) EmbedBatch(ctx context.Context, texts []string) ([]*EmbeddingResponse, error) {
+	if len(texts) == 0 {
+		return nil, NewRAGErrorWithOp("embed_batch", "no texts provided", ErrorTypeValidation)
+	}
+
+	results := make([]*EmbeddingResponse, len(texts))
+	embedded := 0
+	for i, text := range texts {
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, NewRAGErrorWithCause("embedding cancelled", ErrorTypeInternal, err).WithOperation("embed_batch")
+		}
+		results[i] = e.embed(text)
+		embedded++
+	}
+
+	if embedded == 0 {
+		return nil, ErrQueryEmpty.WithOperation("embed_batch")
+	}
+
+	return results, nil
+}
+
+// EmbedWithOptions generates an embedding carrying the request metadata.
+// The model of the request cannot be changed.
+func (e *HashingEmbedder) EmbedWithOptions(ctx context.Context, req EmbeddingRequest) (*EmbeddingResponse, error) {
+	if req.Model != "" && req.Model != e.model {
+		return nil, NewRAGErrorWithOp("embed_with_options",
+			fmt.Sprintf("hashing embedder cannot embed with model %s", req.Model), ErrorTypeValidation)
+	}
+
+	response, err := e.Embed(ctx, req.Text)
+	if err != nil {
+		return nil, err
+	}
+
+	response.Metadata = req.Metadata
+	response.RequestID = req.BatchID
+	return response, nil
+}
+
+// GetModel returns "hashing-<dimension>"
+func (e *HashingEmbedder) GetModel() string {
+	return e.model
+}
+
+// GetDimension returns the configured vector dimension
+func (e *HashingEmbedder) GetDimension() int {
+	return e.config.Dimension
+}
+
+// Close releases nothing; the embedder holds no resources
+func (e *HashingEmbedder) Close() error {
+	return nil
+}
+
+// embed hashes the features of text into a normalized vector
+func (e *HashingEmbedder) embed(text string) *EmbeddingResponse {
+	words := keywordTokens(text, e.config.SplitIdentifiers)
+	if len(words) == 0 {
+		// Nothing but punctuation or symbols: embed the text as one word
+		words = []string{strings.TrimSpace(text)}
+	}
+
+	// Counts are kept in first-seen order so that the floating point sums
+	// below, and therefore the vector, do not depend on map iteration
+	index := make(map[uint64]int)
+	var hashes []uint64
+	var counts []float64
+	var weights []float32
+	add := func(kind byte, feature string, weight float32) {
+		if weight == 0 {
+			return
+		}
+		h := featureHash(kind, feature)
+		i, ok := index[h]
+		if !ok {
+			i = len(hashes)
+			index[h] = i
+			hashes = append(hashes, h)
+			counts = append(counts, 0)
+			weights = append(weights, weight)
+		}
+		counts[i]++
+	}
+
+	for _, word := range words {
+		word = strings.ToLower(word)
+		add('w', word, e.config.WordWeight)
+
+		if e.config.MaxNGram == 0 || e.config.NGramWeight == 0 {
+			continue
+		}
+		// Boundary markers let n-grams tell prefixes and suffixes apart
+		runes := []rune("<" + word + ">")
+		for n := e.config.MinNGram; n <= e.config.MaxNGram && n <= len(runes); n++ {
+			for start := 0; start+n <= len(runes); start++ {
+				add('c', string(runes[start:start+n]), e.config.NGramWeight)
+			}
+		}
+	}
+
+	values := make([]float64, e.config.Dimension)
+	for i, h := range hashes {
+		weight := float64(weights[i]) * (1 + math.Log(counts[i]))
+		if h>>63 == 1 {
+			weight = -weight
+		}
+		values[h%uint64(e.config.Dimension)] += weight
+	}
+
+	var norm float64
+	for _, v := range values {
+		norm += v * v
+	}
+	norm = math.Sqrt(norm)
+
+	vec := make(vector.Vector, e.config.Dimension)
+	if norm > 0 {
+		for i, v := range values {
+			vec[i] = float32(v / norm)
+		}
+	}
+
+	return &EmbeddingResponse{
+		Vector: vec,
+		Model:  e.model,
+		Usage: EmbeddingUsage{
+			PromptTokens: len(words),
+			TotalTokens:  len(words),
+		},
+		RequestID: fmt.Sprintf("embed_%d", time.Now().Unix()),
+	}
+}
+
+// featureHash hashes a feature with FNV-1a, its kind keeping words and
+// n-grams with the same text apart
+func featureHash(kind byte, feature string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte{kind})
+	h.Write([]byte(feature))
+	return h.Sum64()
+}
+
+// Ensure HashingEmbedder implements Embedder
+var _ Embedder = (*HashingEmbedder)(nil)
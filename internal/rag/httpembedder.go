@@ -0,0 +1,571 @@
+package rag
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PerceptivePenguin/MCPRAG-Go/internal/vector"
+)
+
+// embeddingCodec maps embedding requests and responses to the JSON API of
+// one provider
+type embeddingCodec interface {
+	// path is the endpoint below the base URL
+	path() string
+
+	// maxBatch is the most texts one request may carry, zero for no limit
+	maxBatch() int
+
+	// encode builds the request body
+	encode(model string, texts []string, inputType EmbeddingInputType, dimensions int) any
+
+	// decode returns the vectors of a response in request order and the
+	// number of tokens billed, zero when the provider does not say
+	decode(body []byte) ([][]float32, int, error)
+}
+
+// defaultProviderURLs are used when EmbeddingConfig.BaseURL is empty;
+// text-embeddings-inference has no usual address and needs one configured
+var defaultProviderURLs = map[EmbeddingProvider]string{
+	EmbeddingProviderOpenAICompatible: "https://api.openai.com/v1",
+	EmbeddingProviderOllama:           "http://localhost:11434",
+	EmbeddingProviderCohere:           "https://api.cohere.com",
+}
+
+// HTTPEmbedder implements Embedder for embedding servers reached over plain
+// HTTP: any OpenAI-compatible /embeddings endpoint, text-embeddings-inference,
+// Ollama and Cohere. Unless EmbeddingConfig.Dimensions is set, the vector
+// dimension is taken from the first response and every later response must
+// match it.
+//
+// Queries and documents are embedded differently where the provider supports
+// it (Cohere's input_type) and can be given QueryPrefix and DocumentPrefix
+// for models that expect instructions in the text instead. Embed and
+// EmbedBatch embed documents; EmbedWithOptions embeds either.
+type HTTPEmbedder struct {
+	client   *http.Client
+	config   *EmbeddingConfig
+	codec    embeddingCodec
+	endpoint string
+	cache    Cache
+
+	mu        sync.Mutex
+	dimension int
+}
+
+// NewHTTPEmbedder creates an embedder for the HTTP provider named by
+// config.Provider
+func NewHTTPEmbedder(config *EmbeddingConfig, cache Cache) (*HTTPEmbedder, error) {
+	if config == nil {
+		return nil, NewRAGErrorWithOp("new_embedder", "embedding config is required", ErrorTypeValidation)
+	}
+
+	var codec embeddingCodec
+	switch config.Provider {
+	case EmbeddingProviderOpenAICompatible:
+		codec = openAICompatibleCodec{}
+	case EmbeddingProviderTEI:
+		codec = teiCodec{}
+	case EmbeddingProviderOllama:
+		codec = ollamaCodec{}
+	case EmbeddingProviderCohere:
+		codec = cohereCodec{}
+	default:
+		return nil, ValidationError("provider", "not an HTTP embedding provider: "+string(config.Provider))
+	}
+
+	if config.Model == "" && config.Provider != EmbeddingProviderTEI {
+		return nil, ValidationError("model", "embedding model is required")
+	}
+	if config.Dimensions < 0 {
+		return nil, ValidationError("dimensions", "dimensions must not be negative")
+	}
+
+	baseURL := config.BaseURL
+	if baseURL == "" {
+		baseURL = defaultProviderURLs[config.Provider]
+	}
+	if baseURL == "" {
+		return nil, ValidationError("base_url", "base URL is required for provider "+string(config.Provider))
+	}
+
+	return &HTTPEmbedder{
+		client:    &http.Client{},
+		config:    config,
+		codec:     codec,
+		endpoint:  strings.TrimRight(baseURL, "/") + codec.path(),
+		cache:     cache,
+		dimension: config.Dimensions,
+	}, nil
+}
+
+// Embed generates an embedding for a single document text
+func (e *HTTPEmbedder) Embed(ctx context.Context, text string) (*EmbeddingResponse, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, ErrQueryEmpty.WithOperation("embed")
+	}
+
+	results, err := e.embed(ctx, []string{text}, e.GetModel(), InputTypeDocument)
+	if err != nil {
+		return nil, err
+	}
+	return results[0], nil
+}
+
+// EmbedBatch generates embeddings for multiple document texts; blank texts
+// get nil entries
+func (e *HTTPEmbedder) EmbedBatch(ctx context.Context, texts []string) ([]*EmbeddingResponse, error) {
+	if len(texts) == 0 {
+		return nil, NewRAGErrorWithOp("embed_batch", "no texts provided", ErrorTypeValidation)
+	}
+
+	validTexts := make([]string, 0, len(texts))
+	indexMap := make([]int, 0, len(texts))
+	for i, text := range texts {
+		if strings.TrimSpace(text) != "" {
+			validTexts = append(validTexts, text)
+			indexMap = append(indexMap, i)
+		}
+	}
+
+	if len(validTexts) == 0 {
+		return nil, ErrQueryEmpty.WithOperation("embed_batch")
+	}
+
+	embedded, err := e.embed(ctx, validTexts, e.GetModel(), InputTypeDocument)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*EmbeddingResponse, len(texts))
+	for i, result := range embedded {
+		results[indexMap[i]] = result
+	}
+	return results, nil
+}
+
+// EmbedWithOptions generates an embedding of the request's input type,
+// with the request's model when it names one
+func (e *HTTPEmbedder) EmbedWithOptions(ctx context.Context, req EmbeddingRequest) (*EmbeddingResponse, error) {
+	if strings.TrimSpace(req.Text) == "" {
+		return nil, ErrQueryEmpty.WithOperation("embed_with_options")
+	}
+
+	model := req.Model
+	if model == "" {
+		model = e.GetModel()
+	}
+	inputType := req.InputType
+	if inputType == "" {
+		inputType = InputTypeDocument
+	}
+
+	results, err := e.embed(ctx, []string{req.Text}, model, inputType)
+	if err != nil {
+		return nil, err
+	}
+
+	result := results[0]
+	result.Metadata = req.Metadata
+	result.RequestID = req.BatchID
+	return result, nil
+}
+
+// GetModel returns the configured model; text-embeddings-inference serves a
+// single model and may have none configured
+func (e *HTTPEmbedder) GetModel() string {
+	if e.config.Model == "" {
+		return string(e.config.Provider)
+	}
+	return e.config.Model
+}
+
+// GetDimension returns the vector dimension. Before the first embedding it
+// is unknown unless configured, so a short probe text is embedded to learn
+// it; zero is returned if that fails.
+func (e *HTTPEmbedder) GetDimension() int {
+	dimension, err := e.probeDimension()
+	if err != nil {
+		return 0
+	}
+	return dimension
+}
+
+// probeDimension returns the configured or detected dimension, embedding a
+// short probe text while it is unknown
+func (e *HTTPEmbedder) probeDimension() (int, error) {
+	if dimension := e.knownDimension(); dimension > 0 {
+		return dimension, nil
+	}
+
+	ctx := context.Background()
+	if e.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.config.Timeout)
+		defer cancel()
+	}
+	if _, err := e.embed(ctx, []string{"dimension probe"}, e.GetModel(), InputTypeDocument); err != nil {
+		return 0, err
+	}
+
+	return e.knownDimension(), nil
+}
+
+// Close releases the cache
+func (e *HTTPEmbedder) Close() error {
+	e.client.CloseIdleConnections()
+
+	if e.cache != nil {
+		return e.cache.Close()
+	}
+	return nil
+}
+
+// knownDimension returns the configured or detected dimension, zero while
+// unknown
+func (e *HTTPEmbedder) knownDimension() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.dimension
+}
+
+// embed embeds texts, none of them blank, in as few requests as the
+// provider allows, serving what it can from the cache
+func (e *HTTPEmbedder) embed(ctx context.Context, texts []string, model string, inputType EmbeddingInputType) ([]*EmbeddingResponse, error) {
+	prefix := e.config.DocumentPrefix
+	if inputType == InputTypeQuery {
+		prefix = e.config.QueryPrefix
+	}
+
+	results := make([]*EmbeddingResponse, len(texts))
+	var pending []int
+	for i, text := range texts {
+		if e.cache != nil {
+			if entry, err := e.cache.Get(ctx, e.cacheKey(prefix+text, model, inputType)); err == nil {
+				results[i] = &EmbeddingResponse{
+					Vector:   entry.Vector,
+					Model:    model,
+					Metadata: entry.Metadata,
+					Cached:   true,
+				}
+				continue
+			}
+		}
+		pending = append(pending, i)
+	}
+
+	batchSize := e.config.BatchSize
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	if limit := e.codec.maxBatch(); limit > 0 && batchSize > limit {
+		batchSize = limit
+	}
+
+	for start := 0; start < len(pending); start += batchSize {
+		end := min(start+batchSize, len(pending))
+		batch := make([]string, 0, end-start)
+		for _, i := range pending[start:end] {
+			batch = append(batch, prefix+texts[i])
+		}
+
+		vectors, tokens, err := e.request(ctx, model, batch, inputType)
+		if err != nil {
+			return nil, err
+		}
+
+		for j, values := range vectors {
+			vec := vector.Vector(values)
+			text := batch[j]
+			results[pending[start+j]] = &EmbeddingResponse{
+				Vector: vec,
+				Model:  model,
+				Usage: EmbeddingUsage{
+					PromptTokens: tokens / len(batch), // approximate
+					TotalTokens:  tokens / len(batch),
+				},
+				RequestID: fmt.Sprintf("embed_%d", time.Now().Unix()),
+			}
+
+			if e.cache != nil {
+				metadata := map[string]string{
+					"model":     model,
+					"text_hash": fmt.Sprintf("%x", md5.Sum([]byte(text))),
+				}
+				e.cache.Set(ctx, e.cacheKey(text, model, inputType), vec, metadata)
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// request sends one batch and checks that the response has a vector of the
+// expected dimension for every text
+func (e *HTTPEmbedder) request(ctx context.Context, model string, texts []string, inputType EmbeddingInputType) ([][]float32, int, error) {
+	payload, err := json.Marshal(e.codec.encode(model, texts, inputType, e.config.Dimensions))
+	if err != nil {
+		return nil, 0, NewRAGErrorWithCause("failed to encode embedding request", ErrorTypeInternal, err).WithOperation("embed")
+	}
+
+	body, err := e.post(ctx, payload)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	vectors, tokens, err := e.codec.decode(body)
+	if err != nil {
+		return nil, 0, ExternalServiceError(string(e.config.Provider), "embedding", err)
+	}
+	if len(vectors) != len(texts) {
+		return nil, 0, ExternalServiceError(string(e.config.Provider), "embedding",
+			fmt.Errorf("got %d embeddings for %d texts", len(vectors), len(texts)))
+	}
+
+	// A model given per request may have a dimension of its own
+	if model != e.GetModel() {
+		return vectors, tokens, nil
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, values := range vectors {
+		if e.dimension == 0 {
+			e.dimension = len(values)
+		}
+		if len(values) == 0 || len(values) != e.dimension {
+			return nil, 0, ExternalServiceError(string(e.config.Provider), "embedding",
+				fmt.Errorf("got a %d-dimensional embedding, expected %d", len(values), e.dimension))
+		}
+	}
+
+	return vectors, tokens, nil
+}
+
+// post sends payload to the endpoint, retrying rate limited requests,
+// server errors and failed connections with exponential backoff
+func (e *HTTPEmbedder) post(ctx context.Context, payload []byte) ([]byte, error) {
+	var lastErr error
+
+	for attempt := 0; attempt <= e.config.MaxRetries; attempt++ {
+		if attempt > 0 {
+			delay := time.Duration(1<<uint(attempt-1)) * time.Second
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		body, retryable, err := e.send(ctx, payload)
+		if err == nil {
+			return body, nil
+		}
+
+		lastErr = err
+		if !retryable || ctx.Err() != nil {
+			break
+		}
+	}
+
+	return nil, ExternalServiceError(string(e.config.Provider), "embedding", lastErr)
+}
+
+// send makes a single request and reports whether a failure is worth
+// retrying
+func (e *HTTPEmbedder) send(ctx context.Context, payload []byte) ([]byte, bool, error) {
+	if e.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.config.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if e.config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.config.APIKey)
+	}
+	for key, value := range e.config.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, true, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, true, err
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		message := strings.TrimSpace(string(body))
+		if len(message) > 200 {
+			message = message[:200] + "..."
+		}
+		retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+		return nil, retryable, fmt.Errorf("%s: %s", resp.Status, message)
+	}
+
+	return body, false, nil
+}
+
+func (e *HTTPEmbedder) cacheKey(text, model string, inputType EmbeddingInputType) string {
+	hash := md5.Sum([]byte(fmt.Sprintf("%s:%s:%s:%s", e.config.Provider, model, inputType, text)))
+	return fmt.Sprintf("embed:%x", hash)
+}
+
+// openAICompatibleCodec speaks the OpenAI embeddings API, which many
+// self-hosted servers (vLLM, LocalAI, LM Studio, llama.cpp) implement too
+type openAICompatibleCodec struct{}
+
+func (openAICompatibleCodec) path() string { return "/embeddings" }
+
+func (openAICompatibleCodec) maxBatch() int { return 0 }
+
+func (openAICompatibleCodec) encode(model string, texts []string, _ EmbeddingInputType, dimensions int) any {
+	return struct {
+		Model      string   `json:"model"`
+		Input      []string `json:"input"`
+		Dimensions int      `json:"dimensions,omitempty"`
+	}{model, texts, dimensions}
+}
+
+func (openAICompatibleCodec) decode(body []byte) ([][]float32, int, error) {
+	var resp struct {
+		Data []struct {
+			Embedding []float32 `json:"embedding"`
+			Index     int       `json:"index"`
+		} `json:"data"`
+		Usage struct {
+			TotalTokens int `json:"total_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, 0, err
+	}
+
+	sort.SliceStable(resp.Data, func(i, j int) bool { return resp.Data[i].Index < resp.Data[j].Index })
+	vectors := make([][]float32, len(resp.Data))
+	for i, item := range resp.Data {
+		vectors[i] = item.Embedding
+	}
+	return vectors, resp.Usage.TotalTokens, nil
+}
+
+// teiCodec speaks the /embed API of Hugging Face text-embeddings-inference,
+// which serves one model and answers with a bare array of vectors
+type teiCodec struct{}
+
+func (teiCodec) path() string { return "/embed" }
+
+func (teiCodec) maxBatch() int { return 32 }
+
+func (teiCodec) encode(_ string, texts []string, _ EmbeddingInputType, _ int) any {
+	return struct {
+		Inputs   []string `json:"inputs"`
+		Truncate bool     `json:"truncate"`
+	}{texts, true}
+}
+
+func (teiCodec) decode(body []byte) ([][]float32, int, error) {
+	var vectors [][]float32
+	if err := json.Unmarshal(body, &vectors); err != nil {
+		return nil, 0, err
+	}
+	return vectors, 0, nil
+}
+
+// ollamaCodec speaks Ollama's /api/embeddings, one text per request
+type ollamaCodec struct{}
+
+func (ollamaCodec) path() string { return "/api/embeddings" }
+
+func (ollamaCodec) maxBatch() int { return 1 }
+
+func (ollamaCodec) encode(model string, texts []string, _ EmbeddingInputType, _ int) any {
+	return struct {
+		Model  string `json:"model"`
+		Prompt string `json:"prompt"`
+	}{model, texts[0]}
+}
+
+func (ollamaCodec) decode(body []byte) ([][]float32, int, error) {
+	var resp struct {
+		Embedding []float32 `json:"embedding"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, 0, err
+	}
+	if len(resp.Embedding) == 0 {
+		return nil, 0, errors.New("response has no embedding")
+	}
+	return [][]float32{resp.Embedding}, 0, nil
+}
+
+// cohereCodec speaks Cohere's /v1/embed, which embeds search queries and
+// documents differently
+type cohereCodec struct{}
+
+func (cohereCodec) path() string { return "/v1/embed" }
+
+func (cohereCodec) maxBatch() int { return 96 }
+
+func (cohereCodec) encode(model string, texts []string, inputType EmbeddingInputType, _ int) any {
+	cohereType := "search_document"
+	if inputType == InputTypeQuery {
+		cohereType = "search_query"
+	}
+	return struct {
+		Model     string   `json:"model"`
+		Texts     []string `json:"texts"`
+		InputType string   `json:"input_type"`
+		Truncate  string   `json:"truncate"`
+	}{model, texts, cohereType, "END"}
+}
+
+func (cohereCodec) decode(body []byte) ([][]float32, int, error) {
+	var resp struct {
+		Embeddings json.RawMessage `json:"embeddings"`
+		Meta       struct {
+			BilledUnits struct {
+				InputTokens int `json:"input_tokens"`
+			} `json:"billed_units"`
+		} `json:"meta"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, 0, err
+	}
+
+	// Plain float vectors, or vectors by type when embedding_types is set
+	var vectors [][]float32
+	if err := json.Unmarshal(resp.Embeddings, &vectors); err != nil {
+		var byType struct {
+			Float [][]float32 `json:"float"`
+		}
+		if err := json.Unmarshal(resp.Embeddings, &byType); err != nil {
+			return nil, 0, err
+		}
+		vectors = byType.Float
+	}
+	return vectors, resp.Meta.BilledUnits.InputTokens, nil
+}
+
+// Ensure HTTPEmbedder implements Embedder
+var _ Embedder = (*HTTPEmbedder)(nil)
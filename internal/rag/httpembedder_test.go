@@ -0,0 +1,363 @@
+package rag
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// stubRequest is one request received by a stub embedding server
+type stubRequest struct {
+	path   string
+	header http.Header
+	body   map[string]any
+}
+
+// stubServer is an embedding server that records the requests it receives
+// and answers each with respond
+type stubServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []stubRequest
+}
+
+// newStubServer starts a stub server; respond gets the decoded request body
+// and returns the response to encode
+func newStubServer(t *testing.T, respond func(body map[string]any) any) *stubServer {
+	t.Helper()
+
+	stub := &stubServer{}
+	stub.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		stub.mu.Lock()
+		stub.requests = append(stub.requests, stubRequest{path: r.URL.Path, header: r.Header.Clone(), body: body})
+		stub.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(respond(body))
+	}))
+	t.Cleanup(stub.Close)
+
+	return stub
+}
+
+// received returns the requests received so far
+func (s *stubServer) received() []stubRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]stubRequest(nil), s.requests...)
+}
+
+// stubVector is the vector a stub server returns for text, so tests can tell
+// which text a vector belongs to
+func stubVector(text string) []float32 {
+	return []float32{float32(len(text)), 1, 2}
+}
+
+// stringList converts a decoded JSON array of strings
+func stringList(t *testing.T, value any) []string {
+	t.Helper()
+
+	items, ok := value.([]any)
+	if !ok {
+		t.Fatalf("expected a JSON array, got %T", value)
+	}
+	texts := make([]string, len(items))
+	for i, item := range items {
+		text, ok := item.(string)
+		if !ok {
+			t.Fatalf("expected a JSON string, got %T", item)
+		}
+		texts[i] = text
+	}
+	return texts
+}
+
+// newTestEmbedder creates an uncached HTTPEmbedder for provider against
+// stub, without retries
+func newTestEmbedder(t *testing.T, provider EmbeddingProvider, stub *stubServer, configure func(*EmbeddingConfig)) *HTTPEmbedder {
+	t.Helper()
+
+	config := DefaultEmbeddingConfig()
+	config.Provider = provider
+	config.Model = "test-model"
+	config.APIKey = "test-key"
+	config.BaseURL = stub.URL
+	config.MaxRetries = 0
+	if configure != nil {
+		configure(config)
+	}
+
+	embedder, err := NewHTTPEmbedder(config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { embedder.Close() })
+	return embedder
+}
+
+// testTexts returns n distinct texts of distinct lengths
+func testTexts(n int) []string {
+	texts := make([]string, n)
+	for i := range texts {
+		texts[i] = fmt.Sprintf("text %d %s", i, strings.Repeat("x", i))
+	}
+	return texts
+}
+
+// checkVectors fails t unless every result carries the stub vector of its
+// text with prefix prepended
+func checkVectors(t *testing.T, texts []string, prefix string, results []*EmbeddingResponse) {
+	t.Helper()
+
+	if len(results) != len(texts) {
+		t.Fatalf("got %d results for %d texts", len(results), len(texts))
+	}
+	for i, result := range results {
+		want := stubVector(prefix + texts[i])
+		if result == nil || len(result.Vector) != len(want) || result.Vector[0] != want[0] {
+			t.Fatalf("result %d = %v, want %v", i, result, want)
+		}
+	}
+}
+
+func TestHTTPEmbedderOpenAICompatible(t *testing.T) {
+	stub := newStubServer(t, func(body map[string]any) any {
+		input := body["input"].([]any)
+
+		// Answer in reverse order; the index field tells where each belongs
+		data := make([]map[string]any, 0, len(input))
+		for i := len(input) - 1; i >= 0; i-- {
+			data = append(data, map[string]any{"index": i, "embedding": stubVector(input[i].(string))})
+		}
+		return map[string]any{"data": data, "usage": map[string]any{"total_tokens": 4 * len(input)}}
+	})
+	embedder := newTestEmbedder(t, EmbeddingProviderOpenAICompatible, stub, func(config *EmbeddingConfig) {
+		config.BatchSize = 4
+		config.Dimensions = 3
+		config.DocumentPrefix = "passage: "
+	})
+
+	texts := testTexts(10)
+	results, err := embedder.EmbedBatch(context.Background(), texts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkVectors(t, texts, "passage: ", results)
+
+	requests := stub.received()
+	if len(requests) != 3 {
+		t.Fatalf("got %d requests, want 3 batches of at most 4", len(requests))
+	}
+	for _, req := range requests {
+		if req.path != "/embeddings" {
+			t.Errorf("path = %q, want /embeddings", req.path)
+		}
+		if auth := req.header.Get("Authorization"); auth != "Bearer test-key" {
+			t.Errorf("Authorization = %q", auth)
+		}
+		if req.body["model"] != "test-model" {
+			t.Errorf("model = %v", req.body["model"])
+		}
+		if req.body["dimensions"] != float64(3) {
+			t.Errorf("dimensions = %v, want 3", req.body["dimensions"])
+		}
+	}
+	if first := stringList(t, requests[0].body["input"]); len(first) != 4 || first[0] != "passage: "+texts[0] {
+		t.Errorf("first batch = %q", first)
+	}
+	if results[0].Usage.TotalTokens != 4 {
+		t.Errorf("total tokens = %d, want 4", results[0].Usage.TotalTokens)
+	}
+}
+
+func TestHTTPEmbedderTEI(t *testing.T) {
+	stub := newStubServer(t, func(body map[string]any) any {
+		inputs := body["inputs"].([]any)
+		vectors := make([][]float32, len(inputs))
+		for i, input := range inputs {
+			vectors[i] = stubVector(input.(string))
+		}
+		return vectors
+	})
+	embedder := newTestEmbedder(t, EmbeddingProviderTEI, stub, func(config *EmbeddingConfig) {
+		config.Model = ""
+		config.APIKey = ""
+	})
+
+	texts := testTexts(70)
+	results, err := embedder.EmbedBatch(context.Background(), texts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkVectors(t, texts, "", results)
+
+	// The default batch size of 100 is capped at 32
+	requests := stub.received()
+	if len(requests) != 3 {
+		t.Fatalf("got %d requests, want 3 batches of at most 32", len(requests))
+	}
+	for i, want := range []int{32, 32, 6} {
+		req := requests[i]
+		if req.path != "/embed" {
+			t.Errorf("path = %q, want /embed", req.path)
+		}
+		if auth := req.header.Get("Authorization"); auth != "" {
+			t.Errorf("Authorization = %q without an API key", auth)
+		}
+		if req.body["truncate"] != true {
+			t.Errorf("truncate = %v, want true", req.body["truncate"])
+		}
+		if _, ok := req.body["model"]; ok {
+			t.Errorf("request names a model: %v", req.body)
+		}
+		if inputs := stringList(t, req.body["inputs"]); len(inputs) != want {
+			t.Errorf("batch %d has %d inputs, want %d", i, len(inputs), want)
+		}
+	}
+	if dimension := embedder.GetDimension(); dimension != 3 {
+		t.Errorf("detected dimension = %d, want 3", dimension)
+	}
+}
+
+func TestHTTPEmbedderOllama(t *testing.T) {
+	stub := newStubServer(t, func(body map[string]any) any {
+		return map[string]any{"embedding": stubVector(body["prompt"].(string))}
+	})
+	embedder := newTestEmbedder(t, EmbeddingProviderOllama, stub, nil)
+
+	texts := testTexts(3)
+	results, err := embedder.EmbedBatch(context.Background(), texts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkVectors(t, texts, "", results)
+
+	// One text per request
+	requests := stub.received()
+	if len(requests) != len(texts) {
+		t.Fatalf("got %d requests for %d texts", len(requests), len(texts))
+	}
+	for i, req := range requests {
+		if req.path != "/api/embeddings" {
+			t.Errorf("path = %q, want /api/embeddings", req.path)
+		}
+		if req.body["model"] != "test-model" || req.body["prompt"] != texts[i] {
+			t.Errorf("request %d = %v", i, req.body)
+		}
+	}
+}
+
+func TestHTTPEmbedderCohere(t *testing.T) {
+	stub := newStubServer(t, func(body map[string]any) any {
+		texts := body["texts"].([]any)
+		vectors := make([][]float32, len(texts))
+		for i, text := range texts {
+			vectors[i] = stubVector(text.(string))
+		}
+
+		// Queries get vectors by type, as with embedding_types set
+		var embeddings any = vectors
+		if body["input_type"] == "search_query" {
+			embeddings = map[string]any{"float": vectors}
+		}
+		return map[string]any{
+			"embeddings": embeddings,
+			"meta":       map[string]any{"billed_units": map[string]any{"input_tokens": len(texts)}},
+		}
+	})
+	embedder := newTestEmbedder(t, EmbeddingProviderCohere, stub, func(config *EmbeddingConfig) {
+		config.BatchSize = 200
+		config.QueryPrefix = "query: "
+	})
+
+	texts := testTexts(100)
+	results, err := embedder.EmbedBatch(context.Background(), texts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkVectors(t, texts, "", results)
+
+	query, err := embedder.EmbedWithOptions(context.Background(), EmbeddingRequest{Text: "what", InputType: InputTypeQuery})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkVectors(t, []string{"what"}, "query: ", []*EmbeddingResponse{query})
+
+	// Documents in batches of at most 96, then the query
+	requests := stub.received()
+	if len(requests) != 3 {
+		t.Fatalf("got %d requests, want 2 document batches and a query", len(requests))
+	}
+	for i, want := range []struct {
+		inputType string
+		texts     int
+	}{{"search_document", 96}, {"search_document", 4}, {"search_query", 1}} {
+		req := requests[i]
+		if req.path != "/v1/embed" {
+			t.Errorf("path = %q, want /v1/embed", req.path)
+		}
+		if req.body["input_type"] != want.inputType {
+			t.Errorf("request %d input_type = %v, want %s", i, req.body["input_type"], want.inputType)
+		}
+		if req.body["model"] != "test-model" || req.body["truncate"] != "END" {
+			t.Errorf("request %d = %v", i, req.body)
+		}
+		if texts := stringList(t, req.body["texts"]); len(texts) != want.texts {
+			t.Errorf("request %d has %d texts, want %d", i, len(texts), want.texts)
+		}
+	}
+	if got := stringList(t, requests[2].body["texts"]); got[0] != "query: what" {
+		t.Errorf("query text = %q, want the query prefix", got[0])
+	}
+}
+
+func TestHTTPEmbedderDimensionMismatch(t *testing.T) {
+	stub := newStubServer(t, func(body map[string]any) any {
+		return map[string]any{"embedding": []float32{1, 2}}
+	})
+	embedder := newTestEmbedder(t, EmbeddingProviderOllama, stub, func(config *EmbeddingConfig) {
+		config.Dimensions = 3
+	})
+
+	_, err := embedder.Embed(context.Background(), "text")
+	var ragErr *RAGError
+	if !errors.As(err, &ragErr) || ragErr.Type != ErrorTypeExternal {
+		t.Fatalf("err = %v, want an external service error", err)
+	}
+}
+
+func TestNewRetrieverFailsWhenDimensionProbeFails(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "model not loaded", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	config := DefaultRetrieverConfig()
+	config.Embedding = &EmbeddingConfig{
+		Provider:   EmbeddingProviderTEI,
+		BaseURL:    server.URL,
+		MaxRetries: 0,
+	}
+
+	_, err := NewRetriever(config)
+	if err == nil {
+		t.Fatal("NewRetriever succeeded without a detectable embedding dimension")
+	}
+	var ragErr *RAGError
+	if !errors.As(err, &ragErr) || ragErr.Type != ErrorTypeExternal {
+		t.Fatalf("err = %v, want an external service error", err)
+	}
+}
@@ -0,0 +1,345 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/PerceptivePenguin/MCPRAG-Go/internal/vector"
+)
+
+// DefaultIndexName is the index searched by queries that do not name one
+const DefaultIndexName = "default"
+
+// indexNamePattern matches the index names accepted by CreateIndex. Names
+// become directory names below the data directory, so they may not be "."
+// or "..", nor contain path separators.
+var indexNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// RetrieverFactory creates the retriever backing an index
+type RetrieverFactory func(config *RetrievalConfig) (Retriever, error)
+
+// BasicIndexManager implements IndexManager with one retriever per named
+// index, each with its own retrieval settings, embedding model and vector
+// store. Parts of an index configuration that are left nil are taken from the
+// manager defaults, and an empty embedding API key or base URL is inherited.
+type BasicIndexManager struct {
+	mu       sync.RWMutex
+	indexes  map[string]*managedIndex
+	defaults *RetrievalConfig
+	factory  RetrieverFactory
+	closed   bool
+}
+
+type managedIndex struct {
+	config    *RetrievalConfig
+	retriever Retriever
+}
+
+// NewIndexManager creates an index manager. Indexes are created through
+// NewRetriever unless factory is given; defaults fills in missing settings.
+func NewIndexManager(defaults *RetrievalConfig, factory RetrieverFactory) *BasicIndexManager {
+	base := DefaultRetrievalConfig()
+	if defaults != nil {
+		merged := *defaults
+		if merged.Embedding == nil {
+			merged.Embedding = base.Embedding
+		}
+		if merged.Cache == nil {
+			merged.Cache = base.Cache
+		}
+		if merged.Chunking == nil {
+			merged.Chunking = base.Chunking
+		}
+		if merged.Context == nil {
+			merged.Context = base.Context
+		}
+		if merged.Processing == nil {
+			merged.Processing = base.Processing
+		}
+		if merged.VectorStore == nil {
+			merged.VectorStore = base.VectorStore
+		}
+		base = &merged
+	}
+	if factory == nil {
+		factory = func(config *RetrievalConfig) (Retriever, error) {
+			return NewRetriever(config)
+		}
+	}
+
+	return &BasicIndexManager{
+		indexes:  make(map[string]*managedIndex),
+		defaults: base,
+		factory:  factory,
+	}
+}
+
+// CreateIndex creates a new named index
+func (m *BasicIndexManager) CreateIndex(ctx context.Context, name string, config *RetrievalConfig) error {
+	if name == "" {
+		return ValidationError("name", "index name is required")
+	}
+	if !indexNamePattern.MatchString(name) {
+		return ValidationError("name", "index name must start with a letter or digit and contain only letters, digits, '_', '.' and '-'")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return NewRAGErrorWithOp("create_index", "index manager is closed", ErrorTypeInternal)
+	}
+
+	if _, exists := m.indexes[name]; exists {
+		return ErrIndexExists.WithOperation("create_index").WithDetails(map[string]string{"index": name})
+	}
+
+	resolved := m.resolveConfig(name, config)
+	retriever, err := m.factory(resolved)
+	if err != nil {
+		return NewRAGErrorWithCause("failed to create index", ErrorTypeInternal, err).WithOperation("create_index").
+			WithDetails(map[string]string{"index": name})
+	}
+
+	m.indexes[name] = &managedIndex{config: resolved, retriever: retriever}
+	return nil
+}
+
+// DeleteIndex closes an index and removes its persisted vectors, if any
+func (m *BasicIndexManager) DeleteIndex(ctx context.Context, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	index, exists := m.indexes[name]
+	if !exists {
+		return ErrIndexNotFound.WithOperation("delete_index").WithDetails(map[string]string{"index": name})
+	}
+
+	delete(m.indexes, name)
+
+	if err := index.retriever.Close(); err != nil {
+		return NewRAGErrorWithCause("failed to close index", ErrorTypeInternal, err).WithOperation("delete_index")
+	}
+
+	if store := index.config.VectorStore; store != nil && store.Backend == vector.BackendFile && store.Path != "" {
+		if err := vector.RemoveFileStore(store.Path); err != nil {
+			return NewRAGErrorWithCause("failed to remove index files", ErrorTypeInternal, err).WithOperation("delete_index")
+		}
+	}
+
+	return nil
+}
+
+// ListIndexes returns the names of all indexes in sorted order
+func (m *BasicIndexManager) ListIndexes(ctx context.Context) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	names := make([]string, 0, len(m.indexes))
+	for name := range m.indexes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// GetIndexStats returns statistics for an index
+func (m *BasicIndexManager) GetIndexStats(ctx context.Context, name string) (*RetrievalStats, error) {
+	retriever, err := m.Retriever(name)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := retriever.GetStats()
+	return &stats, nil
+}
+
+// BackupIndex writes a vector store snapshot of an index to path. The file
+// is written next to path and renamed into place once complete.
+func (m *BasicIndexManager) BackupIndex(ctx context.Context, name string, path string) error {
+	retriever, err := m.Retriever(name)
+	if err != nil {
+		return err
+	}
+
+	snapshotter, ok := retriever.(vector.Snapshotter)
+	if !ok {
+		return ErrNotImplemented.WithOperation("backup_index").WithDetails(map[string]string{"index": name})
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return NewRAGErrorWithCause("failed to create backup file", ErrorTypeInternal, err).WithOperation("backup_index")
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := snapshotter.Snapshot(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return NewRAGErrorWithCause("failed to write backup file", ErrorTypeInternal, err).WithOperation("backup_index")
+	}
+
+	if err := tmp.Close(); err != nil {
+		return NewRAGErrorWithCause("failed to write backup file", ErrorTypeInternal, err).WithOperation("backup_index")
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return NewRAGErrorWithCause("failed to write backup file", ErrorTypeInternal, err).WithOperation("backup_index")
+	}
+
+	return nil
+}
+
+// RestoreIndex replaces the contents of an index with a backup written by
+// BackupIndex. The index must exist and match the dimension and metric of
+// the backup.
+func (m *BasicIndexManager) RestoreIndex(ctx context.Context, name string, path string) error {
+	retriever, err := m.Retriever(name)
+	if err != nil {
+		return err
+	}
+
+	snapshotter, ok := retriever.(vector.Snapshotter)
+	if !ok {
+		return ErrNotImplemented.WithOperation("restore_index").WithDetails(map[string]string{"index": name})
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return NewRAGErrorWithCause("failed to open backup file", ErrorTypeNotFound, err).WithOperation("restore_index")
+	}
+	defer f.Close()
+
+	return snapshotter.Restore(f)
+}
+
+// Retriever returns the retriever of an index; an empty name selects the
+// default index
+func (m *BasicIndexManager) Retriever(name string) (Retriever, error) {
+	if name == "" {
+		name = DefaultIndexName
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	index, exists := m.indexes[name]
+	if !exists {
+		return nil, ErrIndexNotFound.WithDetails(map[string]string{"index": name})
+	}
+
+	return index.retriever, nil
+}
+
+// Retrieve runs a query against the index named by query.Index
+func (m *BasicIndexManager) Retrieve(ctx context.Context, query Query) (*RetrievalResult, error) {
+	retriever, err := m.Retriever(query.Index)
+	if err != nil {
+		return nil, err
+	}
+
+	return retriever.Retrieve(ctx, query)
+}
+
+// Close closes every index; persisted data is kept
+func (m *BasicIndexManager) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return nil
+	}
+	m.closed = true
+
+	var errs []error
+	for name, index := range m.indexes {
+		if err := index.retriever.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("index %s: %w", name, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("errors during close: %v", errs)
+	}
+
+	return nil
+}
+
+// resolveConfig fills the unset parts of config from the manager defaults.
+// An index inheriting a file-backed store or a disk cache gets its own
+// directory below the default path, so indexes never share files; name must
+// have been checked against indexNamePattern.
+func (m *BasicIndexManager) resolveConfig(name string, config *RetrievalConfig) *RetrievalConfig {
+	resolved := RetrievalConfig{}
+	if config != nil {
+		resolved = *config
+	}
+
+	if resolved.Embedding == nil {
+		embedding := *m.defaults.Embedding
+		resolved.Embedding = &embedding
+	} else if resolved.Embedding.APIKey == "" || resolved.Embedding.BaseURL == "" {
+		embedding := *resolved.Embedding
+		if embedding.APIKey == "" {
+			embedding.APIKey = m.defaults.Embedding.APIKey
+		}
+		if embedding.BaseURL == "" {
+			embedding.BaseURL = m.defaults.Embedding.BaseURL
+		}
+		resolved.Embedding = &embedding
+	}
+
+	if resolved.Cache == nil {
+		cache := *m.defaults.Cache
+		if cache.DiskPath != "" && name != DefaultIndexName {
+			cache.DiskPath = filepath.Join(cache.DiskPath, "indexes", name)
+		}
+		resolved.Cache = &cache
+	}
+	if resolved.Chunking == nil {
+		chunking := *m.defaults.Chunking
+		resolved.Chunking = &chunking
+	}
+	if resolved.Context == nil {
+		contextConfig := *m.defaults.Context
+		resolved.Context = &contextConfig
+	}
+	if resolved.Processing == nil {
+		processing := *m.defaults.Processing
+		resolved.Processing = &processing
+	}
+
+	if resolved.Tokenizer == nil {
+		tokenizer := DefaultTokenizerConfig()
+		if m.defaults.Tokenizer != nil {
+			*tokenizer = *m.defaults.Tokenizer
+		}
+		resolved.Tokenizer = tokenizer
+	}
+
+	if resolved.VectorStore == nil {
+		store := *m.defaults.VectorStore
+		if store.Backend == vector.BackendFile && store.Path != "" && name != DefaultIndexName {
+			store.Path = filepath.Join(store.Path, "indexes", name)
+		}
+		resolved.VectorStore = &store
+	}
+
+	return &resolved
+}
+
+// Ensure BasicIndexManager implements IndexManager
+var _ IndexManager = (*BasicIndexManager)(nil)
+
+// Ensure BasicRetriever can back up and restore its store
+var _ vector.Snapshotter = (*BasicRetriever)(nil)
@@ -0,0 +1,67 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/PerceptivePenguin/MCPRAG-Go/internal/vector"
+)
+
+// newTestIndexManager creates an index manager whose default store and disk
+// cache live below a temporary directory; the factory records the
+// configurations it is asked to create instead of creating retrievers
+func newTestIndexManager(t *testing.T) (*BasicIndexManager, *[]*RetrievalConfig) {
+	t.Helper()
+
+	dir := t.TempDir()
+	defaults := DefaultRetrievalConfig()
+	defaults.VectorStore.Backend = vector.BackendFile
+	defaults.VectorStore.Path = filepath.Join(dir, "vectors")
+	defaults.Cache.DiskPath = filepath.Join(dir, "cache")
+
+	var created []*RetrievalConfig
+	manager := NewIndexManager(defaults, func(config *RetrievalConfig) (Retriever, error) {
+		created = append(created, config)
+		return nil, errors.New("not created in tests")
+	})
+	return manager, &created
+}
+
+func TestCreateIndexRejectsUnsafeNames(t *testing.T) {
+	manager, created := newTestIndexManager(t)
+
+	for _, name := range []string{"", ".", "..", "../x", "../../x", "a/b", `a\b`, "/abs", ".hidden", "-flag", "a b"} {
+		err := manager.CreateIndex(context.Background(), name, nil)
+		var ragErr *RAGError
+		if !errors.As(err, &ragErr) || ragErr.Type != ErrorTypeValidation {
+			t.Errorf("CreateIndex(%q) = %v, want a validation error", name, err)
+		}
+	}
+	if len(*created) != 0 {
+		t.Fatalf("factory called for %d rejected names", len(*created))
+	}
+}
+
+func TestCreateIndexPathsStayBelowDefaults(t *testing.T) {
+	manager, created := newTestIndexManager(t)
+
+	for _, name := range []string{"docs", "team_a", "v1.2-notes"} {
+		manager.CreateIndex(context.Background(), name, nil)
+	}
+	if len(*created) != 3 {
+		t.Fatalf("factory called %d times, want 3", len(*created))
+	}
+
+	storeRoot := filepath.Join(manager.defaults.VectorStore.Path, "indexes")
+	cacheRoot := filepath.Join(manager.defaults.Cache.DiskPath, "indexes")
+	for _, config := range *created {
+		if filepath.Dir(config.VectorStore.Path) != storeRoot {
+			t.Errorf("store path %q is not directly below %q", config.VectorStore.Path, storeRoot)
+		}
+		if filepath.Dir(config.Cache.DiskPath) != cacheRoot {
+			t.Errorf("cache path %q is not directly below %q", config.Cache.DiskPath, cacheRoot)
+		}
+	}
+}
@@ -0,0 +1,465 @@
+package rag
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ingestManifestVersion is the version of the manifest file format
+const ingestManifestVersion = 1
+
+// IngestConfig configures directory ingestion. Globs are matched against
+// slash-separated paths relative to the ingested root: "*" and "?" match
+// within a path segment, "**" matches any number of segments, and a
+// pattern without a slash matches the name of a file or directory at any
+// depth.
+type IngestConfig struct {
+	Include      []string `json:"include,omitempty"`       // files to ingest; empty means every file of a known format
+	Exclude      []string `json:"exclude,omitempty"`       // files and directories to skip, winning over Include
+	ManifestPath string   `json:"manifest_path,omitempty"` // content hashes of ingested files; empty to ingest everything on every run
+	ChunkCode    bool     `json:"chunk_code"`              // chunk source files with ChunkByCode whatever the configured strategy
+}
+
+// DefaultIngestConfig returns the default ingestion configuration, which
+// skips hidden files and dependency directories
+func DefaultIngestConfig() *IngestConfig {
+	return &IngestConfig{
+		Exclude:   []string{".*", "node_modules", "vendor"},
+		ChunkCode: true,
+	}
+}
+
+// IngestReport summarizes an ingestion run
+type IngestReport struct {
+	Added     []string        `json:"added,omitempty"`   // document IDs of new files
+	Updated   []string        `json:"updated,omitempty"` // document IDs of modified files
+	Deleted   []string        `json:"deleted,omitempty"` // document IDs of removed files
+	Unchanged int             `json:"unchanged"`
+	Failed    []IngestFailure `json:"failed,omitempty"`
+}
+
+// IngestFailure is a file that could not be ingested; it is retried on the
+// next run
+type IngestFailure struct {
+	ID  string `json:"id"`
+	Err error  `json:"-"`
+}
+
+// Error describes the failure
+func (f IngestFailure) Error() string {
+	return fmt.Sprintf("%s: %v", f.ID, f.Err)
+}
+
+// ingestManifest records the files ingested into a retriever by document ID
+type ingestManifest struct {
+	Version int                            `json:"version"`
+	Files   map[string]ingestManifestEntry `json:"files"`
+}
+
+// ingestManifestEntry is the state of a file when it was last ingested
+type ingestManifestEntry struct {
+	Hash       string    `json:"hash"` // SHA-256 of the content, hex encoded
+	Size       int64     `json:"size"`
+	IngestedAt time.Time `json:"ingested_at"`
+}
+
+// Ingester loads files from directories into a retriever. Files are read
+// through the retriever's document processor, so their format is detected
+// from the extension. A manifest of content hashes makes runs incremental:
+// only new and modified files are embedded, and the chunks of files that
+// disappeared are deleted.
+type Ingester struct {
+	retriever Retriever
+	config    *IngestConfig
+	mu        sync.Mutex // one run at a time, as each rewrites the manifest
+}
+
+// NewIngester creates an ingester adding documents to retriever
+func NewIngester(retriever Retriever, config *IngestConfig) (*Ingester, error) {
+	if retriever == nil {
+		return nil, NewRAGErrorWithOp("new_ingester", "retriever is required", ErrorTypeValidation)
+	}
+	if config == nil {
+		config = DefaultIngestConfig()
+	}
+
+	for _, pattern := range append(append([]string(nil), config.Include...), config.Exclude...) {
+		if _, err := path.Match(strings.ReplaceAll(pattern, "**", "*"), ""); err != nil {
+			return nil, ValidationError("pattern", fmt.Sprintf("invalid glob %q: %v", pattern, err))
+		}
+	}
+
+	return &Ingester{
+		retriever: retriever,
+		config:    config,
+	}, nil
+}
+
+// Ingest brings the retriever in line with the files under root, which may
+// also be a single file. Document IDs are the slash-separated paths of the
+// files, root included. Files that fail are reported and skipped; the
+// manifest is saved even when ingestion stops early, so the next run picks
+// up where this one left off.
+func (g *Ingester) Ingest(ctx context.Context, root string) (report *IngestReport, err error) {
+	root = filepath.Clean(root)
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, NewRAGErrorWithCause("failed to read ingest root", ErrorTypeValidation, err).WithOperation("ingest")
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	manifest, err := g.loadManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	report = &IngestReport{}
+	defer func() {
+		if saveErr := g.saveManifest(manifest); saveErr != nil && err == nil {
+			err = saveErr
+		}
+	}()
+
+	seen := make(map[string]bool)
+	var unreadable []string
+	err = g.scan(ctx, root, info, func(name string, _ fs.FileInfo) error {
+		id := filepath.ToSlash(name)
+		seen[id] = true
+		return g.ingestFile(ctx, name, id, manifest, report)
+	}, func(name string, err error) {
+		unreadable = append(unreadable, filepath.ToSlash(name))
+		report.Failed = append(report.Failed, IngestFailure{ID: filepath.ToSlash(name), Err: err})
+	})
+	if err != nil {
+		return report, NewRAGErrorWithCause("ingestion stopped", ErrorTypeInternal, err).WithOperation("ingest")
+	}
+
+	// Delete the documents of files that are gone or no longer selected.
+	// Files below a directory that could not be read are kept for the next
+	// run to retry.
+	var removed []string
+	for id := range manifest.Files {
+		if !seen[id] && ingestRootContains(root, id, info.IsDir()) && !withinAnyPath(unreadable, id) {
+			removed = append(removed, id)
+		}
+	}
+	sort.Strings(removed)
+
+	for _, id := range removed {
+		g.deleteFile(ctx, id, manifest, report)
+	}
+
+	return report, nil
+}
+
+// Sync ingests the given files and deletes the documents of removed ones
+// without walking any directory, for callers that track changes
+// themselves. Paths become document IDs as in Ingest, so they must be
+// given the way Ingest sees them: below a root as passed to Ingest.
+func (g *Ingester) Sync(ctx context.Context, changed, removed []string) (report *IngestReport, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	manifest, err := g.loadManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	report = &IngestReport{}
+	defer func() {
+		if saveErr := g.saveManifest(manifest); saveErr != nil && err == nil {
+			err = saveErr
+		}
+	}()
+
+	for _, name := range changed {
+		name = filepath.Clean(name)
+		if err := g.ingestFile(ctx, name, filepath.ToSlash(name), manifest, report); err != nil {
+			return report, NewRAGErrorWithCause("ingestion stopped", ErrorTypeInternal, err).WithOperation("ingest")
+		}
+	}
+	for _, name := range removed {
+		g.deleteFile(ctx, filepath.ToSlash(filepath.Clean(name)), manifest, report)
+	}
+
+	return report, nil
+}
+
+// scan calls visit for every selected regular file under root, or for root
+// itself when it is a file, and fail for entries that cannot be read
+func (g *Ingester) scan(ctx context.Context, root string, info fs.FileInfo, visit func(name string, info fs.FileInfo) error, fail func(name string, err error)) error {
+	if !info.IsDir() {
+		return visit(root, info)
+	}
+
+	return filepath.WalkDir(root, func(name string, entry fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			if name == root {
+				return walkErr
+			}
+			fail(name, walkErr)
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, name)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if entry.IsDir() {
+			if name != root && matchAnyGlob(g.config.Exclude, rel) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() || !g.selected(rel) || g.isManifest(name) {
+			return nil
+		}
+
+		fileInfo, err := entry.Info()
+		if err != nil {
+			// Removed since the directory was read
+			return nil
+		}
+		return visit(name, fileInfo)
+	})
+}
+
+// isManifest reports whether a file is the manifest, or its temporary
+// copy, which is never ingested even when it lies below the root
+func (g *Ingester) isManifest(name string) bool {
+	if g.config.ManifestPath == "" {
+		return false
+	}
+
+	manifest, err := filepath.Abs(g.config.ManifestPath)
+	if err != nil {
+		return false
+	}
+	abs, err := filepath.Abs(name)
+	return err == nil && (abs == manifest || abs == manifest+".tmp")
+}
+
+// deleteFile deletes the document of a removed file; a document that is
+// already gone counts as deleted
+func (g *Ingester) deleteFile(ctx context.Context, id string, manifest *ingestManifest, report *IngestReport) {
+	if err := g.retriever.DeleteDocument(ctx, id); err != nil && !isNotFound(err) {
+		report.Failed = append(report.Failed, IngestFailure{ID: id, Err: err})
+		return
+	}
+	delete(manifest.Files, id)
+	report.Deleted = append(report.Deleted, id)
+}
+
+// ingestFile adds or updates one file unless its content hash matches the
+// manifest
+func (g *Ingester) ingestFile(ctx context.Context, name, id string, manifest *ingestManifest, report *IngestReport) error {
+	content, err := os.ReadFile(name)
+	if err != nil {
+		report.Failed = append(report.Failed, IngestFailure{ID: id, Err: err})
+		return nil
+	}
+
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+	previous, known := manifest.Files[id]
+	if known && previous.Hash == hash {
+		report.Unchanged++
+		return nil
+	}
+
+	doc := Document{
+		ID:       id,
+		Source:   id,
+		Title:    filepath.Base(name),
+		Content:  string(content),
+		Metadata: make(map[string]string),
+	}
+	if info, err := os.Stat(name); err == nil {
+		doc.UpdatedAt = info.ModTime()
+	}
+	if format, ok := canonicalFormat(filepath.Ext(name)); ok && format == FormatCode && g.config.ChunkCode {
+		doc.Metadata[MetadataChunkStrategy] = string(ChunkByCode)
+	}
+
+	// UpdateDocument also replaces chunks left by a run whose manifest was lost
+	if err := g.retriever.UpdateDocument(ctx, doc); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		// The old chunks may be gone already, so ingest the file again next run
+		delete(manifest.Files, id)
+		report.Failed = append(report.Failed, IngestFailure{ID: id, Err: err})
+		return nil
+	}
+
+	manifest.Files[id] = ingestManifestEntry{
+		Hash:       hash,
+		Size:       int64(len(content)),
+		IngestedAt: time.Now(),
+	}
+	if known {
+		report.Updated = append(report.Updated, id)
+	} else {
+		report.Added = append(report.Added, id)
+	}
+	return nil
+}
+
+// selected reports whether a file below the root is ingested
+func (g *Ingester) selected(rel string) bool {
+	if matchAnyGlob(g.config.Exclude, rel) {
+		return false
+	}
+	if len(g.config.Include) > 0 {
+		return matchAnyGlob(g.config.Include, rel)
+	}
+	_, ok := canonicalFormat(path.Ext(rel))
+	return ok
+}
+
+// loadManifest reads the manifest; a missing file is an empty manifest
+func (g *Ingester) loadManifest() (*ingestManifest, error) {
+	manifest := &ingestManifest{Version: ingestManifestVersion, Files: make(map[string]ingestManifestEntry)}
+	if g.config.ManifestPath == "" {
+		return manifest, nil
+	}
+
+	data, err := os.ReadFile(g.config.ManifestPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return manifest, nil
+	}
+	if err != nil {
+		return nil, NewRAGErrorWithCause("failed to read ingest manifest", ErrorTypeInternal, err).WithOperation("ingest")
+	}
+
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, NewRAGErrorWithCause("failed to parse ingest manifest", ErrorTypeInternal, err).WithOperation("ingest")
+	}
+	if manifest.Version != ingestManifestVersion {
+		return nil, NewRAGErrorWithOp("ingest", fmt.Sprintf("unsupported ingest manifest version %d", manifest.Version), ErrorTypeInternal)
+	}
+	if manifest.Files == nil {
+		manifest.Files = make(map[string]ingestManifestEntry)
+	}
+
+	return manifest, nil
+}
+
+// saveManifest writes the manifest through a temporary file, so a crash
+// never leaves a partial manifest behind
+func (g *Ingester) saveManifest(manifest *ingestManifest) error {
+	if g.config.ManifestPath == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return NewRAGErrorWithCause("failed to encode ingest manifest", ErrorTypeInternal, err).WithOperation("ingest")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(g.config.ManifestPath), 0755); err != nil {
+		return NewRAGErrorWithCause("failed to write ingest manifest", ErrorTypeInternal, err).WithOperation("ingest")
+	}
+	tmp := g.config.ManifestPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return NewRAGErrorWithCause("failed to write ingest manifest", ErrorTypeInternal, err).WithOperation("ingest")
+	}
+	if err := os.Rename(tmp, g.config.ManifestPath); err != nil {
+		os.Remove(tmp)
+		return NewRAGErrorWithCause("failed to write ingest manifest", ErrorTypeInternal, err).WithOperation("ingest")
+	}
+
+	return nil
+}
+
+// ingestRootContains reports whether a document ID names a file under root,
+// or root itself when it is a file
+func ingestRootContains(root, id string, dir bool) bool {
+	slashRoot := filepath.ToSlash(root)
+	if !dir {
+		return id == slashRoot
+	}
+	if slashRoot == "." {
+		return !filepath.IsAbs(filepath.FromSlash(id)) && id != ".." && !strings.HasPrefix(id, "../")
+	}
+	return strings.HasPrefix(id, strings.TrimSuffix(slashRoot, "/")+"/")
+}
+
+// withinAnyPath reports whether a slash-separated path is one of paths or
+// lies below one of them
+func withinAnyPath(paths []string, name string) bool {
+	for _, p := range paths {
+		if name == p || strings.HasPrefix(name, strings.TrimSuffix(p, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// isNotFound reports whether err is a RAG not found error
+func isNotFound(err error) bool {
+	var ragErr *RAGError
+	return errors.As(err, &ragErr) && ragErr.Type == ErrorTypeNotFound
+}
+
+// matchAnyGlob reports whether the slash-separated path matches one of the
+// patterns
+func matchAnyGlob(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matchGlob(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob matches a slash-separated path against a pattern; a pattern
+// without a slash is matched against the last path segment
+func matchGlob(pattern, name string) bool {
+	pattern = strings.TrimPrefix(strings.TrimSuffix(pattern, "/"), "./")
+	if !strings.Contains(pattern, "/") && pattern != "**" {
+		ok, _ := path.Match(pattern, path.Base(name))
+		return ok
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+// matchSegments matches path segments, "**" standing for any number of them
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for skip := 0; skip <= len(name); skip++ {
+				if matchSegments(pattern[1:], name[skip:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
@@ -0,0 +1,166 @@
+package rag
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// memoryRetriever is a Retriever keeping whole documents by ID, for tests of
+// code that only adds and deletes documents
+type memoryRetriever struct {
+	mu   sync.Mutex
+	docs map[string]Document
+}
+
+func newMemoryRetriever() *memoryRetriever {
+	return &memoryRetriever{docs: make(map[string]Document)}
+}
+
+func (r *memoryRetriever) Retrieve(ctx context.Context, query Query) (*RetrievalResult, error) {
+	return nil, ErrNotImplemented
+}
+
+func (r *memoryRetriever) AddDocument(ctx context.Context, doc Document) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.docs[doc.ID] = doc
+	return nil
+}
+
+func (r *memoryRetriever) AddDocuments(ctx context.Context, docs []Document) error {
+	for _, doc := range docs {
+		r.AddDocument(ctx, doc)
+	}
+	return nil
+}
+
+func (r *memoryRetriever) UpdateDocument(ctx context.Context, doc Document) error {
+	return r.AddDocument(ctx, doc)
+}
+
+func (r *memoryRetriever) DeleteDocument(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.docs[id]; !ok {
+		return NotFoundError("document", id)
+	}
+	delete(r.docs, id)
+	return nil
+}
+
+func (r *memoryRetriever) GetDocument(ctx context.Context, id string) (*Document, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	doc, ok := r.docs[id]
+	if !ok {
+		return nil, NotFoundError("document", id)
+	}
+	return &doc, nil
+}
+
+func (r *memoryRetriever) GetStats() RetrievalStats { return RetrievalStats{} }
+
+func (r *memoryRetriever) Close() error { return nil }
+
+// ids returns the IDs of the stored documents in order
+func (r *memoryRetriever) ids() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ids := make([]string, 0, len(r.docs))
+	for id := range r.docs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// writeTestFiles creates files with the given content below dir
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// makeUnreadable removes all permissions from dir until the test ends;
+// the test is skipped where permissions do not apply
+func makeUnreadable(t *testing.T, dir string) {
+	t.Helper()
+	if os.Geteuid() == 0 {
+		t.Skip("permissions do not apply to root")
+	}
+	if err := os.Chmod(dir, 0); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(dir, 0755) })
+}
+
+func TestIngestDeletesRemovedFiles(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "docs")
+	writeTestFiles(t, root, map[string]string{"a.md": "alpha", "sub/b.md": "beta"})
+
+	retriever := newMemoryRetriever()
+	ingester, err := NewIngester(retriever, &IngestConfig{ManifestPath: filepath.Join(t.TempDir(), "manifest.json")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ingester.Ingest(context.Background(), root); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Remove(filepath.Join(root, "sub", "b.md")); err != nil {
+		t.Fatal(err)
+	}
+	report, err := ingester.Ingest(context.Background(), root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	removed := filepath.ToSlash(filepath.Join(root, "sub", "b.md"))
+	if len(report.Deleted) != 1 || report.Deleted[0] != removed {
+		t.Fatalf("deleted %v, want %s", report.Deleted, removed)
+	}
+	if ids := retriever.ids(); len(ids) != 1 {
+		t.Fatalf("retriever holds %v, want only a.md", ids)
+	}
+}
+
+func TestIngestKeepsFilesOfUnreadableDirectories(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "docs")
+	writeTestFiles(t, root, map[string]string{"a.md": "alpha", "sub/b.md": "beta", "sub/deeper/c.md": "gamma"})
+
+	retriever := newMemoryRetriever()
+	ingester, err := NewIngester(retriever, &IngestConfig{ManifestPath: filepath.Join(t.TempDir(), "manifest.json")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ingester.Ingest(context.Background(), root); err != nil {
+		t.Fatal(err)
+	}
+
+	makeUnreadable(t, filepath.Join(root, "sub"))
+	report, err := ingester.Ingest(context.Background(), root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Deleted) != 0 {
+		t.Fatalf("deleted %v below an unreadable directory", report.Deleted)
+	}
+	if len(report.Failed) != 1 {
+		t.Fatalf("failed %v, want the unreadable directory", report.Failed)
+	}
+	if ids := retriever.ids(); len(ids) != 3 {
+		t.Fatalf("retriever holds %v, want all three files", ids)
+	}
+}
@@ -120,6 +120,7 @@ type CacheStats struct {
 	Size        int     `json:"size"`
 	MaxSize     int     `json:"max_size"`
 	MemoryUsage int64   `json:"memory_usage_bytes"`
+	DiskUsage   int64   `json:"disk_usage_bytes,omitempty"`
 }
 
 // QueryProcessor handles query preprocessing and optimization
@@ -0,0 +1,372 @@
+package rag
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/PerceptivePenguin/MCPRAG-Go/internal/vector"
+)
+
+// LFUCache implements the Cache interface evicting the least frequently used
+// entry, the least recently used among equally frequent ones. Entries are
+// kept in buckets of equal use count, ordered by count, so every operation
+// takes constant time.
+type LFUCache struct {
+	mu      sync.RWMutex
+	items   map[string]*list.Element // of *lfuItem
+	buckets *list.List               // of *lfuBucket, ascending count
+	maxSize int
+	config  *CacheConfig
+	metrics CacheStats
+	closed  bool
+}
+
+// lfuBucket holds the entries used count times, most recently used first
+type lfuBucket struct {
+	count int
+	items *list.List // of *lfuItem
+}
+
+// lfuItem is a cached entry and the bucket it is in
+type lfuItem struct {
+	key      string
+	entry    *CacheEntry
+	bucket   *list.Element // of *lfuBucket
+	expireAt time.Time
+}
+
+// NewLFUCache creates a new LFU cache with the given configuration
+func NewLFUCache(config *CacheConfig) (*LFUCache, error) {
+	if config == nil {
+		config = DefaultCacheConfig()
+	}
+
+	if config.MaxSize <= 0 {
+		return nil, ErrInvalidCacheSize
+	}
+
+	cache := &LFUCache{
+		items:   make(map[string]*list.Element),
+		buckets: list.New(),
+		maxSize: config.MaxSize,
+		config:  config,
+		metrics: CacheStats{
+			MaxSize: config.MaxSize,
+		},
+	}
+
+	// Load from persistence if configured
+	if config.PersistPath != "" {
+		if err := cache.loadFromDisk(); err != nil {
+			// Log error but don't fail initialization
+			fmt.Printf("Warning: failed to load cache from disk: %v\n", err)
+		}
+	}
+
+	return cache, nil
+}
+
+// Get retrieves a cached embedding by key
+func (c *LFUCache) Get(ctx context.Context, key string) (*CacheEntry, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil, ErrCacheClosed
+	}
+
+	elem, exists := c.items[key]
+	if !exists {
+		c.metrics.Misses++
+		c.updateHitRate()
+		return nil, ErrCacheKeyNotFound
+	}
+
+	// Check if entry has expired
+	item := elem.Value.(*lfuItem)
+	if c.config.TTL > 0 && time.Now().After(item.expireAt) {
+		c.remove(elem)
+		c.metrics.Misses++
+		c.updateHitRate()
+		return nil, ErrCacheKeyNotFound
+	}
+
+	c.touch(elem)
+	item.entry.AccessedAt = time.Now()
+
+	c.metrics.Hits++
+	c.updateHitRate()
+
+	return item.entry, nil
+}
+
+// Set stores an embedding in the cache
+func (c *LFUCache) Set(ctx context.Context, key string, vector vector.Vector, metadata map[string]string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return ErrCacheClosed
+	}
+
+	now := time.Now()
+	var expireAt time.Time
+	if c.config.TTL > 0 {
+		expireAt = now.Add(c.config.TTL)
+	}
+
+	// Updating an entry counts as a use
+	if elem, exists := c.items[key]; exists {
+		item := elem.Value.(*lfuItem)
+		size := calculateCacheEntrySize(key, vector, metadata)
+		c.metrics.MemoryUsage += int64(size - item.entry.Size)
+		item.entry.Vector = vector
+		item.entry.Metadata = metadata
+		item.entry.AccessedAt = now
+		item.entry.Size = size
+		item.expireAt = expireAt
+		c.touch(elem)
+		return nil
+	}
+
+	// Make room before inserting, so the new entry is not the one evicted
+	for len(c.items) >= c.maxSize {
+		c.evict()
+	}
+
+	c.insert(&CacheEntry{
+		Key:         key,
+		Vector:      vector,
+		Metadata:    metadata,
+		CreatedAt:   now,
+		AccessedAt:  now,
+		AccessCount: 1,
+		Size:        calculateCacheEntrySize(key, vector, metadata),
+	}, expireAt)
+
+	return nil
+}
+
+// Delete removes an entry from the cache
+func (c *LFUCache) Delete(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return ErrCacheClosed
+	}
+
+	elem, exists := c.items[key]
+	if !exists {
+		return ErrCacheKeyNotFound
+	}
+
+	c.remove(elem)
+	return nil
+}
+
+// Clear removes all entries from the cache
+func (c *LFUCache) Clear(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return ErrCacheClosed
+	}
+
+	c.items = make(map[string]*list.Element)
+	c.buckets.Init()
+	c.metrics.Size = 0
+	c.metrics.MemoryUsage = 0
+
+	return nil
+}
+
+// Size returns the number of cached entries
+func (c *LFUCache) Size() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.items)
+}
+
+// Stats returns cache statistics
+func (c *LFUCache) Stats() CacheStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.metrics
+}
+
+// Close releases any resources held by the cache
+func (c *LFUCache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil
+	}
+
+	// Persist to disk if configured
+	if c.config.PersistPath != "" {
+		if err := c.saveToDisk(); err != nil {
+			return fmt.Errorf("failed to persist cache: %w", err)
+		}
+	}
+
+	c.closed = true
+	return nil
+}
+
+// EvictExpiredEntries removes expired entries from the cache
+func (c *LFUCache) EvictExpiredEntries(ctx context.Context) int {
+	if c.config.TTL == 0 {
+		return 0 // No TTL configured
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return 0
+	}
+
+	now := time.Now()
+	evicted := 0
+	for _, elem := range c.items {
+		if now.After(elem.Value.(*lfuItem).expireAt) {
+			c.remove(elem)
+			evicted++
+		}
+	}
+
+	return evicted
+}
+
+// Helper methods for the frequency buckets
+
+// insert adds an entry used AccessCount times, creating its bucket if
+// needed
+func (c *LFUCache) insert(entry *CacheEntry, expireAt time.Time) {
+	count := max(entry.AccessCount, 1)
+
+	// Find the first bucket with at least count uses; new entries almost
+	// always land in the front bucket
+	at := c.buckets.Front()
+	for at != nil && at.Value.(*lfuBucket).count < count {
+		at = at.Next()
+	}
+
+	bucket := at
+	if bucket == nil || bucket.Value.(*lfuBucket).count != count {
+		b := &lfuBucket{count: count, items: list.New()}
+		if at == nil {
+			bucket = c.buckets.PushBack(b)
+		} else {
+			bucket = c.buckets.InsertBefore(b, at)
+		}
+	}
+
+	item := &lfuItem{key: entry.Key, entry: entry, bucket: bucket, expireAt: expireAt}
+	c.items[entry.Key] = bucket.Value.(*lfuBucket).items.PushFront(item)
+	c.metrics.Size = len(c.items)
+	c.metrics.MemoryUsage += int64(entry.Size)
+}
+
+// touch counts a use of the entry, moving it to the next bucket
+func (c *LFUCache) touch(elem *list.Element) {
+	item := elem.Value.(*lfuItem)
+	current := item.bucket
+	count := current.Value.(*lfuBucket).count + 1
+	item.entry.AccessCount = count
+
+	next := current.Next()
+	if next == nil || next.Value.(*lfuBucket).count != count {
+		next = c.buckets.InsertAfter(&lfuBucket{count: count, items: list.New()}, current)
+	}
+
+	c.unlink(elem)
+	item.bucket = next
+	c.items[item.key] = next.Value.(*lfuBucket).items.PushFront(item)
+}
+
+// evict removes the least recently used entry of the least used bucket
+func (c *LFUCache) evict() {
+	front := c.buckets.Front()
+	if front == nil {
+		return
+	}
+	c.remove(front.Value.(*lfuBucket).items.Back())
+}
+
+// remove deletes an entry from its bucket and the index
+func (c *LFUCache) remove(elem *list.Element) {
+	item := elem.Value.(*lfuItem)
+	c.unlink(elem)
+	delete(c.items, item.key)
+	c.metrics.Size = len(c.items)
+	c.metrics.MemoryUsage -= int64(item.entry.Size)
+}
+
+// unlink takes an entry out of its bucket, dropping the bucket when it
+// becomes empty
+func (c *LFUCache) unlink(elem *list.Element) {
+	item := elem.Value.(*lfuItem)
+	bucket := item.bucket.Value.(*lfuBucket)
+	bucket.items.Remove(elem)
+	if bucket.items.Len() == 0 {
+		c.buckets.Remove(item.bucket)
+	}
+}
+
+func (c *LFUCache) updateHitRate() {
+	total := c.metrics.Hits + c.metrics.Misses
+	if total > 0 {
+		c.metrics.HitRate = float64(c.metrics.Hits) / float64(total)
+	}
+}
+
+// Persistence methods
+
+func (c *LFUCache) saveToDisk() error {
+	data := make(map[string]*CacheEntry)
+	for key, elem := range c.items {
+		item := elem.Value.(*lfuItem)
+		if c.config.TTL == 0 || time.Now().Before(item.expireAt) {
+			data[key] = item.entry
+		}
+	}
+
+	return writeCacheFile(c.config.PersistPath, data)
+}
+
+// loadFromDisk restores saved entries with their use counts, keeping the
+// most used ones when there are more than fit
+func (c *LFUCache) loadFromDisk() error {
+	data, err := readCacheFile(c.config.PersistPath)
+	if err != nil {
+		return err
+	}
+
+	var expireAt time.Time
+	if c.config.TTL > 0 {
+		expireAt = time.Now().Add(c.config.TTL)
+	}
+	for key, entry := range data {
+		entry.Key = key
+		if len(c.items) >= c.maxSize {
+			front := c.buckets.Front()
+			if front.Value.(*lfuBucket).count >= entry.AccessCount {
+				continue
+			}
+			c.evict()
+		}
+		c.insert(entry, expireAt)
+	}
+
+	return nil
+}
+
+// Ensure LFUCache implements Cache
+var _ Cache = (*LFUCache)(nil)
@@ -0,0 +1,136 @@
+package rag
+
+import (
+	"github.com/PerceptivePenguin/MCPRAG-Go/internal/vector"
+)
+
+// diversityPoolFactor is how many more candidates than requested are
+// retrieved for diversification to choose from
+const diversityPoolFactor = 4
+
+// defaultMMRLambda weighs relevance and redundancy equally
+const defaultMMRLambda = 0.5
+
+// diversify keeps k of the candidates in result, chosen by maximal marginal
+// relevance when query.MMR is set and at most query.MaxPerParent per parent
+// document when that is set. MMR compares the stored vectors of the
+// candidates; scores keep their original relevance values.
+func (r *BasicRetriever) diversify(query Query, result *RetrievalResult, k int) {
+	docs := result.Documents
+	if len(docs) == 0 {
+		return
+	}
+
+	var vectors []vector.Vector
+	if query.MMR {
+		vectors = make([]vector.Vector, len(docs))
+		for i, doc := range docs {
+			if len(doc.Vector) > 0 {
+				vectors[i] = doc.Vector
+			} else if stored, err := r.vectorStore.Get(doc.ID); err == nil {
+				vectors[i] = stored.Vector
+			}
+		}
+	}
+
+	lambda := query.MMRLambda
+	if lambda == 0 {
+		lambda = defaultMMRLambda
+	}
+
+	order := selectDiverse(docs, result.Scores, vectors, lambda, query.MaxPerParent, k)
+
+	selectedDocs := make([]Document, len(order))
+	selectedScores := make([]float32, len(order))
+	var contributions [][]StrategyContribution
+	if len(result.Contributions) == len(docs) {
+		contributions = make([][]StrategyContribution, len(order))
+	}
+	for i, idx := range order {
+		selectedDocs[i] = docs[idx]
+		selectedScores[i] = result.Scores[idx]
+		if contributions != nil {
+			contributions[i] = result.Contributions[idx]
+		}
+	}
+
+	result.Documents = selectedDocs
+	result.Scores = selectedScores
+	result.Contributions = contributions
+}
+
+// selectDiverse greedily picks up to k candidates, ranked by relevance. With
+// vectors each pick maximizes lambda * relevance - (1 - lambda) * similarity
+// to the closest candidate already picked, relevance being the score
+// rescaled to [0, 1]. A positive maxPerParent skips candidates whose parent
+// document already has that many picks. It returns the picked indexes in
+// order.
+func selectDiverse(docs []Document, scores []float32, vectors []vector.Vector, lambda float32, maxPerParent int, k int) []int {
+	relevance := make([]float32, len(scores))
+	lo, hi := scores[0], scores[0]
+	for _, s := range scores {
+		lo = min(lo, s)
+		hi = max(hi, s)
+	}
+	for i, s := range scores {
+		relevance[i] = 1
+		if hi > lo {
+			relevance[i] = (s - lo) / (hi - lo)
+		}
+	}
+
+	// redundancy[i] is the highest similarity of candidate i to a pick
+	redundancy := make([]float32, len(docs))
+	picked := make([]bool, len(docs))
+	perParent := make(map[string]int)
+
+	var order []int
+	for len(order) < k {
+		best := -1
+		var bestValue float32
+		for i, doc := range docs {
+			if picked[i] {
+				continue
+			}
+			if maxPerParent > 0 && perParent[parentKey(doc)] >= maxPerParent {
+				continue
+			}
+
+			value := relevance[i]
+			if vectors != nil {
+				value = lambda*relevance[i] - (1-lambda)*redundancy[i]
+			}
+			if best < 0 || value > bestValue {
+				best, bestValue = i, value
+			}
+		}
+		if best < 0 {
+			break
+		}
+
+		picked[best] = true
+		perParent[parentKey(docs[best])]++
+		order = append(order, best)
+
+		if vectors == nil || len(vectors[best]) == 0 {
+			continue
+		}
+		for i := range docs {
+			if picked[i] || len(vectors[i]) != len(vectors[best]) {
+				continue
+			}
+			redundancy[i] = max(redundancy[i], vector.CosineSimilarity(vectors[i], vectors[best]))
+		}
+	}
+
+	return order
+}
+
+// parentKey groups chunks of the same document; chunks without a parent are
+// their own group
+func parentKey(doc Document) string {
+	if doc.ParentID != "" {
+		return doc.ParentID
+	}
+	return doc.ID
+}
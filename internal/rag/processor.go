@@ -0,0 +1,701 @@
+package rag
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Document formats understood by BasicDocumentProcessor
+const (
+	FormatText     = "txt"
+	FormatMarkdown = "md"
+	FormatJSON     = "json"
+	FormatHTML     = "html"
+	FormatCode     = "code" // source code in any language known to the ChunkByCode strategy
+)
+
+// Metadata keys set on chunks by BasicDocumentProcessor
+const (
+	MetadataFormat  = "format"  // document format; also read from document metadata to force a format
+	MetadataHeading = "heading" // innermost Markdown heading above the chunk
+	MetadataSection = "section" // full Markdown heading path, joined with " > "
+
+	// MetadataChunkStrategy is the strategy a chunk was split with; also
+	// read from document metadata to force a strategy for one document
+	MetadataChunkStrategy = "chunk_strategy"
+)
+
+// formatAliases maps file extensions and format names to canonical formats
+var formatAliases = map[string]string{
+	"txt":      FormatText,
+	"text":     FormatText,
+	"md":       FormatMarkdown,
+	"markdown": FormatMarkdown,
+	"mdown":    FormatMarkdown,
+	"mkd":      FormatMarkdown,
+	"json":     FormatJSON,
+	"html":     FormatHTML,
+	"htm":      FormatHTML,
+	"xhtml":    FormatHTML,
+	"code":     FormatCode,
+}
+
+// BasicDocumentProcessor implements DocumentProcessor. It extracts plain
+// text from the supported formats and splits it with a TextChunker; Markdown
+// is chunked section by section so every chunk records its heading path.
+type BasicDocumentProcessor struct {
+	chunker *TextChunker
+	options *ProcessingOptions
+}
+
+// NewDocumentProcessor creates a document processor that chunks with the
+// given tokenizer
+func NewDocumentProcessor(options *ProcessingOptions, tokenizer Tokenizer) *BasicDocumentProcessor {
+	if options == nil {
+		options = DefaultProcessingOptions()
+	}
+
+	return &BasicDocumentProcessor{
+		chunker: NewTextChunker(tokenizer),
+		options: options,
+	}
+}
+
+// SetEmbedder sets the embedder used by the semantic chunking strategy. It
+// must be called before the processor is used.
+func (p *BasicDocumentProcessor) SetEmbedder(embedder Embedder) {
+	p.chunker.SetEmbedder(embedder)
+}
+
+// textSection is a run of extracted text below one heading path
+type textSection struct {
+	headings []string
+	text     string
+	offset   int // byte offset of text in the full extracted text
+}
+
+// Process extracts the text of a document and splits it into chunks
+func (p *BasicDocumentProcessor) Process(ctx context.Context, doc Document, options ChunkingOptions) ([]Chunk, error) {
+	if err := p.ValidateDocument(doc); err != nil {
+		return nil, err
+	}
+
+	format := p.detectFormat(doc)
+	if strategy, ok := doc.Metadata[MetadataChunkStrategy]; ok {
+		options.Strategy = ChunkStrategy(strategy)
+	}
+
+	// Offset of the chunked text in the document, or -1 when the text is
+	// not a slice of it: parsed Markdown, extracted HTML and JSON, and
+	// normalized newlines have offsets of their own
+	base := -1
+	var sections []textSection
+	if format == FormatMarkdown {
+		sections = parseMarkdown(doc.Content)
+	} else {
+		text, err := p.ExtractText(ctx, []byte(doc.Content), format)
+		if err != nil {
+			return nil, err
+		}
+		sections = []textSection{{text: text}}
+		if format == FormatText || format == FormatCode {
+			base = strings.Index(doc.Content, text)
+		}
+	}
+
+	var chunks []Chunk
+	for _, section := range sections {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if strings.TrimSpace(section.text) == "" {
+			continue
+		}
+
+		part := doc
+		part.Content = section.text
+
+		sectionChunks, err := p.chunker.ChunkDocument(ctx, part, options)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, chunk := range sectionChunks {
+			chunk.StartPos += section.offset
+			chunk.EndPos += section.offset
+			chunk.Metadata[MetadataFormat] = format
+			if base >= 0 && verbatimChunk(options.Strategy, chunk) {
+				chunk.Metadata[MetadataStartPos] = strconv.Itoa(base + chunk.StartPos)
+				chunk.Metadata[MetadataEndPos] = strconv.Itoa(base + chunk.EndPos)
+			}
+			if len(section.headings) > 0 {
+				chunk.Metadata[MetadataHeading] = section.headings[len(section.headings)-1]
+				chunk.Metadata[MetadataSection] = strings.Join(section.headings, " > ")
+			}
+			chunks = append(chunks, chunk)
+		}
+	}
+
+	if len(chunks) == 0 {
+		return nil, ErrDocumentEmpty.WithOperation("process_document")
+	}
+
+	// Number chunks across sections
+	for i := range chunks {
+		chunks[i].Index = i
+		chunks[i].ID = fmt.Sprintf("%s_chunk_%d", doc.ID, i)
+	}
+
+	return chunks, nil
+}
+
+// verbatimChunk reports whether the strategy cuts chunks as slices of the
+// text, so that their positions point into it. Sentence, paragraph and
+// semantic chunks are rebuilt from split text, and so are the chunks of
+// code the code strategy cannot parse.
+func verbatimChunk(strategy ChunkStrategy, chunk Chunk) bool {
+	switch strategy {
+	case ChunkByTokens, ChunkByFixedSize:
+		return true
+	case ChunkByCode:
+		_, parsed := chunk.Metadata[MetadataStartLine]
+		return parsed
+	}
+	return false
+}
+
+// ProcessFromReader reads a document and processes it. The format is taken
+// from the extension of docID when it has one and sniffed otherwise.
+func (p *BasicDocumentProcessor) ProcessFromReader(ctx context.Context, reader io.Reader, docID string, options ChunkingOptions) ([]Chunk, error) {
+	if reader == nil {
+		return nil, NewRAGErrorWithOp("process_from_reader", "reader is required", ErrorTypeValidation)
+	}
+
+	var r io.Reader = reader
+	if limit := p.options.MaxDocumentSize; limit > 0 {
+		r = io.LimitReader(reader, int64(limit)+1)
+	}
+
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, NewRAGErrorWithCause("failed to read document", ErrorTypeInternal, err).WithOperation("process_from_reader")
+	}
+
+	doc := Document{
+		ID:      docID,
+		Content: string(content),
+	}
+	if format, ok := canonicalFormat(filepath.Ext(docID)); ok {
+		doc.Metadata = map[string]string{MetadataFormat: format}
+	}
+
+	return p.Process(ctx, doc, options)
+}
+
+// ExtractText converts content of the given format to plain text
+func (p *BasicDocumentProcessor) ExtractText(ctx context.Context, content []byte, format string) (string, error) {
+	canonical, ok := canonicalFormat(format)
+	if !ok || !p.supports(canonical) {
+		return "", ErrDocumentInvalidFormat.WithOperation("extract_text").WithDetails(map[string]string{
+			"format": format,
+		})
+	}
+
+	if !utf8.Valid(content) {
+		return "", ErrDocumentInvalidFormat.WithOperation("extract_text").WithDetails(map[string]string{
+			"reason": "content is not valid UTF-8",
+		})
+	}
+
+	text := normalizeNewlines(string(content))
+
+	switch canonical {
+	case FormatMarkdown:
+		sections := parseMarkdown(text)
+		parts := make([]string, 0, len(sections))
+		for _, section := range sections {
+			parts = append(parts, section.text)
+		}
+		return strings.Join(parts, ""), nil
+	case FormatJSON:
+		return flattenJSON(text)
+	case FormatHTML:
+		return extractHTML(text), nil
+	case FormatCode:
+		// Keep leading lines so that chunk line numbers match the file
+		return text, nil
+	default:
+		return strings.TrimSpace(text), nil
+	}
+}
+
+// ValidateDocument checks that a document has an ID and content within
+// MaxDocumentSize, and that a format forced through metadata is supported
+func (p *BasicDocumentProcessor) ValidateDocument(doc Document) error {
+	if doc.ID == "" {
+		return ValidationError("id", "document ID is required")
+	}
+
+	if strings.TrimSpace(doc.Content) == "" {
+		return ErrDocumentEmpty.WithOperation("validate_document")
+	}
+
+	if p.options.MaxDocumentSize > 0 && len(doc.Content) > p.options.MaxDocumentSize {
+		return ErrDocumentTooLarge.WithOperation("validate_document").WithDetails(map[string]string{
+			"size":  fmt.Sprintf("%d", len(doc.Content)),
+			"limit": fmt.Sprintf("%d", p.options.MaxDocumentSize),
+		})
+	}
+
+	if !utf8.ValidString(doc.Content) {
+		return ErrDocumentInvalidFormat.WithOperation("validate_document").WithDetails(map[string]string{
+			"reason": "content is not valid UTF-8",
+		})
+	}
+
+	if format, ok := doc.Metadata[MetadataFormat]; ok {
+		if canonical, known := canonicalFormat(format); !known || !p.supports(canonical) {
+			return ErrDocumentInvalidFormat.WithOperation("validate_document").WithDetails(map[string]string{
+				"format": format,
+			})
+		}
+	}
+
+	return nil
+}
+
+// GetSupportedFormats returns the formats enabled in the processing options
+func (p *BasicDocumentProcessor) GetSupportedFormats() []string {
+	formats := make([]string, 0, len(p.options.SupportedFormats))
+	for _, format := range p.options.SupportedFormats {
+		if canonical, ok := canonicalFormat(format); ok {
+			formats = append(formats, canonical)
+		}
+	}
+	return formats
+}
+
+// supports reports whether a canonical format is enabled
+func (p *BasicDocumentProcessor) supports(format string) bool {
+	for _, supported := range p.GetSupportedFormats() {
+		if supported == format {
+			return true
+		}
+	}
+	return false
+}
+
+// detectFormat picks the format of a document from its metadata, the
+// extension of its source or ID, or finally its content
+func (p *BasicDocumentProcessor) detectFormat(doc Document) string {
+	if format, ok := canonicalFormat(doc.Metadata[MetadataFormat]); ok {
+		return format
+	}
+
+	for _, name := range []string{doc.Source, doc.ID} {
+		if format, ok := canonicalFormat(filepath.Ext(name)); ok && p.supports(format) {
+			return format
+		}
+	}
+
+	trimmed := strings.TrimSpace(doc.Content)
+	if (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && json.Valid([]byte(trimmed)) && p.supports(FormatJSON) {
+		return FormatJSON
+	}
+
+	lower := strings.ToLower(trimmed[:min(len(trimmed), 64)])
+	if (strings.HasPrefix(lower, "<!doctype html") || strings.HasPrefix(lower, "<html")) && p.supports(FormatHTML) {
+		return FormatHTML
+	}
+
+	return FormatText
+}
+
+// canonicalFormat maps a format name or file extension to a canonical
+// format; source file extensions map to FormatCode
+func canonicalFormat(format string) (string, bool) {
+	name := strings.ToLower(strings.TrimPrefix(format, "."))
+	if canonical, ok := formatAliases[name]; ok {
+		return canonical, true
+	}
+	if _, ok := codeLanguages[name]; ok {
+		return FormatCode, true
+	}
+	return "", false
+}
+
+func normalizeNewlines(text string) string {
+	text = strings.TrimPrefix(text, "\ufeff")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.ReplaceAll(text, "\r", "\n")
+}
+
+// Markdown
+
+var (
+	mdATXHeading   = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	mdSetextH1     = regexp.MustCompile(`^ {0,3}=+[ \t]*$`)
+	mdSetextH2     = regexp.MustCompile(`^ {0,3}-+[ \t]*$`)
+	mdFence        = regexp.MustCompile("^ {0,3}(```|~~~)")
+	mdRule         = regexp.MustCompile(`^ {0,3}([-*_])([ \t]*([-*_])){2,}[ \t]*$`)
+	mdBlockquote   = regexp.MustCompile(`^ {0,3}(>[ \t]?)+`)
+	mdListItem     = regexp.MustCompile(`^([ \t]*)([-*+]|\d{1,9}[.)])[ \t]+(\[[ xX]\][ \t]+)?`)
+	mdTableDivider = regexp.MustCompile(`^[ \t]*\|?[ \t]*:?-+:?[ \t]*(\|[ \t]*:?-+:?[ \t]*)*\|?[ \t]*$`)
+	mdLinkDef      = regexp.MustCompile(`^ {0,3}\[[^\]]+\]:[ \t]+\S+`)
+	mdImage        = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	mdLink         = regexp.MustCompile(`\[([^\]]+)\](\([^)]*\)|\[[^\]]*\])`)
+	mdAutolink     = regexp.MustCompile(`<((?:https?|mailto):[^>\s]+)>`)
+	mdCode         = regexp.MustCompile("`+([^`]+)`+")
+	mdStrong       = regexp.MustCompile(`(\*\*|__)([^*_]+)(\*\*|__)`)
+	mdEmphasis     = regexp.MustCompile(`(^|[^\w*])[*_]([^*_\s](?:[^*_]*[^*_\s])?)[*_]`)
+	mdStrike       = regexp.MustCompile(`~~([^~]+)~~`)
+	mdHTMLTag      = regexp.MustCompile(`</?[a-zA-Z][^>]*>`)
+)
+
+// parseMarkdown strips Markdown markup and splits the text into sections at
+// headings. Every section starts with its heading line; fenced code is kept
+// verbatim.
+func parseMarkdown(src string) []textSection {
+	lines := strings.Split(normalizeNewlines(src), "\n")
+
+	var (
+		sections []textSection
+		stack    []string // heading text per level, 1-based levels at index level-1
+		current  strings.Builder
+		headings []string
+		offset   int
+		fence    string
+	)
+
+	flush := func() {
+		text := strings.TrimRight(current.String(), "\n")
+		if strings.TrimSpace(text) != "" {
+			text += "\n\n"
+			sections = append(sections, textSection{headings: headings, text: text, offset: offset})
+			offset += len(text)
+		}
+		current.Reset()
+	}
+
+	startSection := func(level int, title string) {
+		flush()
+		if len(stack) >= level {
+			stack = stack[:level-1]
+		}
+		for len(stack) < level-1 {
+			stack = append(stack, "")
+		}
+		stack = append(stack, title)
+
+		headings = nil
+		for _, h := range stack {
+			if h != "" {
+				headings = append(headings, h)
+			}
+		}
+		current.WriteString(title)
+		current.WriteString("\n")
+	}
+
+	// pending holds the previous paragraph line so that a setext underline
+	// can turn it into a heading
+	pending, hasPending := "", false
+	emit := func(line string) {
+		if hasPending {
+			current.WriteString(pending)
+			current.WriteString("\n")
+		}
+		pending, hasPending = line, true
+	}
+	blank := func() {
+		emit("")
+		pending, hasPending = "", false
+		current.WriteString("\n")
+	}
+
+	for _, line := range lines {
+		if fence != "" {
+			if strings.HasPrefix(strings.TrimSpace(line), fence) {
+				fence = ""
+				continue
+			}
+			emit(line)
+			continue
+		}
+
+		if m := mdFence.FindStringSubmatch(line); m != nil {
+			fence = m[1]
+			continue
+		}
+
+		if hasPending && pending != "" {
+			if mdSetextH1.MatchString(line) {
+				title := pending
+				pending, hasPending = "", false
+				startSection(1, title)
+				continue
+			}
+			if mdSetextH2.MatchString(line) {
+				title := pending
+				pending, hasPending = "", false
+				startSection(2, title)
+				continue
+			}
+		}
+
+		if m := mdATXHeading.FindStringSubmatch(line); m != nil {
+			emit("")
+			pending, hasPending = "", false
+			startSection(len(m[1]), stripInlineMarkdown(m[2]))
+			continue
+		}
+
+		if strings.TrimSpace(line) == "" {
+			blank()
+			continue
+		}
+
+		if mdRule.MatchString(line) || mdTableDivider.MatchString(line) && strings.Contains(line, "-") || mdLinkDef.MatchString(line) {
+			continue
+		}
+
+		line = mdBlockquote.ReplaceAllString(line, "")
+		line = mdListItem.ReplaceAllString(line, "$1")
+		if strings.Contains(line, "|") && strings.HasPrefix(strings.TrimSpace(line), "|") {
+			cells := strings.Split(strings.Trim(strings.TrimSpace(line), "|"), "|")
+			for i := range cells {
+				cells[i] = strings.TrimSpace(cells[i])
+			}
+			line = strings.Join(cells, " | ")
+		}
+
+		emit(stripInlineMarkdown(strings.TrimRight(line, " \t")))
+	}
+
+	if hasPending {
+		current.WriteString(pending)
+		current.WriteString("\n")
+	}
+	flush()
+
+	// The last section does not need a trailing separator
+	if n := len(sections); n > 0 {
+		sections[n-1].text = strings.TrimRight(sections[n-1].text, "\n")
+	}
+
+	return sections
+}
+
+// stripInlineMarkdown removes emphasis, links, images, code spans and
+// inline HTML, keeping the visible text
+func stripInlineMarkdown(s string) string {
+	s = mdImage.ReplaceAllString(s, "$1")
+	s = mdLink.ReplaceAllString(s, "$1")
+	s = mdAutolink.ReplaceAllString(s, "$1")
+	s = mdCode.ReplaceAllString(s, "$1")
+	s = mdStrong.ReplaceAllString(s, "$2")
+	s = mdEmphasis.ReplaceAllString(s, "$1$2")
+	s = mdStrike.ReplaceAllString(s, "$1")
+	s = mdHTMLTag.ReplaceAllString(s, "")
+	return html.UnescapeString(s)
+}
+
+// JSON
+
+// flattenJSON renders every leaf of a JSON document as "path: value" on its
+// own line, with object keys in sorted order
+func flattenJSON(text string) (string, error) {
+	dec := json.NewDecoder(strings.NewReader(text))
+	dec.UseNumber()
+
+	var lines []string
+	for {
+		var value interface{}
+		if err := dec.Decode(&value); err == io.EOF {
+			break
+		} else if err != nil {
+			return "", ErrDocumentInvalidFormat.WithOperation("extract_text").WithCause(err).WithDetails(map[string]string{
+				"format": FormatJSON,
+			})
+		}
+		lines = flattenJSONValue(lines, "", value)
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+func flattenJSONValue(lines []string, path string, value interface{}) []string {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			child := key
+			if path != "" {
+				child = path + "." + key
+			}
+			lines = flattenJSONValue(lines, child, v[key])
+		}
+	case []interface{}:
+		for i, item := range v {
+			lines = flattenJSONValue(lines, fmt.Sprintf("%s[%d]", path, i), item)
+		}
+	default:
+		var text string
+		switch leaf := v.(type) {
+		case nil:
+			text = "null"
+		case string:
+			text = leaf
+		default:
+			text = fmt.Sprint(leaf)
+		}
+		if path == "" {
+			lines = append(lines, text)
+		} else {
+			lines = append(lines, path+": "+text)
+		}
+	}
+	return lines
+}
+
+// HTML
+
+// htmlSkipped are elements whose content is never visible text
+var htmlSkipped = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true, "svg": true, "iframe": true,
+}
+
+// htmlBlocks are elements that start a new line
+var htmlBlocks = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "br": true, "dd": true,
+	"div": true, "dl": true, "dt": true, "fieldset": true, "figcaption": true, "figure": true,
+	"footer": true, "form": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true,
+	"h6": true, "header": true, "hr": true, "li": true, "main": true, "nav": true, "ol": true,
+	"p": true, "pre": true, "section": true, "table": true, "title": true, "tr": true, "ul": true,
+}
+
+// extractHTML returns the visible text of an HTML document. Block elements
+// become line breaks, table cells are separated by " | " and whitespace is
+// collapsed outside <pre>.
+func extractHTML(src string) string {
+	var out strings.Builder
+	pre := 0
+	lower := strings.ToLower(src)
+
+	for i := 0; i < len(src); {
+		if src[i] != '<' {
+			end := strings.IndexByte(src[i:], '<')
+			if end < 0 {
+				end = len(src) - i
+			}
+			text := html.UnescapeString(src[i : i+end])
+			if pre == 0 {
+				text = strings.Join(strings.Fields(text), " ")
+				if text != "" && strings.ContainsAny(src[i:i+1], " \t\n") {
+					text = " " + text
+				}
+				if end > 0 && strings.ContainsAny(src[i+end-1:i+end], " \t\n") && text != "" {
+					text += " "
+				}
+			}
+			out.WriteString(text)
+			i += end
+			continue
+		}
+
+		if strings.HasPrefix(src[i:], "<!--") {
+			end := strings.Index(src[i+4:], "-->")
+			if end < 0 {
+				break
+			}
+			i += 4 + end + 3
+			continue
+		}
+
+		end := strings.IndexByte(src[i:], '>')
+		if end < 0 {
+			break
+		}
+		tag := src[i+1 : i+end]
+		i += end + 1
+
+		closing := strings.HasPrefix(tag, "/")
+		name := strings.ToLower(strings.TrimLeft(tag, "/"))
+		if j := strings.IndexAny(name, " \t\n/"); j >= 0 {
+			name = name[:j]
+		}
+
+		switch {
+		case name == "" || strings.HasPrefix(name, "!") || strings.HasPrefix(name, "?"):
+		case htmlSkipped[name] && !closing && !strings.HasSuffix(tag, "/"):
+			closeTag := strings.Index(lower[i:], "</"+name)
+			if closeTag < 0 {
+				i = len(src)
+				break
+			}
+			i += closeTag
+			if gt := strings.IndexByte(src[i:], '>'); gt >= 0 {
+				i += gt + 1
+			} else {
+				i = len(src)
+			}
+		case name == "td" || name == "th":
+			if !closing {
+				out.WriteString(" | ")
+			}
+		case htmlBlocks[name]:
+			if name == "pre" {
+				if closing && pre > 0 {
+					pre--
+				} else if !closing {
+					pre++
+				}
+			}
+			out.WriteString("\n")
+			if len(name) == 2 && name[0] == 'h' && name[1] >= '1' && name[1] <= '6' || name == "p" {
+				out.WriteString("\n")
+			}
+		}
+	}
+
+	// Trim every line and collapse runs of blank lines
+	lines := strings.Split(out.String(), "\n")
+	var result bytes.Buffer
+	blankRun := 0
+	for _, line := range lines {
+		line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "|"))
+		if line == "" {
+			blankRun++
+			continue
+		}
+		if result.Len() > 0 {
+			if blankRun > 1 {
+				result.WriteString("\n\n")
+			} else {
+				result.WriteString("\n")
+			}
+		}
+		blankRun = 0
+		result.WriteString(line)
+	}
+
+	return result.String()
+}
+
+// Ensure BasicDocumentProcessor implements DocumentProcessor
+var _ DocumentProcessor = (*BasicDocumentProcessor)(nil)
@@ -0,0 +1,203 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/PerceptivePenguin/MCPRAG-Go/internal/chat"
+)
+
+// LLMQueryProcessorConfig configures LLMQueryProcessor
+type LLMQueryProcessorConfig struct {
+	// Paraphrases is the number of alternative phrasings ExpandQuery asks
+	// for; zero disables expansion
+	Paraphrases int `json:"paraphrases"`
+
+	// HypotheticalDocuments is the number of hypothetical answers (HyDE)
+	// generated per query; zero disables them
+	HypotheticalDocuments int `json:"hypothetical_documents"`
+
+	// MaxKeywords caps ExtractKeywords; zero disables keyword extraction
+	MaxKeywords int `json:"max_keywords"`
+
+	// HistoryTurns is the number of most recent conversation turns used to
+	// rewrite follow-up questions
+	HistoryTurns int `json:"history_turns"`
+}
+
+// DefaultLLMQueryProcessorConfig returns default query processing settings
+func DefaultLLMQueryProcessorConfig() *LLMQueryProcessorConfig {
+	return &LLMQueryProcessorConfig{
+		Paraphrases:           3,
+		HypotheticalDocuments: 1,
+		MaxKeywords:           8,
+		HistoryTurns:          6,
+	}
+}
+
+// LLMQueryProcessor uses a chat model to turn follow-up questions into
+// standalone queries, phrase queries differently, write hypothetical answer
+// passages to search with (HyDE) and pick keywords for keyword search. Like
+// LLMReranker it needs a chat client of its own.
+type LLMQueryProcessor struct {
+	chat   *chatCompleter
+	config LLMQueryProcessorConfig
+}
+
+// NewLLMQueryProcessor creates a query processor backed by client
+func NewLLMQueryProcessor(client chat.ChatClient, config *LLMQueryProcessorConfig) (*LLMQueryProcessor, error) {
+	if client == nil {
+		return nil, NewRAGErrorWithOp("new_query_processor", "chat client is required", ErrorTypeValidation)
+	}
+	if config == nil {
+		config = DefaultLLMQueryProcessorConfig()
+	}
+	if config.Paraphrases < 0 || config.HypotheticalDocuments < 0 || config.MaxKeywords < 0 || config.HistoryTurns < 0 {
+		return nil, NewRAGErrorWithOp("new_query_processor", "query processor counts must not be negative", ErrorTypeValidation)
+	}
+
+	return &LLMQueryProcessor{
+		chat:   &chatCompleter{client: client},
+		config: *config,
+	}, nil
+}
+
+// ProcessQuery rewrites a follow-up question into a standalone query using
+// query.History; queries without history are returned unchanged
+func (p *LLMQueryProcessor) ProcessQuery(ctx context.Context, query Query) (Query, error) {
+	if len(query.History) == 0 || p.config.HistoryTurns == 0 {
+		return query, nil
+	}
+
+	history := query.History
+	if len(history) > p.config.HistoryTurns {
+		history = history[len(history)-p.config.HistoryTurns:]
+	}
+
+	var b strings.Builder
+	b.WriteString("Rewrite the last question of this conversation as a standalone search query. ")
+	b.WriteString("Resolve pronouns and references using the conversation, keep names, identifiers and error messages verbatim, and reply with the query only.\n\n")
+	b.WriteString("Conversation:\n")
+	for _, turn := range history {
+		fmt.Fprintf(&b, "- %s\n", strings.TrimSpace(turn))
+	}
+	fmt.Fprintf(&b, "\nLast question: %s\n", query.Text)
+
+	rewritten, err := p.chat.complete(ctx, b.String())
+	if err != nil {
+		return query, NewRAGErrorWithCause("failed to rewrite query", ErrorTypeExternal, err).WithOperation("process_query")
+	}
+
+	if rewritten = cleanModelLine(rewritten); rewritten != "" {
+		query.Text = rewritten
+	}
+	return query, nil
+}
+
+// ExpandQuery returns up to Paraphrases different phrasings of query
+func (p *LLMQueryProcessor) ExpandQuery(ctx context.Context, query string) ([]string, error) {
+	if p.config.Paraphrases == 0 {
+		return nil, nil
+	}
+
+	prompt := fmt.Sprintf("Write %d different phrasings of the following search query that a relevant document might match. "+
+		"Keep names and identifiers verbatim. Reply with one phrasing per line and nothing else.\n\nQuery: %s\n",
+		p.config.Paraphrases, query)
+
+	reply, err := p.chat.complete(ctx, prompt)
+	if err != nil {
+		return nil, NewRAGErrorWithCause("failed to expand query", ErrorTypeExternal, err).WithOperation("expand_query")
+	}
+
+	return modelLines(reply, p.config.Paraphrases, query), nil
+}
+
+// RewriteQuery rewrites query into a concise search query
+func (p *LLMQueryProcessor) RewriteQuery(ctx context.Context, query string) (string, error) {
+	prompt := "Rewrite the following question as a concise search query for a document search engine. " +
+		"Keep names, identifiers and error messages verbatim and reply with the query only.\n\nQuestion: " + query + "\n"
+
+	rewritten, err := p.chat.complete(ctx, prompt)
+	if err != nil {
+		return query, NewRAGErrorWithCause("failed to rewrite query", ErrorTypeExternal, err).WithOperation("rewrite_query")
+	}
+
+	if rewritten = cleanModelLine(rewritten); rewritten == "" {
+		return query, nil
+	}
+	return rewritten, nil
+}
+
+// ExtractKeywords returns up to MaxKeywords keywords or key phrases of query
+func (p *LLMQueryProcessor) ExtractKeywords(ctx context.Context, query string) ([]string, error) {
+	if p.config.MaxKeywords == 0 {
+		return nil, nil
+	}
+
+	prompt := fmt.Sprintf("List up to %d keywords or short key phrases from the following query that a keyword search should match, "+
+		"most important first. Keep identifiers verbatim. Reply with one per line and nothing else.\n\nQuery: %s\n",
+		p.config.MaxKeywords, query)
+
+	reply, err := p.chat.complete(ctx, prompt)
+	if err != nil {
+		return nil, NewRAGErrorWithCause("failed to extract keywords", ErrorTypeExternal, err).WithOperation("extract_keywords")
+	}
+
+	return modelLines(reply, p.config.MaxKeywords, ""), nil
+}
+
+// GenerateHypotheticalDocuments writes HypotheticalDocuments short passages
+// answering query, to be searched with in place of the query (HyDE)
+func (p *LLMQueryProcessor) GenerateHypotheticalDocuments(ctx context.Context, query string) ([]string, error) {
+	prompt := "Write a short passage of three to five sentences that answers the following question, " +
+		"as it might appear in technical documentation. Reply with the passage only.\n\nQuestion: " + query + "\n"
+
+	var docs []string
+	for i := 0; i < p.config.HypotheticalDocuments; i++ {
+		reply, err := p.chat.complete(ctx, prompt)
+		if err != nil {
+			return docs, NewRAGErrorWithCause("failed to generate hypothetical document", ErrorTypeExternal, err).WithOperation("hyde")
+		}
+		if reply != "" {
+			docs = append(docs, reply)
+		}
+	}
+
+	return docs, nil
+}
+
+// listMarker matches bullets and numbering models put in front of lines
+var listMarker = regexp.MustCompile(`^\s*(?:[-*•]|\d+[.)]|\[\d+\])\s*`)
+
+// cleanModelLine strips list markers and quotes from a one-line reply
+func cleanModelLine(line string) string {
+	line = listMarker.ReplaceAllString(strings.TrimSpace(line), "")
+	return strings.TrimSpace(strings.Trim(line, "\"'`"))
+}
+
+// modelLines returns up to n distinct non-empty lines of a reply, skipping
+// any equal to exclude
+func modelLines(reply string, n int, exclude string) []string {
+	seen := map[string]bool{strings.ToLower(strings.TrimSpace(exclude)): true}
+
+	var lines []string
+	for _, line := range strings.Split(reply, "\n") {
+		line = cleanModelLine(line)
+		key := strings.ToLower(line)
+		if line == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		lines = append(lines, line)
+		if len(lines) == n {
+			break
+		}
+	}
+
+	return lines
+}
+
+// Ensure LLMQueryProcessor implements QueryProcessor
+var _ QueryProcessor = (*LLMQueryProcessor)(nil)
@@ -0,0 +1,407 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/PerceptivePenguin/MCPRAG-Go/internal/chat"
+	"github.com/PerceptivePenguin/MCPRAG-Go/internal/vector"
+)
+
+// RerankOptions configures the reranking stage of a retriever
+type RerankOptions struct {
+	// PoolSize is the number of candidates retrieved for reranking; zero
+	// means four times the final K
+	PoolSize int `json:"pool_size"`
+
+	// FinalK is the number of results kept after reranking; zero means the
+	// query's TopK
+	FinalK int `json:"final_k"`
+}
+
+// finalK returns the number of results to keep for query
+func (o RerankOptions) finalK(query Query) int {
+	if o.FinalK > 0 {
+		return o.FinalK
+	}
+	return query.TopK
+}
+
+// poolSize returns the number of candidates to retrieve for query
+func (o RerankOptions) poolSize(query Query) int {
+	k := o.finalK(query)
+	if o.PoolSize <= 0 {
+		return 4 * k
+	}
+	return max(o.PoolSize, k)
+}
+
+// LLMRerankConfig configures LLMReranker
+type LLMRerankConfig struct {
+	// BatchSize is the number of passages scored per request
+	BatchSize int `json:"batch_size"`
+
+	// MaxPassageLength truncates passages in the prompt, in characters
+	MaxPassageLength int `json:"max_passage_length"`
+}
+
+// DefaultLLMRerankConfig returns default LLM reranking settings
+func DefaultLLMRerankConfig() *LLMRerankConfig {
+	return &LLMRerankConfig{
+		BatchSize:        10,
+		MaxPassageLength: 1500,
+	}
+}
+
+// LLMReranker asks a chat model to grade the relevance of each passage to
+// the query from 0 to 10, a batch of passages per request. The client's
+// message history is cleared around every request, so the reranker needs a
+// client of its own.
+type LLMReranker struct {
+	chat   *chatCompleter
+	config LLMRerankConfig
+}
+
+// NewLLMReranker creates a reranker backed by client
+func NewLLMReranker(client chat.ChatClient, config *LLMRerankConfig) (*LLMReranker, error) {
+	if client == nil {
+		return nil, NewRAGErrorWithOp("new_llm_reranker", "chat client is required", ErrorTypeValidation)
+	}
+	if config == nil {
+		config = DefaultLLMRerankConfig()
+	}
+	if config.BatchSize <= 0 {
+		return nil, ValidationError("batch_size", "batch size must be positive")
+	}
+	if config.MaxPassageLength <= 0 {
+		return nil, ValidationError("max_passage_length", "max passage length must be positive")
+	}
+
+	return &LLMReranker{
+		chat:   &chatCompleter{client: client},
+		config: *config,
+	}, nil
+}
+
+// Rerank scores docs in batches and orders them by the model's grades,
+// scaled to [0, 1]. Passages the model leaves out of its reply score 0.
+func (r *LLMReranker) Rerank(ctx context.Context, query Query, docs []Document) ([]Document, []float32, error) {
+	scores := make([]float32, len(docs))
+
+	for start := 0; start < len(docs); start += r.config.BatchSize {
+		end := min(start+r.config.BatchSize, len(docs))
+
+		reply, err := r.chat.complete(ctx, r.buildPrompt(query.Text, docs[start:end]))
+		if err != nil {
+			return nil, nil, NewRAGErrorWithCause("reranking request failed", ErrorTypeExternal, err).WithOperation("llm_rerank")
+		}
+
+		grades := parseRelevanceGrades(reply, end-start)
+		if len(grades) == 0 {
+			return nil, nil, NewRAGErrorWithOp("llm_rerank", "could not parse relevance grades from model reply", ErrorTypeExternal)
+		}
+		for i, grade := range grades {
+			scores[start+i] = grade / 10
+		}
+	}
+
+	ranked, rankedScores := sortByScore(docs, scores)
+	return ranked, rankedScores, nil
+}
+
+// GetName returns the reranking strategy name
+func (r *LLMReranker) GetName() string {
+	return "llm"
+}
+
+func (r *LLMReranker) buildPrompt(queryText string, docs []Document) string {
+	var b strings.Builder
+
+	b.WriteString("Rate how relevant each passage is to the query on a scale from 0 (unrelated) to 10 (answers it directly).\n")
+	b.WriteString("Reply with one line per passage in the form \"<passage number>: <score>\" and nothing else.\n\n")
+	fmt.Fprintf(&b, "Query: %s\n\nPassages:\n", queryText)
+
+	for i, doc := range docs {
+		content := strings.TrimSpace(doc.Content)
+		if len(content) > r.config.MaxPassageLength {
+			cut := r.config.MaxPassageLength
+			for cut > 0 && !utf8.RuneStart(content[cut]) {
+				cut--
+			}
+			content = content[:cut] + "..."
+		}
+		fmt.Fprintf(&b, "\n[%d] %s\n", i+1, content)
+	}
+
+	return b.String()
+}
+
+// relevanceGradeLine matches reply lines such as "3: 7", "[3] 7.5" or "3 - 7"
+var relevanceGradeLine = regexp.MustCompile(`(?m)^\s*\[?(\d+)\]?\s*[:=\-)]?\s*(\d+(?:\.\d+)?)\s*(?:/\s*10)?\s*$`)
+
+// parseRelevanceGrades extracts the grade of each of n passages from a model
+// reply, indexed from zero and clamped to [0, 10]
+func parseRelevanceGrades(reply string, n int) map[int]float32 {
+	grades := make(map[int]float32)
+
+	for _, match := range relevanceGradeLine.FindAllStringSubmatch(reply, -1) {
+		number, err := strconv.Atoi(match[1])
+		if err != nil || number < 1 || number > n {
+			continue
+		}
+		grade, err := strconv.ParseFloat(match[2], 32)
+		if err != nil {
+			continue
+		}
+		grades[number-1] = float32(math.Min(math.Max(grade, 0), 10))
+	}
+
+	return grades
+}
+
+// LocalRerankConfig configures LocalReranker
+type LocalRerankConfig struct {
+	// LexicalWeight is the share of the lexical score in the final score,
+	// the rest being embedding similarity; it is ignored without an embedder
+	LexicalWeight float32 `json:"lexical_weight"`
+
+	// SplitIdentifiers also matches the camelCase and snake_case parts of
+	// identifiers
+	SplitIdentifiers bool `json:"split_identifiers"`
+}
+
+// DefaultLocalRerankConfig returns default local reranking settings
+func DefaultLocalRerankConfig() *LocalRerankConfig {
+	return &LocalRerankConfig{
+		LexicalWeight:    0.5,
+		SplitIdentifiers: true,
+	}
+}
+
+// LocalReranker reranks without network access beyond the optional
+// embedder. The lexical score combines how much of the query's vocabulary a
+// passage covers, weighted by rarity within the candidate pool, with how many
+// of the query's word pairs it contains in order. With an embedder the
+// cosine similarity of query and passage is blended in; stored chunk vectors
+// are reused when present.
+type LocalReranker struct {
+	embedder Embedder
+	config   LocalRerankConfig
+}
+
+// NewLocalReranker creates a local reranker; embedder may be nil for a
+// purely lexical reranker
+func NewLocalReranker(embedder Embedder, config *LocalRerankConfig) (*LocalReranker, error) {
+	if config == nil {
+		config = DefaultLocalRerankConfig()
+	}
+	if config.LexicalWeight < 0 || config.LexicalWeight > 1 {
+		return nil, ValidationError("lexical_weight", "lexical weight must be between 0 and 1")
+	}
+
+	return &LocalReranker{
+		embedder: embedder,
+		config:   *config,
+	}, nil
+}
+
+// Rerank scores docs against the query and orders them by score
+func (r *LocalReranker) Rerank(ctx context.Context, query Query, docs []Document) ([]Document, []float32, error) {
+	scores := r.lexicalScores(query.Text, docs)
+
+	if r.embedder != nil && r.config.LexicalWeight < 1 && len(docs) > 0 {
+		similarities, err := r.similarities(ctx, query.Text, docs)
+		if err != nil {
+			return nil, nil, err
+		}
+		w := r.config.LexicalWeight
+		for i := range scores {
+			scores[i] = w*scores[i] + (1-w)*similarities[i]
+		}
+	}
+
+	ranked, rankedScores := sortByScore(docs, scores)
+	return ranked, rankedScores, nil
+}
+
+// GetName returns the reranking strategy name
+func (r *LocalReranker) GetName() string {
+	return "local"
+}
+
+func (r *LocalReranker) lexicalScores(queryText string, docs []Document) []float32 {
+	scores := make([]float32, len(docs))
+
+	queryTerms := distinctTerms(r.terms(queryText))
+	queryPairs := termPairs(r.words(queryText))
+	if len(queryTerms) == 0 {
+		return scores
+	}
+
+	docTerms := make([]map[string]bool, len(docs))
+	docPairs := make([]map[string]bool, len(docs))
+	df := make(map[string]int)
+	for i, doc := range docs {
+		docTerms[i] = make(map[string]bool)
+		for _, term := range r.terms(doc.Content) {
+			docTerms[i][term] = true
+		}
+		for _, term := range queryTerms {
+			if docTerms[i][term] {
+				df[term]++
+			}
+		}
+		docPairs[i] = make(map[string]bool)
+		for _, pair := range termPairs(r.words(doc.Content)) {
+			docPairs[i][pair] = true
+		}
+	}
+
+	n := float64(len(docs))
+	idf := make(map[string]float64, len(queryTerms))
+	var totalIDF float64
+	for _, term := range queryTerms {
+		d := float64(df[term])
+		idf[term] = math.Log(1 + (n-d+0.5)/(d+0.5))
+		totalIDF += idf[term]
+	}
+
+	for i := range docs {
+		var covered float64
+		for _, term := range queryTerms {
+			if docTerms[i][term] {
+				covered += idf[term]
+			}
+		}
+		coverage := covered / totalIDF
+
+		if len(queryPairs) == 0 {
+			scores[i] = float32(coverage)
+			continue
+		}
+		var pairs int
+		for _, pair := range queryPairs {
+			if docPairs[i][pair] {
+				pairs++
+			}
+		}
+		scores[i] = float32(0.7*coverage + 0.3*float64(pairs)/float64(len(queryPairs)))
+	}
+
+	return scores
+}
+
+// similarities returns the cosine similarity of each doc to the query,
+// clamped to [0, 1]
+func (r *LocalReranker) similarities(ctx context.Context, queryText string, docs []Document) ([]float32, error) {
+	queryEmbedding, err := r.embedder.EmbedWithOptions(ctx, EmbeddingRequest{Text: queryText, InputType: InputTypeQuery})
+	if err != nil {
+		return nil, NewRAGErrorWithCause("failed to embed query for reranking", ErrorTypeExternal, err).WithOperation("local_rerank")
+	}
+	dimension := len(queryEmbedding.Vector)
+
+	vectors := make([]vector.Vector, len(docs))
+	var missing []int
+	var texts []string
+	for i, doc := range docs {
+		if len(doc.Vector) == dimension {
+			vectors[i] = doc.Vector
+			continue
+		}
+		missing = append(missing, i)
+		texts = append(texts, doc.Content)
+	}
+
+	if len(texts) > 0 {
+		embeddings, err := r.embedder.EmbedBatch(ctx, texts)
+		if err != nil {
+			return nil, NewRAGErrorWithCause("failed to embed passages for reranking", ErrorTypeExternal, err).WithOperation("local_rerank")
+		}
+		for j, i := range missing {
+			vectors[i] = embeddings[j].Vector
+		}
+	}
+
+	similarities := make([]float32, len(docs))
+	for i, v := range vectors {
+		similarities[i] = min(max(vector.CosineSimilarity(queryEmbedding.Vector, v), 0), 1)
+	}
+
+	return similarities, nil
+}
+
+// terms returns the lowercased keyword terms of text
+func (r *LocalReranker) terms(text string) []string {
+	terms := keywordTokens(text, r.config.SplitIdentifiers)
+	for i, term := range terms {
+		terms[i] = strings.ToLower(term)
+	}
+	return terms
+}
+
+// words returns the lowercased words of text in order, without identifier
+// parts
+func (r *LocalReranker) words(text string) []string {
+	words := keywordTokens(text, false)
+	for i, word := range words {
+		words[i] = strings.ToLower(word)
+	}
+	return words
+}
+
+func distinctTerms(terms []string) []string {
+	seen := make(map[string]bool, len(terms))
+	distinct := terms[:0]
+	for _, term := range terms {
+		if !seen[term] {
+			seen[term] = true
+			distinct = append(distinct, term)
+		}
+	}
+	return distinct
+}
+
+// termPairs returns the adjacent word pairs of words
+func termPairs(words []string) []string {
+	if len(words) < 2 {
+		return nil
+	}
+	pairs := make([]string, 0, len(words)-1)
+	for i := 1; i < len(words); i++ {
+		pairs = append(pairs, words[i-1]+" "+words[i])
+	}
+	return pairs
+}
+
+// sortByScore orders docs by descending score, keeping the incoming order
+// among equal scores
+func sortByScore(docs []Document, scores []float32) ([]Document, []float32) {
+	order := make([]int, len(docs))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return scores[order[i]] > scores[order[j]]
+	})
+
+	rankedDocs := make([]Document, len(docs))
+	rankedScores := make([]float32, len(docs))
+	for i, idx := range order {
+		rankedDocs[i] = docs[idx]
+		rankedScores[i] = scores[idx]
+	}
+
+	return rankedDocs, rankedScores
+}
+
+// Ensure both rerankers implement RerankStrategy
+var (
+	_ RerankStrategy = (*LLMReranker)(nil)
+	_ RerankStrategy = (*LocalReranker)(nil)
+)
@@ -3,7 +3,9 @@ package rag
 import (
 	"context"
 	"fmt"
+	"io"
 	"sort"
+	"strconv"
 	"sync"
 	"time"
 
@@ -25,32 +27,82 @@ func NewRetriever(config *RetrieverConfig) (Retriever, error) {
 		config = DefaultRetrieverConfig()
 	}
 
-	// Create vector store
-	vectorStore, err := vector.NewMemoryStore(config.VectorStore)
-	if err != nil {
-		return nil, NewRAGErrorWithOp("new_retriever", 
-			fmt.Sprintf("failed to create vector store: %v", err), ErrorTypeInternal)
-	}
-
 	// Create embedder
-	cache, err := NewLRUCache(config.Cache)
+	cache, err := NewCache(config.Cache)
 	if err != nil {
 		return nil, NewRAGErrorWithOp("new_retriever", 
 			fmt.Sprintf("failed to create cache: %v", err), ErrorTypeInternal)
 	}
 	
-	embedder, err := NewOpenAIEmbedder(config.Embedding, cache)
+	embeddingConfig := config.Embedding
+	if embeddingConfig.Provider == EmbeddingProviderHashing && embeddingConfig.Dimensions == 0 && config.VectorStore != nil {
+		// Local vectors fit whatever dimension the store expects
+		sized := *embeddingConfig
+		sized.Dimensions = config.VectorStore.Dimension
+		embeddingConfig = &sized
+	}
+
+	embedder, err := NewEmbedder(embeddingConfig, cache)
 	if err != nil {
+		if cache != nil {
+			cache.Close()
+		}
 		return nil, NewRAGErrorWithOp("new_retriever", 
 			fmt.Sprintf("failed to create embedder: %v", err), ErrorTypeInternal)
 	}
 
-	// Create document processor
-	// TODO: Implement proper document processor factory
-	var processor DocumentProcessor
+	// Create vector store, sized after the vectors an embedding server
+	// returns when its dimension is not configured. A store must not be
+	// created at a guessed dimension: a file store would keep it.
+	storeConfig := config.VectorStore
+	if httpEmbedder, ok := embedder.(*HTTPEmbedder); ok && embeddingConfig.Dimensions == 0 && storeConfig != nil {
+		dimension, err := httpEmbedder.probeDimension()
+		if err != nil {
+			embedder.Close()
+			return nil, NewRAGErrorWithCause("failed to detect the embedding dimension; configure it explicitly",
+				ErrorTypeExternal, err).WithOperation("new_retriever")
+		}
+		if dimension != storeConfig.Dimension {
+			sized := *storeConfig
+			sized.Dimension = dimension
+			storeConfig = &sized
+		}
+	}
+
+	vectorStore, err := vector.NewStore(storeConfig)
+	if err != nil {
+		embedder.Close()
+		return nil, NewRAGErrorWithOp("new_retriever", 
+			fmt.Sprintf("failed to create vector store: %v", err), ErrorTypeInternal)
+	}
+
+	// Create document processor, counting tokens like the embedding model
+	tokenizerConfig := DefaultTokenizerConfig()
+	if config.Tokenizer != nil {
+		tokenizerConfig = config.Tokenizer
+	}
+	if tokenizerConfig.Model == "" {
+		tokenizerConfig = &TokenizerConfig{Model: config.Embedding.Model, Dir: tokenizerConfig.Dir}
+	}
+	tokenizer, err := NewTokenizer(tokenizerConfig)
+	if err != nil {
+		vectorStore.Close()
+		embedder.Close()
+		return nil, NewRAGErrorWithOp("new_retriever",
+			fmt.Sprintf("failed to create tokenizer: %v", err), ErrorTypeInternal)
+	}
+
+	processor := NewDocumentProcessor(config.Processing, tokenizer)
+	processor.SetEmbedder(embedder)
 
 	// Create basic retriever
-	return NewBasicRetriever(vectorStore, embedder, processor, config)
+	retriever, err := NewBasicRetriever(vectorStore, embedder, processor, config)
+	if err != nil {
+		return nil, err
+	}
+	retriever.SetTokenizer(tokenizer)
+
+	return retriever, nil
 }
 
 // BasicRetriever implements the Retriever interface using vector search
@@ -60,8 +112,19 @@ type BasicRetriever struct {
 	processor   DocumentProcessor
 	config      *RetrievalConfig
 	stats       RetrievalStats
+	listeners   []EventListener
+	reranker    RerankStrategy
+	rerank      RerankOptions
+	queries     QueryProcessor
+	fusion      FusionConfig // fusion of query variants
+	tokenizer   Tokenizer    // sizes expanded results, see Query.Expand
 	mu          sync.RWMutex
 	closed      bool
+
+	// Store IDs of the chunks of every document, built from the store the
+	// first time a document is deleted and kept up to date after that
+	chunksMu sync.Mutex
+	chunks   map[string][]string
 }
 
 // NewBasicRetriever creates a new basic retriever
@@ -104,18 +167,82 @@ func (r *BasicRetriever) Retrieve(ctx context.Context, query Query) (*RetrievalR
 	}
 	r.mu.RUnlock()
 
-	start := time.Now()
-	
 	// Validate query
 	if err := r.validateQuery(query); err != nil {
 		return nil, err
 	}
 
+	return r.retrieve(ctx, query, func(ctx context.Context, query Query) (*RetrievalResult, error) {
+		return r.semanticSearch(ctx, query, r.vectorStore)
+	})
+}
+
+// retrieve runs search for the query, or for each of its variants when a
+// query processor is set, reranks the candidates and records the query
+func (r *BasicRetriever) retrieve(ctx context.Context, query Query, search variantSearch) (*RetrievalResult, error) {
+	start := time.Now()
+
+	reranker, options := r.getReranker()
+	diversify := query.MMR || query.MaxPerParent > 0
+
+	finalK := query.TopK
+	candidateQuery := query
+	if reranker != nil {
+		finalK = options.finalK(query)
+		candidateQuery.TopK = options.poolSize(query)
+	}
+	if diversify {
+		candidateQuery.TopK = max(candidateQuery.TopK, diversityPoolFactor*finalK)
+	}
+
+	result, searchQuery, err := r.searchVariants(ctx, candidateQuery, search)
+	if err != nil {
+		return nil, err
+	}
+
+	if reranker != nil {
+		// Keep every candidate for diversification to choose from
+		keep := finalK
+		if diversify {
+			keep = len(result.Documents)
+		}
+
+		// Rerank against the standalone query, not the follow-up
+		rerankQuery := query
+		rerankQuery.Text = searchQuery.Text
+		if err := r.rerankResult(ctx, rerankQuery, result, reranker, keep); err != nil {
+			return nil, err
+		}
+	}
+
+	if diversify {
+		r.diversify(query, result, finalK)
+	}
+
+	r.expand(query, result)
+
+	result.Query = query
+	result.QueryTime = time.Since(start).Milliseconds()
+
+	// Update statistics
+	r.updateStats(result)
+
+	for _, listener := range r.getListeners() {
+		listener.OnQueryExecuted(ctx, query, result)
+	}
+
+	return result, nil
+}
+
+// semanticSearch embeds the query text and searches store with it
+func (r *BasicRetriever) semanticSearch(ctx context.Context, query Query, store vector.Store) (*RetrievalResult, error) {
+	start := time.Now()
+
 	// Record query start
 	embeddingStart := time.Now()
 
 	// Generate query embedding
-	embeddingResp, err := r.embedder.Embed(ctx, query.Text)
+	embeddingResp, err := r.embedder.EmbedWithOptions(ctx, EmbeddingRequest{Text: query.Text, InputType: InputTypeQuery})
 	if err != nil {
 		return nil, NewRAGErrorWithCause("failed to generate query embedding", ErrorTypeExternal, err).WithOperation("retrieve")
 	}
@@ -124,7 +251,7 @@ func (r *BasicRetriever) Retrieve(ctx context.Context, query Query) (*RetrievalR
 	searchStart := time.Now()
 
 	// Perform vector search
-	searchResults, err := r.vectorSearch(ctx, embeddingResp.Vector, query)
+	searchResults, err := r.vectorSearch(ctx, store, embeddingResp.Vector, query)
 	if err != nil {
 		return nil, err
 	}
@@ -136,17 +263,11 @@ func (r *BasicRetriever) Retrieve(ctx context.Context, query Query) (*RetrievalR
 	scores := make([]float32, len(searchResults.Documents))
 	
 	for i, doc := range searchResults.Documents {
-		documents[i] = Document{
-			ID:        doc.ID,
-			Content:   doc.Content,
-			Vector:    doc.Vector,
-			CreatedAt: time.Now(), // This should come from stored metadata
-			UpdatedAt: time.Now(),
-		}
+		documents[i] = documentFromVector(doc)
 		scores[i] = doc.Score // Use the score from the document
 	}
 
-	result := &RetrievalResult{
+	return &RetrievalResult{
 		Query:         query,
 		Documents:     documents,
 		Scores:        scores,
@@ -154,12 +275,7 @@ func (r *BasicRetriever) Retrieve(ctx context.Context, query Query) (*RetrievalR
 		QueryTime:     time.Since(start).Milliseconds(),
 		EmbeddingTime: embeddingTime,
 		SearchTime:    searchTime,
-	}
-
-	// Update statistics
-	r.updateStats(result)
-
-	return result, nil
+	}, nil
 }
 
 // AddDocument adds a single document to the retrieval system
@@ -212,20 +328,28 @@ func (r *BasicRetriever) AddDocument(ctx context.Context, doc Document) error {
 	}
 
 	// Store chunks with embeddings in vector store
+	stored := make([]string, 0, len(chunks))
+	defer func() { r.recordChunks(doc.ID, stored) }()
 	for i, chunk := range chunks {
 		if embeddings[i] == nil {
 			continue // Skip empty chunks
 		}
 
 		vectorDoc := vector.Document{
-			ID:      chunk.ID,
-			Vector:  embeddings[i].Vector,
-			Content: chunk.Content,
+			ID:       chunk.ID,
+			Vector:   embeddings[i].Vector,
+			Content:  chunk.Content,
+			Metadata: chunkMetadata(doc, chunk),
 		}
 
 		if err := r.vectorStore.Add(vectorDoc); err != nil {
 			return NewRAGErrorWithCause("failed to store document chunk", ErrorTypeInternal, err).WithOperation("add_document")
 		}
+		stored = append(stored, vectorDoc.ID)
+
+		for _, listener := range r.getListeners() {
+			listener.OnDocumentAdded(ctx, documentFromVector(vectorDoc))
+		}
 	}
 
 	// Update statistics
@@ -289,15 +413,141 @@ func (r *BasicRetriever) DeleteDocument(ctx context.Context, id string) error {
 		return NewRAGErrorWithOp("delete_document", "document ID is required", ErrorTypeValidation)
 	}
 
-	// Use the vector store's Delete method directly
-	// This assumes document ID is the same as vector store ID
-	if err := r.vectorStore.Delete(id); err != nil {
-		return NewRAGErrorWithCause("failed to delete document", ErrorTypeInternal, err).WithOperation("delete_document")
+	ids := r.chunkIDs(id)
+	if len(ids) == 0 {
+		return ErrDocumentNotFound.WithOperation("delete_document").WithDetails(map[string]string{"id": id})
+	}
+
+	for i, chunkID := range ids {
+		if err := r.vectorStore.Delete(chunkID); err != nil {
+			r.recordDeleted(id, ids[:i])
+			return NewRAGErrorWithCause("failed to delete document", ErrorTypeInternal, err).WithOperation("delete_document")
+		}
+
+		for _, listener := range r.getListeners() {
+			listener.OnDocumentDeleted(ctx, chunkID)
+		}
 	}
+	r.recordDeleted(id, ids)
+
+	r.mu.Lock()
+	if r.stats.TotalDocuments > 0 {
+		r.stats.TotalDocuments--
+	}
+	r.stats.TotalChunks = max(r.stats.TotalChunks-len(ids), 0)
+	r.stats.LastUpdated = time.Now()
+	r.mu.Unlock()
 
 	return nil
 }
 
+// chunkIDs returns the store IDs of every chunk of a document. Chunks are
+// stored as "<id>_chunk_<n>" and carry the document ID in their metadata;
+// a document stored under its own ID is matched as well. The IDs come from
+// the chunk index, which is built with one pass over the store.
+func (r *BasicRetriever) chunkIDs(id string) []string {
+	r.chunksMu.Lock()
+	defer r.chunksMu.Unlock()
+
+	if r.chunks == nil {
+		r.chunks = r.indexChunks()
+	}
+	if r.chunks != nil {
+		return append([]string(nil), r.chunks[id]...)
+	}
+
+	// Without a listing, probe consecutive chunk IDs
+	var ids []string
+	if _, err := r.vectorStore.Get(id); err == nil {
+		ids = append(ids, id)
+	}
+	for i := 0; ; i++ {
+		chunkID := fmt.Sprintf("%s_chunk_%d", id, i)
+		if _, err := r.vectorStore.Get(chunkID); err != nil {
+			break
+		}
+		ids = append(ids, chunkID)
+	}
+	return ids
+}
+
+// indexChunks maps every document in the store to the store IDs of its
+// chunks, or returns nil when the store cannot list its IDs
+func (r *BasicRetriever) indexChunks() map[string][]string {
+	lister, ok := r.vectorStore.(interface{ ListIDs() []string })
+	if !ok {
+		return nil
+	}
+
+	chunks := make(map[string][]string)
+	for _, storeID := range lister.ListIDs() {
+		vd, err := r.vectorStore.Get(storeID)
+		if err != nil {
+			continue
+		}
+		parent := vd.Metadata[MetadataDocumentID]
+		if parent == "" {
+			parent = storeID
+		}
+		chunks[parent] = append(chunks[parent], storeID)
+	}
+
+	return chunks
+}
+
+// recordChunks adds the chunks stored for a document to the chunk index
+func (r *BasicRetriever) recordChunks(id string, chunkIDs []string) {
+	r.chunksMu.Lock()
+	defer r.chunksMu.Unlock()
+
+	if r.chunks == nil || len(chunkIDs) == 0 {
+		return
+	}
+
+	known := make(map[string]bool, len(r.chunks[id]))
+	for _, chunkID := range r.chunks[id] {
+		known[chunkID] = true
+	}
+	for _, chunkID := range chunkIDs {
+		if !known[chunkID] {
+			r.chunks[id] = append(r.chunks[id], chunkID)
+		}
+	}
+}
+
+// recordDeleted removes the deleted chunks of a document from the chunk index
+func (r *BasicRetriever) recordDeleted(id string, chunkIDs []string) {
+	r.chunksMu.Lock()
+	defer r.chunksMu.Unlock()
+
+	if r.chunks == nil {
+		return
+	}
+
+	deleted := make(map[string]bool, len(chunkIDs))
+	for _, chunkID := range chunkIDs {
+		deleted[chunkID] = true
+	}
+	remaining := r.chunks[id][:0]
+	for _, chunkID := range r.chunks[id] {
+		if !deleted[chunkID] {
+			remaining = append(remaining, chunkID)
+		}
+	}
+	if len(remaining) == 0 {
+		delete(r.chunks, id)
+	} else {
+		r.chunks[id] = remaining
+	}
+}
+
+// resetChunks drops the chunk index after the store was replaced
+func (r *BasicRetriever) resetChunks() {
+	r.chunksMu.Lock()
+	defer r.chunksMu.Unlock()
+	r.chunks = nil
+}
+
 // GetDocument retrieves a document by ID
 func (r *BasicRetriever) GetDocument(ctx context.Context, id string) (*Document, error) {
 	r.mu.RLock()
@@ -318,15 +568,9 @@ func (r *BasicRetriever) GetDocument(ctx context.Context, id string) (*Document,
 	}
 
 	// Convert vector document to RAG document
-	ragDoc := &Document{
-		ID:        vectorDoc.ID,
-		Content:   vectorDoc.Content,
-		Vector:    vectorDoc.Vector,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-	}
+	ragDoc := documentFromVector(*vectorDoc)
 
-	return ragDoc, nil
+	return &ragDoc, nil
 }
 
 // GetStats returns retrieval system statistics
@@ -343,6 +587,341 @@ func (r *BasicRetriever) GetStats() RetrievalStats {
 	return r.stats
 }
 
+// Snapshot writes the contents of the retriever's vector store to w
+func (r *BasicRetriever) Snapshot(w io.Writer) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.closed {
+		return NewRAGErrorWithOp("snapshot", "retriever is closed", ErrorTypeInternal)
+	}
+
+	if err := vector.SaveSnapshot(r.vectorStore, w); err != nil {
+		return NewRAGErrorWithCause("failed to write snapshot", ErrorTypeInternal, err).WithOperation("snapshot")
+	}
+
+	return nil
+}
+
+// Restore replaces the contents of the retriever's vector store with a
+// snapshot and recounts documents and chunks from the restored data
+func (r *BasicRetriever) Restore(rd io.Reader) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return NewRAGErrorWithOp("restore", "retriever is closed", ErrorTypeInternal)
+	}
+
+	defer r.resetChunks()
+	if err := vector.LoadSnapshot(r.vectorStore, rd); err != nil {
+		return NewRAGErrorWithCause("failed to restore snapshot", ErrorTypeInternal, err).WithOperation("restore")
+	}
+
+	r.stats.TotalChunks = r.vectorStore.Size()
+	r.stats.TotalDocuments = r.stats.TotalChunks
+	if lister, ok := r.vectorStore.(interface{ ListIDs() []string }); ok {
+		documents := make(map[string]struct{})
+		for _, id := range lister.ListIDs() {
+			if vd, err := r.vectorStore.Get(id); err == nil {
+				documents[documentFromVector(*vd).ParentID] = struct{}{}
+			}
+		}
+		r.stats.TotalDocuments = len(documents)
+	}
+	r.stats.LastUpdated = time.Now()
+
+	for _, listener := range r.listeners {
+		if syncer, ok := listener.(storeSyncer); ok {
+			if err := syncer.SyncStore(r.vectorStore); err != nil {
+				return NewRAGErrorWithCause("failed to sync listener", ErrorTypeInternal, err).WithOperation("restore")
+			}
+		}
+	}
+
+	return nil
+}
+
+// storeSyncer is implemented by listeners that mirror the contents of the
+// vector store and must be rebuilt when it is replaced wholesale
+type storeSyncer interface {
+	SyncStore(store vector.Store) error
+}
+
+// AddListener registers a listener for retriever events. Listeners see the
+// stored chunks: OnDocumentAdded and OnDocumentDeleted are called once per
+// chunk with the chunk ID. Listeners that mirror the store, such as
+// BM25Strategy, are first synced with the chunks already stored.
+func (r *BasicRetriever) AddListener(listener EventListener) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return NewRAGErrorWithOp("add_listener", "retriever is closed", ErrorTypeInternal)
+	}
+
+	if syncer, ok := listener.(storeSyncer); ok {
+		if err := syncer.SyncStore(r.vectorStore); err != nil {
+			return NewRAGErrorWithCause("failed to sync listener", ErrorTypeInternal, err).WithOperation("add_listener")
+		}
+	}
+
+	r.listeners = append(r.listeners, listener)
+	return nil
+}
+
+// SetReranker adds a reranking stage to Retrieve: options.PoolSize
+// candidates are retrieved, reordered by reranker and cut to options.FinalK.
+// A nil reranker removes the stage.
+func (r *BasicRetriever) SetReranker(reranker RerankStrategy, options *RerankOptions) error {
+	if options == nil {
+		options = &RerankOptions{}
+	}
+	if options.PoolSize < 0 {
+		return ValidationError("pool_size", "pool size must not be negative")
+	}
+	if options.FinalK < 0 {
+		return ValidationError("final_k", "final K must not be negative")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.reranker = reranker
+	r.rerank = *options
+	return nil
+}
+
+func (r *BasicRetriever) getReranker() (RerankStrategy, RerankOptions) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.reranker, r.rerank
+}
+
+// SetTokenizer sets the tokenizer expanded results are sized with, see
+// Query.Expand. Without one tokens are estimated from the text length.
+func (r *BasicRetriever) SetTokenizer(tokenizer Tokenizer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.tokenizer = tokenizer
+}
+
+// rerankResult reorders the candidates in result and keeps the best k of
+// them
+func (r *BasicRetriever) rerankResult(ctx context.Context, query Query, result *RetrievalResult, reranker RerankStrategy, k int) error {
+	start := time.Now()
+
+	docs, scores, err := reranker.Rerank(ctx, query, result.Documents)
+	if err != nil {
+		return NewRAGErrorWithCause("reranking failed", ErrorTypeExternal, err).WithOperation("rerank")
+	}
+
+	k = min(k, len(docs))
+
+	// Contributions of fused results follow their documents
+	var contributions [][]StrategyContribution
+	if len(result.Contributions) == len(result.Documents) {
+		byID := make(map[string][]StrategyContribution, len(result.Documents))
+		for i, doc := range result.Documents {
+			byID[doc.ID] = result.Contributions[i]
+		}
+		contributions = make([][]StrategyContribution, k)
+		for i, doc := range docs[:k] {
+			contributions[i] = byID[doc.ID]
+		}
+	}
+
+	result.Documents = docs[:k]
+	result.Scores = scores[:k]
+	result.Contributions = contributions
+	result.RerankTime = time.Since(start).Milliseconds()
+
+	return nil
+}
+
+// SetQueryProcessor makes Retrieve search several variants of each query:
+// the query as rewritten by processor.ProcessQuery, its expansions and, when
+// processor can generate them, hypothetical answer documents. The variants
+// are searched in parallel and their results merged with fusion; keywords
+// from processor.ExtractKeywords are added to every variant. A nil processor
+// searches the query as given.
+func (r *BasicRetriever) SetQueryProcessor(processor QueryProcessor, fusion *FusionConfig) error {
+	if fusion == nil {
+		fusion = DefaultFusionConfig()
+	}
+	if err := fusion.Validate(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.queries = processor
+	r.fusion = *fusion
+	return nil
+}
+
+func (r *BasicRetriever) getQueryProcessor() (QueryProcessor, FusionConfig) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.queries, r.fusion
+}
+
+// hypotheticalDocumenter is implemented by query processors that can write
+// hypothetical answers to search with (HyDE), such as LLMQueryProcessor
+type hypotheticalDocumenter interface {
+	GenerateHypotheticalDocuments(ctx context.Context, query string) ([]string, error)
+}
+
+// variantSearch searches candidates for a single query
+type variantSearch func(ctx context.Context, query Query) (*RetrievalResult, error)
+
+type queryVariant struct {
+	name  string
+	query Query
+}
+
+// searchVariants runs search for every variant of query and fuses the
+// results. It also returns the query the variants were derived from, which
+// is the rewritten query when a processor is set.
+func (r *BasicRetriever) searchVariants(ctx context.Context, query Query, search variantSearch) (*RetrievalResult, Query, error) {
+	processor, fusion := r.getQueryProcessor()
+	if processor == nil {
+		result, err := search(ctx, query)
+		return result, query, err
+	}
+
+	variants := r.queryVariants(ctx, query, processor)
+	base := variants[0].query
+	if len(variants) == 1 {
+		result, err := search(ctx, base)
+		return result, base, err
+	}
+
+	type variantResult struct {
+		result *RetrievalResult
+		err    error
+		index  int
+	}
+
+	resultChan := make(chan variantResult, len(variants))
+	for i, variant := range variants {
+		go func(idx int, q Query) {
+			result, err := search(ctx, q)
+			resultChan <- variantResult{result: result, err: err, index: idx}
+		}(i, variant.query)
+	}
+
+	results := make([]*RetrievalResult, len(variants))
+	for range variants {
+		vr := <-resultChan
+		if vr.err != nil {
+			return nil, base, NewRAGErrorWithCause(
+				fmt.Sprintf("search for query variant %s failed", variants[vr.index].name),
+				ErrorTypeInternal,
+				vr.err,
+			).WithOperation("retrieve")
+		}
+		results[vr.index] = vr.result
+	}
+
+	names := make([]string, len(variants))
+	weights := make([]float32, len(variants))
+	for i, variant := range variants {
+		names[i] = variant.name
+		weights[i] = 1
+	}
+
+	ranked := fuseResults(results, names, weights, fusion)
+
+	k := min(len(ranked), query.TopK)
+	result := &RetrievalResult{
+		Query:         base,
+		Documents:     make([]Document, k),
+		Scores:        make([]float32, k),
+		Contributions: make([][]StrategyContribution, k),
+		TotalFound:    len(ranked),
+	}
+	for i, fr := range ranked[:k] {
+		result.Documents[i] = fr.doc
+		result.Scores[i] = float32(fr.score)
+		result.Contributions[i] = fr.contributions
+	}
+
+	// Variants run in parallel, so the slowest one bounds each phase
+	for _, vr := range results {
+		result.EmbeddingTime = max(result.EmbeddingTime, vr.EmbeddingTime)
+		result.SearchTime = max(result.SearchTime, vr.SearchTime)
+	}
+
+	return result, base, nil
+}
+
+// queryVariants derives the queries to search from query. The first variant
+// is the rewritten query; processing failures are reported to listeners and
+// leave out the variants concerned, so retrieval degrades to the query as
+// given rather than failing.
+func (r *BasicRetriever) queryVariants(ctx context.Context, query Query, processor QueryProcessor) []queryVariant {
+	base, err := processor.ProcessQuery(ctx, query)
+	if err != nil || base.Text == "" {
+		r.notifyError(ctx, err)
+		base = query
+	}
+
+	if keywords, err := processor.ExtractKeywords(ctx, base.Text); err != nil {
+		r.notifyError(ctx, err)
+	} else if len(keywords) > 0 {
+		base.Keywords = append(append([]string(nil), base.Keywords...), keywords...)
+	}
+
+	variants := []queryVariant{{name: "query", query: base}}
+
+	expansions, err := processor.ExpandQuery(ctx, base.Text)
+	if err != nil {
+		r.notifyError(ctx, err)
+	}
+	for i, text := range expansions {
+		variant := base
+		variant.Text = text
+		variants = append(variants, queryVariant{name: fmt.Sprintf("paraphrase_%d", i+1), query: variant})
+	}
+
+	if generator, ok := processor.(hypotheticalDocumenter); ok {
+		docs, err := generator.GenerateHypotheticalDocuments(ctx, base.Text)
+		if err != nil {
+			r.notifyError(ctx, err)
+		}
+		for i, text := range docs {
+			variant := base
+			variant.Text = text
+			variants = append(variants, queryVariant{name: fmt.Sprintf("hyde_%d", i+1), query: variant})
+		}
+	}
+
+	return variants
+}
+
+// notifyError reports a non-fatal error to the listeners
+func (r *BasicRetriever) notifyError(ctx context.Context, err error) {
+	if err == nil {
+		return
+	}
+	for _, listener := range r.getListeners() {
+		listener.OnError(ctx, err)
+	}
+}
+
+// getListeners returns a copy of the registered listeners
+func (r *BasicRetriever) getListeners() []EventListener {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]EventListener(nil), r.listeners...)
+}
+
 // Close releases any resources held by the retriever
 func (r *BasicRetriever) Close() error {
 	r.mu.Lock()
@@ -388,10 +967,34 @@ func (r *BasicRetriever) validateQuery(query Query) error {
 		return ErrInvalidTopK.WithOperation("retrieve")
 	}
 
-	if query.Threshold < 0 || query.Threshold > 1 {
+	if err := r.metric().ValidateThreshold(query.Threshold); err != nil {
 		return ErrInvalidThreshold.WithOperation("retrieve")
 	}
 
+	if _, err := buildFilter(query); err != nil {
+		return err
+	}
+
+	if query.MMRLambda < 0 || query.MMRLambda > 1 {
+		return ValidationError("mmr_lambda", "MMR lambda must be between 0 and 1")
+	}
+
+	if query.MaxPerParent < 0 {
+		return ValidationError("max_per_parent", "max chunks per parent must not be negative")
+	}
+
+	if query.Expand != ExpandNone && query.Expand != ExpandNeighbors && query.Expand != ExpandParent {
+		return ValidationError("expand", "unsupported expand mode: "+string(query.Expand))
+	}
+
+	if query.ExpandWindow < 0 {
+		return ValidationError("expand_window", "expand window must not be negative")
+	}
+
+	if query.ExpandMaxTokens < 0 {
+		return ValidationError("expand_max_tokens", "expand token budget must not be negative")
+	}
+
 	return nil
 }
 
@@ -411,9 +1014,28 @@ func (r *BasicRetriever) validateDocument(doc Document) error {
 	return nil
 }
 
-func (r *BasicRetriever) vectorSearch(ctx context.Context, queryVector vector.Vector, query Query) (*vector.SearchResult, error) {
-	// Use the vector store's SearchWithThreshold method
-	result, err := r.vectorStore.SearchWithThreshold(queryVector, query.TopK, query.Threshold)
+// metric returns the similarity metric of the configured vector store
+func (r *BasicRetriever) metric() vector.Metric {
+	if r.config == nil || r.config.VectorStore == nil || r.config.VectorStore.Metric == "" {
+		return vector.MetricCosine
+	}
+	return r.config.VectorStore.Metric
+}
+
+func (r *BasicRetriever) vectorSearch(ctx context.Context, store vector.Store, queryVector vector.Vector, query Query) (*vector.SearchResult, error) {
+	filter, err := buildFilter(query)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unbounded metrics have no natural zero, so an unset threshold means none
+	threshold := query.Threshold
+	if threshold == 0 && !r.metric().Bounded() {
+		threshold = vector.NoThreshold
+	}
+
+	// Filters are evaluated by the store before ranking
+	result, err := store.SearchWithFilter(queryVector, query.TopK, threshold, filter)
 	if err != nil {
 		return nil, NewRAGErrorWithCause("vector search failed", ErrorTypeInternal, err).WithOperation("retrieve")
 	}
@@ -421,6 +1043,97 @@ func (r *BasicRetriever) vectorSearch(ctx context.Context, queryVector vector.Ve
 	return result, nil
 }
 
+// buildFilter combines the equality Filters map and the Filter expression of
+// a query into a single store filter; it returns nil when neither is set
+func buildFilter(query Query) (vector.Filter, error) {
+	var filters []vector.Filter
+
+	keys := make([]string, 0, len(query.Filters))
+	for key := range query.Filters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		filters = append(filters, vector.Eq(key, query.Filters[key]))
+	}
+
+	if query.Filter != "" {
+		expr, err := vector.ParseFilter(query.Filter)
+		if err != nil {
+			return nil, ErrInvalidFilter.WithOperation("retrieve").WithCause(err).WithDetails(map[string]string{
+				"filter": query.Filter,
+			})
+		}
+		if expr != nil {
+			filters = append(filters, expr)
+		}
+	}
+
+	if len(filters) == 0 {
+		return nil, nil
+	}
+
+	return vector.And(filters...), nil
+}
+
+// chunkMetadata builds the metadata stored with a chunk: the chunk's own
+// metadata plus the fields needed to map it back to its source document
+func chunkMetadata(doc Document, chunk Chunk) map[string]string {
+	metadata := make(map[string]string, len(chunk.Metadata)+6)
+	for k, v := range doc.Metadata {
+		metadata[k] = v
+	}
+	for k, v := range chunk.Metadata {
+		metadata[k] = v
+	}
+
+	metadata[MetadataDocumentID] = doc.ID
+	metadata[MetadataChunkIndex] = strconv.Itoa(chunk.Index)
+	if doc.Title != "" {
+		metadata[MetadataTitle] = doc.Title
+	}
+	if doc.Source != "" {
+		metadata[MetadataSource] = doc.Source
+	}
+	if !doc.CreatedAt.IsZero() {
+		metadata[MetadataCreatedAt] = doc.CreatedAt.UTC().Format(time.RFC3339Nano)
+	}
+	if !doc.UpdatedAt.IsZero() {
+		metadata[MetadataUpdatedAt] = doc.UpdatedAt.UTC().Format(time.RFC3339Nano)
+	}
+
+	return metadata
+}
+
+// documentFromVector converts a stored chunk back into a RAG document
+func documentFromVector(vd vector.Document) Document {
+	doc := Document{
+		ID:       vd.ID,
+		Content:  vd.Content,
+		Vector:   vd.Vector,
+		Metadata: vd.Metadata,
+	}
+
+	if vd.Metadata == nil {
+		return doc
+	}
+
+	doc.Title = vd.Metadata[MetadataTitle]
+	doc.Source = vd.Metadata[MetadataSource]
+	doc.ParentID = vd.Metadata[MetadataDocumentID]
+	if index, err := strconv.Atoi(vd.Metadata[MetadataChunkIndex]); err == nil {
+		doc.ChunkIndex = index
+	}
+	if t, err := time.Parse(time.RFC3339Nano, vd.Metadata[MetadataCreatedAt]); err == nil {
+		doc.CreatedAt = t
+	}
+	if t, err := time.Parse(time.RFC3339Nano, vd.Metadata[MetadataUpdatedAt]); err == nil {
+		doc.UpdatedAt = t
+	}
+
+	return doc
+}
+
 func (r *BasicRetriever) updateStats(result *RetrievalResult) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -437,33 +1150,79 @@ type HybridRetriever struct {
 	*BasicRetriever
 	strategies []SearchStrategy
 	weights    []float32
+	fusion     FusionConfig
 }
 
-// NewHybridRetriever creates a retriever that combines multiple search strategies
+// NewHybridRetriever creates a retriever that combines multiple search
+// strategies with reciprocal rank fusion
 func NewHybridRetriever(
 	basic *BasicRetriever,
 	strategies []SearchStrategy,
 	weights []float32,
 ) (*HybridRetriever, error) {
+	return NewHybridRetrieverWithFusion(basic, strategies, weights, DefaultFusionConfig())
+}
+
+// NewHybridRetrieverWithFusion creates a retriever that combines multiple
+// search strategies with the given fusion method
+func NewHybridRetrieverWithFusion(
+	basic *BasicRetriever,
+	strategies []SearchStrategy,
+	weights []float32,
+	fusion *FusionConfig,
+) (*HybridRetriever, error) {
+	if basic == nil {
+		return nil, NewRAGErrorWithOp("new_hybrid_retriever", "basic retriever is required", ErrorTypeValidation)
+	}
 	if len(strategies) != len(weights) {
 		return nil, NewRAGErrorWithOp("new_hybrid_retriever", "strategies and weights must have same length", ErrorTypeValidation)
 	}
+	for i, weight := range weights {
+		if strategies[i] == nil {
+			return nil, NewRAGErrorWithOp("new_hybrid_retriever", "strategy must not be nil", ErrorTypeValidation)
+		}
+		if weight < 0 {
+			return nil, NewRAGErrorWithOp("new_hybrid_retriever", "weights must not be negative", ErrorTypeValidation)
+		}
+	}
+
+	if fusion == nil {
+		fusion = DefaultFusionConfig()
+	}
+	if err := fusion.Validate(); err != nil {
+		return nil, err
+	}
 
 	return &HybridRetriever{
 		BasicRetriever: basic,
 		strategies:     strategies,
 		weights:        weights,
+		fusion:         *fusion,
 	}, nil
 }
 
-// Retrieve implements hybrid search by combining multiple strategies
+// Retrieve implements hybrid search by combining multiple strategies.
+// query.Threshold is applied by each strategy on the scale of its own
+// scores: the semantic strategy drops chunks below the similarity
+// threshold, while BM25 scores have no such scale and keyword hits are kept.
+// Fused scores are never compared with it, since rank fusion has no
+// absolute scale; query.TopK is applied to the fused ranking.
 func (h *HybridRetriever) Retrieve(ctx context.Context, query Query) (*RetrievalResult, error) {
 	if len(h.strategies) == 0 {
 		return h.BasicRetriever.Retrieve(ctx, query)
 	}
 
-	start := time.Now()
-	
+	if err := h.validateQuery(query); err != nil {
+		return nil, err
+	}
+
+	return h.retrieve(ctx, query, h.searchStrategies)
+}
+
+// searchStrategies runs all strategies in parallel and fuses their results
+func (h *HybridRetriever) searchStrategies(ctx context.Context, query Query) (*RetrievalResult, error) {
+	candidateQuery := query
+
 	// Execute all strategies in parallel
 	type strategyResult struct {
 		result *RetrievalResult
@@ -472,10 +1231,10 @@ func (h *HybridRetriever) Retrieve(ctx context.Context, query Query) (*Retrieval
 	}
 
 	resultChan := make(chan strategyResult, len(h.strategies))
-	
+
 	for i, strategy := range h.strategies {
 		go func(idx int, strat SearchStrategy) {
-			result, err := strat.Search(ctx, query, h.vectorStore)
+			result, err := strat.Search(ctx, candidateQuery, h.vectorStore)
 			resultChan <- strategyResult{result: result, err: err, index: idx}
 		}(i, strategy)
 	}
@@ -486,7 +1245,7 @@ func (h *HybridRetriever) Retrieve(ctx context.Context, query Query) (*Retrieval
 		sr := <-resultChan
 		if sr.err != nil {
 			return nil, NewRAGErrorWithCause(
-				fmt.Sprintf("strategy %d failed", sr.index),
+				fmt.Sprintf("strategy %s failed", h.strategies[sr.index].GetName()),
 				ErrorTypeInternal,
 				sr.err,
 			).WithOperation("hybrid_retrieve")
@@ -494,80 +1253,43 @@ func (h *HybridRetriever) Retrieve(ctx context.Context, query Query) (*Retrieval
 		strategyResults[sr.index] = sr.result
 	}
 
-	// Combine results using weighted scoring
-	combinedResult, err := h.combineResults(strategyResults, h.weights)
-	if err != nil {
-		return nil, err
-	}
-
-	combinedResult.QueryTime = time.Since(start).Milliseconds()
-	
-	// Update statistics
-	h.updateStats(combinedResult)
-
-	return combinedResult, nil
+	return h.combineResults(query, strategyResults), nil
 }
 
-func (h *HybridRetriever) combineResults(results []*RetrievalResult, weights []float32) (*RetrievalResult, error) {
-	if len(results) == 0 {
-		return nil, NewRAGErrorWithOp("combine_results", "no results to combine", ErrorTypeValidation)
+// combineResults fuses the strategy results and applies the query's TopK
+// to the merged list
+func (h *HybridRetriever) combineResults(query Query, results []*RetrievalResult) *RetrievalResult {
+	names := make([]string, len(h.strategies))
+	for i, strategy := range h.strategies {
+		names[i] = strategy.GetName()
 	}
 
-	// Create a map to collect document scores across strategies
-	docScores := make(map[string]float32)
-	docMap := make(map[string]Document)
+	ranked := fuseResults(results, names, h.weights, h.fusion)
 
-	// Combine scores from all strategies
-	for i, result := range results {
-		weight := weights[i]
-		for j, doc := range result.Documents {
-			score := result.Scores[j] * weight
-			
-			if existingScore, exists := docScores[doc.ID]; exists {
-				docScores[doc.ID] = existingScore + score
-			} else {
-				docScores[doc.ID] = score
-				docMap[doc.ID] = doc
-			}
-		}
-	}
-
-	// Sort documents by combined score
-	type docScore struct {
-		doc   Document
-		score float32
+	combined := &RetrievalResult{
+		Query:         query,
+		Documents:     make([]Document, 0, min(len(ranked), query.TopK)),
+		Scores:        make([]float32, 0, min(len(ranked), query.TopK)),
+		Contributions: make([][]StrategyContribution, 0, min(len(ranked), query.TopK)),
 	}
 
-	var sortedDocs []docScore
-	for docID, score := range docScores {
-		sortedDocs = append(sortedDocs, docScore{
-			doc:   docMap[docID],
-			score: score,
-		})
+	for _, fr := range ranked {
+		combined.TotalFound++
+		if len(combined.Documents) < query.TopK {
+			combined.Documents = append(combined.Documents, fr.doc)
+			combined.Scores = append(combined.Scores, float32(fr.score))
+			combined.Contributions = append(combined.Contributions, fr.contributions)
+		}
 	}
 
-	sort.Slice(sortedDocs, func(i, j int) bool {
-		return sortedDocs[i].score > sortedDocs[j].score
-	})
-
-	// Extract final results
-	finalDocs := make([]Document, len(sortedDocs))
-	finalScores := make([]float32, len(sortedDocs))
-	
-	for i, ds := range sortedDocs {
-		finalDocs[i] = ds.doc
-		finalScores[i] = ds.score
+	// Strategies run in parallel, so the slowest one bounds each phase
+	for _, result := range results {
+		if result == nil {
+			continue
+		}
+		combined.EmbeddingTime = max(combined.EmbeddingTime, result.EmbeddingTime)
+		combined.SearchTime = max(combined.SearchTime, result.SearchTime)
 	}
 
-	// Use the first result as template for other fields
-	template := results[0]
-	
-	return &RetrievalResult{
-		Query:         template.Query,
-		Documents:     finalDocs,
-		Scores:        finalScores,
-		TotalFound:    len(finalDocs),
-		EmbeddingTime: template.EmbeddingTime, // Average could be computed
-		SearchTime:    template.SearchTime,    // Sum could be computed
-	}, nil
-}
\ No newline at end of file
+	return combined
+}
@@ -0,0 +1,259 @@
+package rag
+
+import (
+	"context"
+	"regexp"
+	"sort"
+
+	"github.com/PerceptivePenguin/MCPRAG-Go/internal/vector"
+)
+
+// SemanticBreakpoint selects how ChunkBySemantic decides where topics change
+type SemanticBreakpoint string
+
+const (
+	// SemanticBreakpointPercentile breaks where the distance between
+	// adjacent sentences is above the SemanticThreshold percentile of all
+	// distances in the document
+	SemanticBreakpointPercentile SemanticBreakpoint = "percentile"
+
+	// SemanticBreakpointGradient breaks where the distance rises fastest,
+	// its gradient being above the SemanticThreshold percentile; it finds
+	// shifts in text whose distances drift as a whole
+	SemanticBreakpointGradient SemanticBreakpoint = "gradient"
+)
+
+// Defaults of the ChunkBySemantic options
+const (
+	defaultSemanticThreshold = 95
+	defaultSemanticWindow    = 1
+)
+
+// sentenceEnd matches the end of a sentence with the whitespace after it:
+// Western terminators followed by whitespace, CJK terminators, which need
+// none, and blank lines
+var sentenceEnd = regexp.MustCompile(`[.!?]+["')\]]*\s+|[。！？]+["'」』）]*\s*|\n[ \t]*\n\s*`)
+
+// chunkBySemantic splits text where the topic changes. Each sentence is
+// embedded together with SemanticWindow sentences on either side, and the
+// text is broken between sentences whose windows are far apart, as chosen
+// by SemanticBreakpoint. Groups larger than MaxChunkSize are split further
+// at their widest gap. Embeddings go through the embedder and its cache, so
+// chunking the same text again costs no embedding calls. Without an
+// embedder the word overlap heuristic is used.
+func (c *TextChunker) chunkBySemantic(ctx context.Context, doc Document, options ChunkingOptions) ([]Chunk, error) {
+	if c.embedder == nil {
+		return c.chunkByWordOverlap(doc, options)
+	}
+
+	method := options.SemanticBreakpoint
+	if method == "" {
+		method = SemanticBreakpointPercentile
+	}
+	if method != SemanticBreakpointPercentile && method != SemanticBreakpointGradient {
+		return nil, ValidationError("semantic_breakpoint", "unsupported semantic breakpoint: "+string(method))
+	}
+	threshold := options.SemanticThreshold
+	if threshold == 0 {
+		threshold = defaultSemanticThreshold
+	}
+	if threshold < 0 || threshold > 100 {
+		return nil, ValidationError("semantic_threshold", "semantic threshold must be a percentile between 0 and 100")
+	}
+	window := options.SemanticWindow
+	if window == 0 {
+		window = defaultSemanticWindow
+	}
+	if window < 0 {
+		return nil, ValidationError("semantic_window", "semantic window must not be negative")
+	}
+
+	text := doc.Content
+	spans := sentenceSpans(text)
+	if len(spans) == 0 {
+		return nil, ErrDocumentEmpty.WithOperation("chunk_by_semantic")
+	}
+
+	distances, err := c.sentenceDistances(ctx, text, spans, window)
+	if err != nil {
+		return nil, err
+	}
+
+	scores := distances
+	if method == SemanticBreakpointGradient {
+		scores = gradient(distances)
+	}
+	var cut float64
+	if len(scores) > 0 {
+		cut = percentile(scores, threshold)
+	}
+
+	var chunks []Chunk
+	start := 0
+	for i := range spans {
+		if i < len(scores) && scores[i] <= cut {
+			continue
+		}
+		chunks, err = c.semanticGroup(chunks, doc, options, spans, distances, start, i+1)
+		if err != nil {
+			return nil, err
+		}
+		start = i + 1
+	}
+
+	return chunks, nil
+}
+
+// sentenceSpans returns the byte offsets of the sentences of text, without
+// surrounding whitespace
+func sentenceSpans(text string) [][2]int {
+	var spans [][2]int
+	add := func(start, end int) {
+		for start < end && isSpaceByte(text[start]) {
+			start++
+		}
+		for end > start && isSpaceByte(text[end-1]) {
+			end--
+		}
+		if start < end {
+			spans = append(spans, [2]int{start, end})
+		}
+	}
+
+	start := 0
+	for _, match := range sentenceEnd.FindAllStringIndex(text, -1) {
+		add(start, match[1])
+		start = match[1]
+	}
+	add(start, len(text))
+
+	return spans
+}
+
+func isSpaceByte(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r' || b == '\v' || b == '\f'
+}
+
+// sentenceDistances returns the cosine distance between the windows around
+// each pair of adjacent sentences
+func (c *TextChunker) sentenceDistances(ctx context.Context, text string, spans [][2]int, window int) ([]float64, error) {
+	if len(spans) < 2 {
+		return nil, nil
+	}
+
+	windows := make([]string, len(spans))
+	for i := range spans {
+		first := max(i-window, 0)
+		last := min(i+window, len(spans)-1)
+		windows[i] = text[spans[first][0]:spans[last][1]]
+	}
+
+	embeddings, err := c.embedder.EmbedBatch(ctx, windows)
+	if err != nil {
+		return nil, NewRAGErrorWithCause("failed to embed sentences", ErrorTypeExternal, err).WithOperation("chunk_by_semantic")
+	}
+
+	distances := make([]float64, len(spans)-1)
+	for i := range distances {
+		if embeddings[i] == nil || embeddings[i+1] == nil {
+			continue
+		}
+		distances[i] = 1 - float64(vector.CosineSimilarity(embeddings[i].Vector, embeddings[i+1].Vector))
+	}
+
+	return distances, nil
+}
+
+// semanticGroup appends the sentences lo to hi as one chunk, or as several
+// split at the largest distance when they do not fit in MaxChunkSize. A
+// single sentence that does not fit is split by size.
+func (c *TextChunker) semanticGroup(chunks []Chunk, doc Document, options ChunkingOptions, spans [][2]int, distances []float64, lo, hi int) ([]Chunk, error) {
+	text := doc.Content
+	start, end := spans[lo][0], spans[hi-1][1]
+
+	if c.chunkSize(text[start:end]) <= options.MaxChunkSize {
+		return append(chunks, Chunk{
+			Content:  text[start:end],
+			StartPos: start,
+			EndPos:   end,
+		}), nil
+	}
+
+	if hi-lo == 1 {
+		part := doc
+		part.Content = text[start:end]
+		sized := options
+		sized.Overlap = 0
+
+		var pieces []Chunk
+		var err error
+		if c.tokenizer != nil {
+			pieces, err = c.chunkByTokens(part, sized)
+		} else {
+			pieces, err = c.chunkByFixedSize(part, sized)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, piece := range pieces {
+			piece.StartPos += start
+			piece.EndPos += start
+			chunks = append(chunks, piece)
+		}
+		return chunks, nil
+	}
+
+	// Split where the topic shifts most
+	gap := lo
+	for k := lo + 1; k < hi-1; k++ {
+		if distances[k] > distances[gap] {
+			gap = k
+		}
+	}
+
+	chunks, err := c.semanticGroup(chunks, doc, options, spans, distances, lo, gap+1)
+	if err != nil {
+		return nil, err
+	}
+	return c.semanticGroup(chunks, doc, options, spans, distances, gap+1, hi)
+}
+
+// chunkSize measures text in tokens, or in bytes without a tokenizer
+func (c *TextChunker) chunkSize(text string) int {
+	if c.tokenizer != nil {
+		return c.tokenizer.CountTokens(text)
+	}
+	return len(text)
+}
+
+// gradient returns the discrete derivative of values, using central
+// differences inside and one-sided ones at the ends
+func gradient(values []float64) []float64 {
+	n := len(values)
+	if n < 2 {
+		return make([]float64, n)
+	}
+
+	g := make([]float64, n)
+	g[0] = values[1] - values[0]
+	g[n-1] = values[n-1] - values[n-2]
+	for i := 1; i < n-1; i++ {
+		g[i] = (values[i+1] - values[i-1]) / 2
+	}
+	return g
+}
+
+// percentile returns the p-th percentile of values, interpolating linearly
+// between the closest ranks
+func percentile(values []float64, p float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(rank)
+	if lower >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+	frac := rank - float64(lower)
+	return sorted[lower] + frac*(sorted[lower+1]-sorted[lower])
+}
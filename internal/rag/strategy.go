@@ -0,0 +1,42 @@
+package rag
+
+import (
+	"context"
+
+	"github.com/PerceptivePenguin/MCPRAG-Go/internal/vector"
+)
+
+// SemanticStrategy runs the embedding and vector search of a BasicRetriever
+// as a SearchStrategy, so that it can be combined with other strategies such
+// as BM25Strategy in a HybridRetriever
+type SemanticStrategy struct {
+	retriever *BasicRetriever
+}
+
+// NewSemanticStrategy creates a vector search strategy using the embedder
+// and metric of retriever
+func NewSemanticStrategy(retriever *BasicRetriever) *SemanticStrategy {
+	return &SemanticStrategy{retriever: retriever}
+}
+
+// Search embeds the query and searches store
+func (s *SemanticStrategy) Search(ctx context.Context, query Query, store vector.Store) (*RetrievalResult, error) {
+	if err := s.retriever.validateQuery(query); err != nil {
+		return nil, err
+	}
+
+	return s.retriever.semanticSearch(ctx, query, store)
+}
+
+// GetName returns the strategy name
+func (s *SemanticStrategy) GetName() string {
+	return string(SearchSemantic)
+}
+
+// GetDescription returns strategy description
+func (s *SemanticStrategy) GetDescription() string {
+	return "embedding similarity search over the vector store"
+}
+
+// Ensure SemanticStrategy implements SearchStrategy
+var _ SearchStrategy = (*SemanticStrategy)(nil)
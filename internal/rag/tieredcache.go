@@ -0,0 +1,145 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/PerceptivePenguin/MCPRAG-Go/internal/vector"
+)
+
+// TieredCache puts an in-memory cache in front of a DiskCache. Lookups
+// missing the memory tier fall through to disk and are promoted on a hit;
+// writes go to both, so the disk tier holds everything the memory tier
+// evicted and survives restarts.
+type TieredCache struct {
+	memory Cache
+	disk   *DiskCache
+
+	mu      sync.Mutex
+	metrics CacheStats
+}
+
+// NewTieredCache combines memory and disk, warming memory with the most
+// recently used entries on disk
+func NewTieredCache(memory Cache, disk *DiskCache) (*TieredCache, error) {
+	if memory == nil || disk == nil {
+		return nil, NewRAGErrorWithOp("new_tiered_cache", "memory and disk caches are required", ErrorTypeValidation)
+	}
+
+	ctx := context.Background()
+	for _, entry := range disk.Recent(memory.Stats().MaxSize) {
+		if err := memory.Set(ctx, entry.Key, entry.Vector, entry.Metadata); err != nil {
+			break
+		}
+	}
+
+	return &TieredCache{
+		memory: memory,
+		disk:   disk,
+	}, nil
+}
+
+// Get retrieves a cached embedding from memory, or from disk
+func (c *TieredCache) Get(ctx context.Context, key string) (*CacheEntry, error) {
+	entry, err := c.memory.Get(ctx, key)
+	if err == nil {
+		c.record(true)
+		return entry, nil
+	}
+	if !errors.Is(err, ErrCacheKeyNotFound) {
+		return nil, err
+	}
+
+	entry, err = c.disk.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, ErrCacheKeyNotFound) {
+			c.record(false)
+		}
+		return nil, err
+	}
+
+	c.memory.Set(ctx, key, entry.Vector, entry.Metadata)
+	c.record(true)
+	return entry, nil
+}
+
+// Set stores an embedding in both tiers
+func (c *TieredCache) Set(ctx context.Context, key string, vector vector.Vector, metadata map[string]string) error {
+	if err := c.disk.Set(ctx, key, vector, metadata); err != nil {
+		return err
+	}
+	return c.memory.Set(ctx, key, vector, metadata)
+}
+
+// Delete removes an entry from both tiers
+func (c *TieredCache) Delete(ctx context.Context, key string) error {
+	memoryErr := c.memory.Delete(ctx, key)
+	diskErr := c.disk.Delete(ctx, key)
+
+	// Found in either tier is found
+	if errors.Is(memoryErr, ErrCacheKeyNotFound) && errors.Is(diskErr, ErrCacheKeyNotFound) {
+		return ErrCacheKeyNotFound
+	}
+	for _, err := range []error{memoryErr, diskErr} {
+		if err != nil && !errors.Is(err, ErrCacheKeyNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Clear removes all entries from both tiers
+func (c *TieredCache) Clear(ctx context.Context) error {
+	if err := c.memory.Clear(ctx); err != nil {
+		return err
+	}
+	return c.disk.Clear(ctx)
+}
+
+// Size returns the number of entries on disk, which holds every entry
+// kept in memory
+func (c *TieredCache) Size() int {
+	return c.disk.Size()
+}
+
+// Stats returns the statistics of the memory tier with hits and misses
+// counted across both tiers and the disk usage
+func (c *TieredCache) Stats() CacheStats {
+	stats := c.memory.Stats()
+
+	c.mu.Lock()
+	stats.Hits = c.metrics.Hits
+	stats.Misses = c.metrics.Misses
+	stats.HitRate = c.metrics.HitRate
+	c.mu.Unlock()
+
+	stats.DiskUsage = c.disk.Stats().DiskUsage
+	return stats
+}
+
+// Close closes both tiers
+func (c *TieredCache) Close() error {
+	memoryErr := c.memory.Close()
+	if err := c.disk.Close(); err != nil {
+		return err
+	}
+	return memoryErr
+}
+
+// record counts a lookup as a hit in either tier or a miss in both
+func (c *TieredCache) record(hit bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if hit {
+		c.metrics.Hits++
+	} else {
+		c.metrics.Misses++
+	}
+	total := c.metrics.Hits + c.metrics.Misses
+	c.metrics.HitRate = float64(c.metrics.Hits) / float64(total)
+}
+
+// Ensure TieredCache implements Cache
+var _ Cache = (*TieredCache)(nil)
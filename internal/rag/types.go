@@ -22,15 +22,45 @@ type Document struct {
 
 // Query represents a retrieval query with parameters
 type Query struct {
-	Text           string            `json:"text"`
-	TopK           int               `json:"top_k"`
-	Threshold      float32           `json:"threshold"`
-	Filters        map[string]string `json:"filters,omitempty"`
-	IncludeVector  bool              `json:"include_vector"`
-	MaxTokens      int               `json:"max_tokens,omitempty"`
-	Strategy       string            `json:"strategy,omitempty"`
+	Text            string            `json:"text"`
+	TopK            int               `json:"top_k"`
+	Threshold       float32           `json:"threshold"`         // minimum similarity of vector matches; keyword and fused scores are not compared with it
+	Filters         map[string]string `json:"filters,omitempty"` // metadata equality matches, ANDed together
+	Filter          string            `json:"filter,omitempty"`  // filter expression, see vector.ParseFilter
+	IncludeVector   bool              `json:"include_vector"`
+	MaxTokens       int               `json:"max_tokens,omitempty"`
+	Strategy        string            `json:"strategy,omitempty"`
+	Index           string            `json:"index,omitempty"`             // named index to search, see IndexManager
+	History         []string          `json:"history,omitempty"`           // earlier conversation turns, oldest first, for rewriting follow-ups
+	Keywords        []string          `json:"keywords,omitempty"`          // extra terms for keyword search, see QueryProcessor
+	MMR             bool              `json:"mmr,omitempty"`               // diversify results with maximal marginal relevance
+	MMRLambda       float32           `json:"mmr_lambda,omitempty"`        // MMR weight of relevance against redundancy in [0, 1], zero means 0.5
+	MaxPerParent    int               `json:"max_per_parent,omitempty"`    // maximum chunks per ParentID, zero for no cap
+	Expand          ExpandMode        `json:"expand,omitempty"`            // return matched chunks with their neighbors or parent section
+	ExpandWindow    int               `json:"expand_window,omitempty"`     // chunks on each side for ExpandNeighbors, zero means 1
+	ExpandMaxTokens int               `json:"expand_max_tokens,omitempty"` // token budget of each expanded result, zero means DefaultExpandMaxTokens
 }
 
+// Metadata keys the retriever stores with every chunk so that results can be
+// mapped back to their source document and filtered on
+const (
+	MetadataDocumentID = "document_id"
+	MetadataTitle      = "title"
+	MetadataSource     = "source"
+	MetadataChunkIndex = "chunk_index"
+	MetadataCreatedAt  = "created_at"
+	MetadataUpdatedAt  = "updated_at"
+)
+
+// Metadata keys of the byte offsets of a chunk in Document.Content. They are
+// set only where the chunk is a slice of it: token, fixed-size and code
+// chunks of plain text and source code. Markdown, HTML and JSON chunks are
+// cut from extracted text and have none.
+const (
+	MetadataStartPos = "start_pos"
+	MetadataEndPos   = "end_pos"
+)
+
 // RetrievalResult contains the results of a document retrieval
 type RetrievalResult struct {
 	Query         Query      `json:"query"`
@@ -40,7 +70,21 @@ type RetrievalResult struct {
 	QueryTime     int64      `json:"query_time_ms"`
 	EmbeddingTime int64      `json:"embedding_time_ms"`
 	SearchTime    int64      `json:"search_time_ms"`
+	RerankTime    int64      `json:"rerank_time_ms,omitempty"`
 	Context       string     `json:"context,omitempty"`
+
+	// Contributions is parallel to Documents for fused results and lists
+	// the strategies that found each document
+	Contributions [][]StrategyContribution `json:"contributions,omitempty"`
+}
+
+// StrategyContribution describes how one search strategy ranked a fused
+// result
+type StrategyContribution struct {
+	Strategy string  `json:"strategy"`
+	Rank     int     `json:"rank"`  // 1-based rank in the strategy's own results
+	Score    float32 `json:"score"` // score reported by the strategy
+	Fused    float32 `json:"fused"` // part of the fused score due to this strategy
 }
 
 // ChunkingOptions configures document chunking behavior
@@ -50,6 +94,14 @@ type ChunkingOptions struct {
 	Overlap      int           `json:"overlap"`
 	Separators   []string      `json:"separators,omitempty"`
 	PreserveStructure bool     `json:"preserve_structure"`
+
+	// ChunkBySemantic only: how breakpoints are chosen (percentile when
+	// empty), the percentile of distances or gradients above which text is
+	// broken (95 when zero) and the number of sentences on each side
+	// embedded with a sentence (1 when zero)
+	SemanticBreakpoint SemanticBreakpoint `json:"semantic_breakpoint,omitempty"`
+	SemanticThreshold  float64            `json:"semantic_threshold,omitempty"`
+	SemanticWindow     int                `json:"semantic_window,omitempty"`
 }
 
 // ChunkStrategy defines different chunking strategies
@@ -61,20 +113,39 @@ const (
 	ChunkByParagraphs ChunkStrategy = "paragraphs"
 	ChunkByFixedSize ChunkStrategy = "fixed_size"
 	ChunkBySemantic  ChunkStrategy = "semantic"
+	ChunkByCode      ChunkStrategy = "code" // syntactic boundaries of source code, see chunkByCode
 )
 
 // EmbeddingConfig configures the embedding generation
 type EmbeddingConfig struct {
-	Model       string            `json:"model"`
-	APIKey      string            `json:"api_key"`
-	BaseURL     string            `json:"base_url,omitempty"`
-	MaxRetries  int               `json:"max_retries"`
-	Timeout     time.Duration     `json:"timeout"`
-	BatchSize   int               `json:"batch_size"`
-	RateLimit   int               `json:"rate_limit"`
-	Headers     map[string]string `json:"headers,omitempty"`
+	Provider       EmbeddingProvider `json:"provider,omitempty"`        // empty means EmbeddingProviderOpenAI
+	Model          string            `json:"model"`
+	APIKey         string            `json:"api_key"`
+	BaseURL        string            `json:"base_url,omitempty"`
+	MaxRetries     int               `json:"max_retries"`
+	Timeout        time.Duration     `json:"timeout"`
+	BatchSize      int               `json:"batch_size"`
+	RateLimit      int               `json:"rate_limit"`
+	Headers        map[string]string `json:"headers,omitempty"`
+	Dimensions     int               `json:"dimensions,omitempty"`      // vector dimension; HTTP providers detect it when zero
+	QueryPrefix    string            `json:"query_prefix,omitempty"`    // prepended to queries by HTTP providers, e.g. "query: " for E5 models
+	DocumentPrefix string            `json:"document_prefix,omitempty"` // prepended to documents by HTTP providers
 }
 
+// EmbeddingProvider selects the Embedder implementation, see NewEmbedder
+type EmbeddingProvider string
+
+const (
+	EmbeddingProviderOpenAI  EmbeddingProvider = "openai"
+	EmbeddingProviderHashing EmbeddingProvider = "hashing" // local, see HashingEmbedder
+
+	// Providers served over HTTP by HTTPEmbedder
+	EmbeddingProviderOpenAICompatible EmbeddingProvider = "openai_compatible" // POST /embeddings
+	EmbeddingProviderTEI              EmbeddingProvider = "tei"               // text-embeddings-inference, POST /embed
+	EmbeddingProviderOllama           EmbeddingProvider = "ollama"            // POST /api/embeddings
+	EmbeddingProviderCohere           EmbeddingProvider = "cohere"            // POST /v1/embed with input_type
+)
+
 // DefaultEmbeddingConfig returns default embedding configuration
 func DefaultEmbeddingConfig() *EmbeddingConfig {
 	return &EmbeddingConfig{
@@ -93,6 +164,8 @@ type CacheConfig struct {
 	TTL         time.Duration `json:"ttl"`
 	PersistPath string        `json:"persist_path,omitempty"`
 	Strategy    CacheStrategy `json:"strategy"`
+	DiskPath    string        `json:"disk_path,omitempty"`     // directory of a disk cache behind the in-memory one, see DiskCache
+	DiskMaxSize int64         `json:"disk_max_size,omitempty"` // bytes of live disk cache entries, zero means DefaultDiskCacheMaxSize
 }
 
 // CacheStrategy defines cache eviction strategies
@@ -153,7 +226,7 @@ func DefaultProcessingOptions() *ProcessingOptions {
 		RemoveStopWords:  false,
 		Lowercase:        false,
 		RemovePunctuation: false,
-		SupportedFormats: []string{"txt", "md", "json"},
+		SupportedFormats: []string{"txt", "md", "json", "html", "code"},
 		MaxDocumentSize:  1024 * 1024, // 1MB
 	}
 }
@@ -171,13 +244,23 @@ type RetrievalStats struct {
 
 // EmbeddingRequest represents a request for generating embeddings
 type EmbeddingRequest struct {
-	Text      string            `json:"text"`
-	Model     string            `json:"model,omitempty"`
-	Metadata  map[string]string `json:"metadata,omitempty"`
-	BatchID   string            `json:"batch_id,omitempty"`
-	Priority  int               `json:"priority,omitempty"`
+	Text      string             `json:"text"`
+	Model     string             `json:"model,omitempty"`
+	Metadata  map[string]string  `json:"metadata,omitempty"`
+	BatchID   string             `json:"batch_id,omitempty"`
+	Priority  int                `json:"priority,omitempty"`
+	InputType EmbeddingInputType `json:"input_type,omitempty"` // empty means InputTypeDocument
 }
 
+// EmbeddingInputType tells providers that embed search queries and the
+// documents they search differently which of the two a text is
+type EmbeddingInputType string
+
+const (
+	InputTypeDocument EmbeddingInputType = "document"
+	InputTypeQuery    EmbeddingInputType = "query"
+)
+
 // EmbeddingResponse represents the response from embedding generation
 type EmbeddingResponse struct {
 	Vector    vector.Vector     `json:"vector"`
@@ -236,6 +319,7 @@ type RetrievalConfig struct {
 	Context    *ContextConfig     `json:"context"`
 	Processing *ProcessingOptions `json:"processing"`
 	VectorStore *vector.Config    `json:"vector_store"`
+	Tokenizer  *TokenizerConfig   `json:"tokenizer"`
 }
 
 // DefaultRetrievalConfig returns a default retrieval configuration
@@ -247,6 +331,7 @@ func DefaultRetrievalConfig() *RetrievalConfig {
 		Context:     DefaultContextConfig(),
 		Processing:  DefaultProcessingOptions(),
 		VectorStore: vector.DefaultConfig(),
+		Tokenizer:   DefaultTokenizerConfig(),
 	}
 }
 
@@ -0,0 +1,284 @@
+package rag
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Defaults of WatchConfig
+const (
+	DefaultWatchInterval = time.Second
+	DefaultWatchDebounce = 2 * time.Second
+)
+
+// WatchConfig configures a Watcher
+type WatchConfig struct {
+	Interval time.Duration `json:"interval"` // how often the directories are polled
+	Debounce time.Duration `json:"debounce"` // how long changes must settle before they are applied
+}
+
+// DefaultWatchConfig returns the default watch configuration
+func DefaultWatchConfig() *WatchConfig {
+	return &WatchConfig{
+		Interval: DefaultWatchInterval,
+		Debounce: DefaultWatchDebounce,
+	}
+}
+
+// WatchCallback is called after the watcher applied the changes under a
+// root; err is set when the changes could not be applied at all
+type WatchCallback func(root string, report *IngestReport, err error)
+
+// Watcher keeps a retriever in line with directories while they are being
+// edited. Every root is ingested once when the watcher starts; after that
+// the roots are polled for files that are created, modified or deleted,
+// and once a burst of changes has been quiet for the debounce period the
+// changed files are ingested and the documents of deleted ones removed
+// through the Ingester.
+type Watcher struct {
+	ingester *Ingester
+	roots    []string
+	config   *WatchConfig
+
+	mu       sync.RWMutex
+	callback WatchCallback
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// watchedFile is what the watcher remembers about a file between polls
+type watchedFile struct {
+	size    int64
+	modTime time.Time
+}
+
+// pendingChanges are the changes under a root waiting for the burst to end
+type pendingChanges struct {
+	changed map[string]bool
+	removed map[string]bool
+	last    time.Time
+}
+
+// NewWatcher creates a watcher applying the changes under roots through
+// ingester
+func NewWatcher(ingester *Ingester, roots []string, config *WatchConfig) (*Watcher, error) {
+	if ingester == nil {
+		return nil, NewRAGErrorWithOp("new_watcher", "ingester is required", ErrorTypeValidation)
+	}
+	if len(roots) == 0 {
+		return nil, NewRAGErrorWithOp("new_watcher", "no directories to watch", ErrorTypeValidation)
+	}
+	if config == nil {
+		config = DefaultWatchConfig()
+	}
+	if config.Interval < 0 || config.Debounce < 0 {
+		return nil, ValidationError("interval", "watch interval and debounce must not be negative")
+	}
+
+	resolved := *config
+	if resolved.Interval == 0 {
+		resolved.Interval = DefaultWatchInterval
+	}
+
+	cleaned := make([]string, len(roots))
+	for i, root := range roots {
+		cleaned[i] = filepath.Clean(root)
+	}
+
+	return &Watcher{
+		ingester: ingester,
+		roots:    cleaned,
+		config:   &resolved,
+	}, nil
+}
+
+// OnChange sets the callback called after changes are applied
+func (w *Watcher) OnChange(callback WatchCallback) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.callback = callback
+}
+
+// Start ingests the roots and starts watching them in the background until
+// ctx is done or Stop is called
+func (w *Watcher) Start(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.done != nil {
+		return NewRAGErrorWithOp("start_watcher", "watcher already started", ErrorTypeInternal)
+	}
+
+	ctx, w.cancel = context.WithCancel(ctx)
+	w.done = make(chan struct{})
+	go w.watch(ctx, w.done)
+
+	return nil
+}
+
+// Stop stops watching and waits for changes being applied to finish
+func (w *Watcher) Stop() error {
+	w.mu.Lock()
+	cancel, done := w.cancel, w.done
+	w.cancel, w.done = nil, nil
+	w.mu.Unlock()
+
+	if done == nil {
+		return nil
+	}
+
+	cancel()
+	<-done
+	return nil
+}
+
+// watch ingests the roots and polls them until ctx is done
+func (w *Watcher) watch(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	// Take the state before ingesting, so edits made meanwhile are caught
+	states := make(map[string]map[string]watchedFile, len(w.roots))
+	for _, root := range w.roots {
+		states[root], _ = w.poll(ctx, root, nil)
+		report, err := w.ingester.Ingest(ctx, root)
+		if ctx.Err() != nil {
+			return
+		}
+		w.notify(root, report, err)
+	}
+
+	pending := make(map[string]*pendingChanges)
+	ticker := time.NewTicker(w.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for _, root := range w.roots {
+				current, err := w.poll(ctx, root, states[root])
+				if ctx.Err() != nil {
+					return
+				}
+				if err != nil {
+					// Compare again once the root can be read
+					continue
+				}
+				if changes := diffWatched(states[root], current, pending[root]); changes != nil {
+					changes.last = now
+					pending[root] = changes
+				}
+				states[root] = current
+			}
+
+			for _, root := range w.roots {
+				changes := pending[root]
+				if changes == nil || now.Sub(changes.last) < w.config.Debounce {
+					continue
+				}
+				delete(pending, root)
+
+				report, err := w.ingester.Sync(ctx, sortedKeys(changes.changed), sortedKeys(changes.removed))
+				if ctx.Err() != nil {
+					return
+				}
+				w.notify(root, report, err)
+			}
+		}
+	}
+}
+
+// poll returns the size and modification time of the selected files under
+// root by path. Files of previous below a directory that cannot be read are
+// kept as they were, so a passing failure does not look like deletions; an
+// error is returned when root itself cannot be read.
+func (w *Watcher) poll(ctx context.Context, root string, previous map[string]watchedFile) (map[string]watchedFile, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]watchedFile)
+	var unreadable []string
+	err = w.ingester.scan(ctx, root, info, func(name string, info fs.FileInfo) error {
+		files[name] = watchedFile{size: info.Size(), modTime: info.ModTime()}
+		return nil
+	}, func(name string, _ error) {
+		unreadable = append(unreadable, filepath.ToSlash(name))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for name, file := range previous {
+		if _, ok := files[name]; !ok && withinAnyPath(unreadable, filepath.ToSlash(name)) {
+			files[name] = file
+		}
+	}
+
+	return files, nil
+}
+
+// diffWatched adds the differences between two polls to the pending
+// changes, returning nil when there are none. A file deleted and created
+// again within a burst is changed; one created and deleted again is removed,
+// which is harmless when it was never ingested.
+func diffWatched(previous, current map[string]watchedFile, changes *pendingChanges) *pendingChanges {
+	found := false
+	record := func(name string, removed bool) {
+		if changes == nil {
+			changes = &pendingChanges{changed: make(map[string]bool), removed: make(map[string]bool)}
+		}
+		if removed {
+			delete(changes.changed, name)
+			changes.removed[name] = true
+		} else {
+			delete(changes.removed, name)
+			changes.changed[name] = true
+		}
+		found = true
+	}
+
+	for name, file := range current {
+		if old, ok := previous[name]; !ok || old.size != file.size || !old.modTime.Equal(file.modTime) {
+			record(name, false)
+		}
+	}
+	for name := range previous {
+		if _, ok := current[name]; !ok {
+			record(name, true)
+		}
+	}
+
+	if !found {
+		return nil
+	}
+	return changes
+}
+
+// notify calls the callback, if any
+func (w *Watcher) notify(root string, report *IngestReport, err error) {
+	w.mu.RLock()
+	callback := w.callback
+	w.mu.RUnlock()
+
+	if callback != nil {
+		callback(root, report, err)
+	}
+}
+
+// sortedKeys returns the keys of a set in order
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,83 @@
+package rag
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newTestWatcher creates a watcher of root without starting it
+func newTestWatcher(t *testing.T, root string) *Watcher {
+	t.Helper()
+
+	ingester, err := NewIngester(newMemoryRetriever(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	watcher, err := NewWatcher(ingester, []string{root}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return watcher
+}
+
+func TestWatcherPollMissingRoot(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "docs")
+	writeTestFiles(t, root, map[string]string{"a.md": "alpha", "sub/b.md": "beta"})
+	watcher := newTestWatcher(t, root)
+
+	previous, err := watcher.poll(context.Background(), root, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(previous) != 2 {
+		t.Fatalf("polled %d files, want 2", len(previous))
+	}
+
+	// A root that disappears, e.g. an unmounted volume, is not an empty root
+	moved := root + ".moved"
+	if err := os.Rename(root, moved); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := watcher.poll(context.Background(), root, previous); err == nil {
+		t.Fatal("poll of a missing root succeeded")
+	}
+
+	if err := os.Rename(moved, root); err != nil {
+		t.Fatal(err)
+	}
+	current, err := watcher.poll(context.Background(), root, previous)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changes := diffWatched(previous, current, nil); changes != nil {
+		t.Fatalf("root came back with changes %v", changes)
+	}
+}
+
+func TestWatcherPollUnreadableDirectory(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "docs")
+	writeTestFiles(t, root, map[string]string{"a.md": "alpha", "sub/b.md": "beta", "sub/deeper/c.md": "gamma"})
+	watcher := newTestWatcher(t, root)
+
+	previous, err := watcher.poll(context.Background(), root, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	makeUnreadable(t, filepath.Join(root, "sub"))
+	writeTestFiles(t, root, map[string]string{"a.md": "alpha, edited"})
+
+	current, err := watcher.poll(context.Background(), root, previous)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := diffWatched(previous, current, nil)
+	if changes == nil || len(changes.removed) != 0 {
+		t.Fatalf("changes = %+v, want no removals below the unreadable directory", changes)
+	}
+	if edited := filepath.Join(root, "a.md"); !changes.changed[edited] || len(changes.changed) != 1 {
+		t.Fatalf("changed %v, want only %s", changes.changed, edited)
+	}
+}
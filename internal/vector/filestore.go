@@ -0,0 +1,880 @@
+package vector
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"syscall"
+)
+
+// On-disk layout of a FileStore directory:
+//
+//	segment.dat  compacted base image, rewritten atomically on compaction
+//	wal.log      append-only log of changes made since the last compaction
+//
+// Both files start with the same header followed by a sequence of records.
+// Each record is framed as [uint32 length][uint32 crc32c][payload] so that a
+// torn write at the tail of the log can be detected and discarded on replay.
+const (
+	segmentFileName = "segment.dat"
+	logFileName     = "wal.log"
+
+	fileMagic       = "MRVS"
+	fileVersion     = uint16(2) // version 2 appends metadata to put records
+	fileHeaderSize  = 4 + 2 + 4 // magic + version + dimension
+	recordFrameSize = 8         // length + checksum
+
+	opPut    byte = 1
+	opDelete byte = 2
+
+	// minCompactionDead avoids rewriting tiny stores on every delete
+	minCompactionDead = 64
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// FileStore implements Store with durable storage in a segment/log directory.
+// Searches are served by an in-memory index that is rebuilt from disk on open.
+type FileStore struct {
+	mu        sync.Mutex
+	index     Store
+	dir       string
+	dimension int
+	log       *os.File
+	logWriter *bufio.Writer
+	sync      bool
+	ratio     float64
+	live      map[string]struct{}
+	dead      int
+	closed    bool
+}
+
+// NewFileStore opens (or creates) a persistent vector store at config.Path
+func NewFileStore(config *Config) (*FileStore, error) {
+	if config == nil {
+		config = DefaultConfig()
+	}
+
+	index, err := newIndex(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return openFileStore(config, index)
+}
+
+// openFileStore opens the directory at config.Path and replays it into index
+func openFileStore(config *Config, index Store) (*FileStore, error) {
+	if config.Path == "" {
+		return nil, NewVectorErrorWithOp("open_file_store", fmt.Errorf("store path is required for the file backend"))
+	}
+
+	if config.Dimension <= 0 {
+		return nil, NewVectorErrorWithOp("open_file_store", ErrInvalidDimension)
+	}
+
+	if err := os.MkdirAll(config.Path, 0755); err != nil {
+		return nil, NewVectorErrorWithOp("open_file_store", err)
+	}
+
+	s := &FileStore{
+		index:     index,
+		dir:       config.Path,
+		dimension: config.Dimension,
+		sync:      config.SyncWrites,
+		ratio:     config.CompactionRatio,
+		live:      make(map[string]struct{}),
+	}
+
+	if err := s.load(); err != nil {
+		return nil, NewVectorErrorWithOp("open_file_store", err)
+	}
+
+	return s, nil
+}
+
+// RemoveFileStore deletes the files of a closed file store in dir, and dir
+// itself once nothing else is left in it
+func RemoveFileStore(dir string) error {
+	for _, name := range []string{segmentFileName, logFileName} {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil && !os.IsNotExist(err) {
+			return NewVectorErrorWithOp("remove_file_store", err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return NewVectorErrorWithOp("remove_file_store", err)
+	}
+
+	if len(entries) == 0 {
+		if err := os.Remove(dir); err != nil {
+			return NewVectorErrorWithOp("remove_file_store", err)
+		}
+	}
+
+	return nil
+}
+
+// Add adds a document with its vector to the store
+func (s *FileStore) Add(doc Document) error {
+	return s.AddBatch([]Document{doc})
+}
+
+// AddBatch adds multiple documents in a single operation.
+// The batch is written to the log before the in-memory index is updated.
+func (s *FileStore) AddBatch(docs []Document) error {
+	if len(docs) == 0 {
+		return nil
+	}
+
+	for i, doc := range docs {
+		if doc.ID == "" {
+			return NewVectorErrorWithOp("add_batch", fmt.Errorf("document at index %d has empty ID", i))
+		}
+
+		if len(doc.Vector) != s.dimension {
+			return NewVectorErrorWithOp("add_batch", fmt.Errorf("document at index %d has vector dimension %d, expected %d", i, len(doc.Vector), s.dimension))
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return NewVectorErrorWithOp("add_batch", ErrStoreClosed)
+	}
+
+	for _, doc := range docs {
+		if err := s.appendRecord(opPut, encodeDocument(doc)); err != nil {
+			return NewVectorErrorWithOp("add_batch", err)
+		}
+	}
+
+	if err := s.flushLog(); err != nil {
+		return NewVectorErrorWithOp("add_batch", err)
+	}
+
+	if err := s.index.AddBatch(docs); err != nil {
+		return err
+	}
+
+	for _, doc := range docs {
+		if _, exists := s.live[doc.ID]; exists {
+			s.dead++
+		}
+		s.live[doc.ID] = struct{}{}
+	}
+
+	return nil
+}
+
+// Search performs similarity search and returns top-k most similar documents
+func (s *FileStore) Search(queryVector Vector, topK int) (*SearchResult, error) {
+	return s.index.Search(queryVector, topK)
+}
+
+// SearchWithThreshold performs similarity search with a minimum similarity threshold
+func (s *FileStore) SearchWithThreshold(queryVector Vector, topK int, threshold float32) (*SearchResult, error) {
+	return s.index.SearchWithThreshold(queryVector, topK, threshold)
+}
+
+// SearchWithFilter performs similarity search over documents matching filter
+func (s *FileStore) SearchWithFilter(queryVector Vector, topK int, threshold float32, filter Filter) (*SearchResult, error) {
+	return s.index.SearchWithFilter(queryVector, topK, threshold, filter)
+}
+
+// Get retrieves a document by its ID
+func (s *FileStore) Get(id string) (*Document, error) {
+	return s.index.Get(id)
+}
+
+// Delete removes a document from the store and compacts the files once
+// enough dead records have accumulated
+func (s *FileStore) Delete(id string) error {
+	if id == "" {
+		return NewVectorErrorWithOp("delete", fmt.Errorf("document ID cannot be empty"))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return NewVectorErrorWithOp("delete", ErrStoreClosed)
+	}
+
+	if _, exists := s.live[id]; !exists {
+		return NewVectorErrorWithOp("delete", ErrDocumentNotFound)
+	}
+
+	if err := s.appendRecord(opDelete, encodeString(nil, id)); err != nil {
+		return NewVectorErrorWithOp("delete", err)
+	}
+
+	if err := s.flushLog(); err != nil {
+		return NewVectorErrorWithOp("delete", err)
+	}
+
+	if err := s.index.Delete(id); err != nil {
+		return err
+	}
+
+	delete(s.live, id)
+	s.dead += 2 // the put record and the delete record
+
+	if s.needsCompaction() {
+		if err := s.compact(); err != nil {
+			return NewVectorErrorWithOp("compact", err)
+		}
+	}
+
+	return nil
+}
+
+// Size returns the number of documents in the store
+func (s *FileStore) Size() int {
+	return s.index.Size()
+}
+
+// Clear removes all documents from the store and truncates its files
+func (s *FileStore) Clear() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return NewVectorErrorWithOp("clear", ErrStoreClosed)
+	}
+
+	if err := s.index.Clear(); err != nil {
+		return err
+	}
+
+	s.live = make(map[string]struct{})
+	s.dead = 0
+
+	if err := s.rewrite(); err != nil {
+		return NewVectorErrorWithOp("clear", err)
+	}
+
+	return nil
+}
+
+// Close flushes pending writes and releases the log file
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	var errs []error
+	if err := s.flushLog(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := s.log.Close(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := s.index.Close(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if len(errs) > 0 {
+		return NewVectorErrorWithOp("close", errors.Join(errs...))
+	}
+
+	return nil
+}
+
+// Compact rewrites the segment with only live documents and resets the log
+func (s *FileStore) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return NewVectorErrorWithOp("compact", ErrStoreClosed)
+	}
+
+	if err := s.compact(); err != nil {
+		return NewVectorErrorWithOp("compact", err)
+	}
+
+	return nil
+}
+
+// Snapshot writes all live documents to w. Documents are read from the
+// store files rather than the index, so vectors keep full precision even
+// when the index is quantized.
+func (s *FileStore) Snapshot(w io.Writer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return NewVectorErrorWithOp("snapshot", ErrStoreClosed)
+	}
+
+	if err := s.flushLog(); err != nil {
+		return NewVectorErrorWithOp("snapshot", err)
+	}
+
+	latest, err := s.latestRecords()
+	if err != nil {
+		return NewVectorErrorWithOp("snapshot", err)
+	}
+
+	ids := make([]string, 0, len(s.live))
+	for id := range s.live {
+		ids = append(ids, id)
+	}
+
+	get := func(id string) (Document, error) {
+		payload, ok := latest[id]
+		if !ok {
+			return Document{}, fmt.Errorf("%w: no record for live document %q", ErrCorruptData, id)
+		}
+		return decodeDocument(payload)
+	}
+
+	if err := writeSnapshot(w, s.dimension, s.GetMetric(), ids, get); err != nil {
+		return NewVectorErrorWithOp("snapshot", err)
+	}
+
+	return nil
+}
+
+// Restore replaces the contents of the store with a snapshot and compacts
+// the result into a fresh segment
+func (s *FileStore) Restore(r io.Reader) error {
+	if err := restoreSnapshot(s, r); err != nil {
+		return err
+	}
+
+	return s.Compact()
+}
+
+// GetDimension returns the vector dimension of the store
+func (s *FileStore) GetDimension() int {
+	return s.dimension
+}
+
+// GetMetric returns the similarity metric of the underlying index
+func (s *FileStore) GetMetric() Metric {
+	if m, ok := s.index.(interface{ GetMetric() Metric }); ok {
+		return m.GetMetric()
+	}
+	return MetricCosine
+}
+
+// ListIDs returns all document IDs in the store
+func (s *FileStore) ListIDs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make([]string, 0, len(s.live))
+	for id := range s.live {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Path returns the directory backing the store
+func (s *FileStore) Path() string {
+	return s.dir
+}
+
+// load replays the segment and the log into the in-memory index
+func (s *FileStore) load() error {
+	docs := make(map[string]Document)
+	order := make([]string, 0)
+
+	apply := func(op byte, payload []byte) error {
+		switch op {
+		case opPut:
+			doc, err := decodeDocument(payload)
+			if err != nil {
+				return err
+			}
+			if _, exists := docs[doc.ID]; exists {
+				s.dead++
+			} else {
+				order = append(order, doc.ID)
+			}
+			docs[doc.ID] = doc
+		case opDelete:
+			id, _, err := decodeString(payload)
+			if err != nil {
+				return err
+			}
+			if _, exists := docs[id]; exists {
+				delete(docs, id)
+				s.dead++
+			}
+			s.dead++
+		default:
+			return fmt.Errorf("%w: unknown record type %d", ErrCorruptData, op)
+		}
+		return nil
+	}
+
+	segmentPath := filepath.Join(s.dir, segmentFileName)
+	if _, err := s.replayFile(segmentPath, apply, false); err != nil {
+		return err
+	}
+
+	logPath := filepath.Join(s.dir, logFileName)
+	validSize, err := s.replayFile(logPath, apply, true)
+	if err != nil {
+		return err
+	}
+
+	// Open the log for appending, dropping any torn record at its tail
+	log, err := os.OpenFile(logPath, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	if validSize == 0 {
+		if err := log.Truncate(0); err != nil {
+			log.Close()
+			return err
+		}
+		if _, err := log.Write(s.header()); err != nil {
+			log.Close()
+			return err
+		}
+	} else if err := log.Truncate(validSize); err != nil {
+		log.Close()
+		return err
+	}
+
+	s.log = log
+	s.logWriter = bufio.NewWriter(log)
+
+	batch := make([]Document, 0, len(docs))
+	for _, id := range order {
+		if doc, exists := docs[id]; exists {
+			batch = append(batch, doc)
+			s.live[id] = struct{}{}
+		}
+	}
+
+	if err := s.index.AddBatch(batch); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// replayFile feeds every valid record in path to apply and returns the offset
+// just past the last valid record. A missing file yields offset 0. When
+// tolerateTail is set, a truncated or corrupt tail ends replay instead of
+// failing, which is how a crash in the middle of an append is recovered.
+func (s *FileStore) replayFile(path string, apply func(op byte, payload []byte) error, tolerateTail bool) (int64, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	r := bufio.NewReader(f)
+
+	header := make([]byte, fileHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		if tolerateTail {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("%w: %s: short header", ErrCorruptData, filepath.Base(path))
+	}
+
+	if err := s.checkHeader(header); err != nil {
+		return 0, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+
+	offset := int64(fileHeaderSize)
+	frame := make([]byte, recordFrameSize)
+
+	for {
+		if _, err := io.ReadFull(r, frame); err != nil {
+			if err == io.EOF {
+				return offset, nil
+			}
+			if tolerateTail {
+				return offset, nil
+			}
+			return 0, fmt.Errorf("%w: %s: truncated record", ErrCorruptData, filepath.Base(path))
+		}
+
+		length := binary.LittleEndian.Uint32(frame[0:4])
+		checksum := binary.LittleEndian.Uint32(frame[4:8])
+
+		// A length reaching past the end of the file is a torn or garbage
+		// frame; it must not size the allocation below
+		if offset+int64(recordFrameSize)+int64(length) > info.Size() {
+			if tolerateTail {
+				return offset, nil
+			}
+			return 0, fmt.Errorf("%w: %s: truncated record at offset %d", ErrCorruptData, filepath.Base(path), offset)
+		}
+
+		record := make([]byte, length)
+		if _, err := io.ReadFull(r, record); err != nil || length == 0 || crc32.Checksum(record, crcTable) != checksum {
+			if tolerateTail {
+				return offset, nil
+			}
+			return 0, fmt.Errorf("%w: %s: bad record at offset %d", ErrCorruptData, filepath.Base(path), offset)
+		}
+
+		if err := apply(record[0], record[1:]); err != nil {
+			return 0, err
+		}
+
+		offset += int64(recordFrameSize) + int64(length)
+	}
+}
+
+func (s *FileStore) header() []byte {
+	header := make([]byte, fileHeaderSize)
+	copy(header[0:4], fileMagic)
+	binary.LittleEndian.PutUint16(header[4:6], fileVersion)
+	binary.LittleEndian.PutUint32(header[6:10], uint32(s.dimension))
+	return header
+}
+
+func (s *FileStore) checkHeader(header []byte) error {
+	if string(header[0:4]) != fileMagic {
+		return fmt.Errorf("%w: bad magic", ErrCorruptData)
+	}
+
+	if version := binary.LittleEndian.Uint16(header[4:6]); version > fileVersion {
+		return fmt.Errorf("unsupported file version %d", version)
+	}
+
+	if dim := int(binary.LittleEndian.Uint32(header[6:10])); dim != s.dimension {
+		return fmt.Errorf("stored dimension %d does not match configured dimension %d", dim, s.dimension)
+	}
+
+	return nil
+}
+
+func (s *FileStore) appendRecord(op byte, payload []byte) error {
+	return writeRecord(s.logWriter, op, payload)
+}
+
+func (s *FileStore) flushLog() error {
+	if err := s.logWriter.Flush(); err != nil {
+		return err
+	}
+
+	if s.sync {
+		return s.log.Sync()
+	}
+
+	return nil
+}
+
+func (s *FileStore) needsCompaction() bool {
+	if s.ratio <= 0 || s.dead < minCompactionDead {
+		return false
+	}
+
+	return float64(s.dead) >= s.ratio*float64(len(s.live))
+}
+
+// compact writes every live document into a fresh segment and resets the log
+func (s *FileStore) compact() error {
+	if err := s.flushLog(); err != nil {
+		return err
+	}
+
+	if err := s.rewrite(); err != nil {
+		return err
+	}
+
+	s.dead = 0
+	return nil
+}
+
+// latestRecords replays the segment and log and returns the payload of the
+// last put record of every document that was not deleted afterwards. The
+// log must have been flushed.
+func (s *FileStore) latestRecords() (map[string][]byte, error) {
+	latest := make(map[string][]byte, len(s.live))
+	collect := func(op byte, payload []byte) error {
+		id, _, err := decodeString(payload)
+		if err != nil {
+			return err
+		}
+		if op == opPut {
+			latest[id] = payload
+		} else {
+			delete(latest, id)
+		}
+		return nil
+	}
+
+	if _, err := s.replayFile(filepath.Join(s.dir, segmentFileName), collect, false); err != nil {
+		return nil, err
+	}
+	if _, err := s.replayFile(filepath.Join(s.dir, logFileName), collect, true); err != nil {
+		return nil, err
+	}
+
+	return latest, nil
+}
+
+// rewrite replaces the segment with the live documents and starts an empty log.
+// Put records are copied from the current segment and log rather than read
+// back from the index, which may only hold quantized vectors.
+// The segment is renamed into place before the log is reset; if a crash happens
+// in between, replaying the old log over the new segment is idempotent.
+func (s *FileStore) rewrite() error {
+	segmentPath := filepath.Join(s.dir, segmentFileName)
+	logPath := filepath.Join(s.dir, logFileName)
+
+	latest, err := s.latestRecords()
+	if err != nil {
+		return err
+	}
+
+	err = writeFileAtomic(segmentPath, func(w io.Writer) error {
+		bw := bufio.NewWriter(w)
+		if _, err := bw.Write(s.header()); err != nil {
+			return err
+		}
+
+		for id := range s.live {
+			payload, ok := latest[id]
+			if !ok {
+				return fmt.Errorf("%w: no record for live document %q", ErrCorruptData, id)
+			}
+			if err := writeRecord(bw, opPut, payload); err != nil {
+				return err
+			}
+		}
+
+		return bw.Flush()
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(logPath, func(w io.Writer) error {
+		_, err := w.Write(s.header())
+		return err
+	}); err != nil {
+		return err
+	}
+
+	log, err := os.OpenFile(logPath, os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	if s.log != nil {
+		s.log.Close()
+	}
+	s.log = log
+	s.logWriter = bufio.NewWriter(log)
+
+	return nil
+}
+
+// writeFileAtomic writes a file through a temporary sibling, fsyncs it and
+// renames it over path so readers never observe a partially written file
+func writeFileAtomic(path string, write func(w io.Writer) error) error {
+	dir := filepath.Dir(path)
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	cleanup := func(err error) error {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := write(tmp); err != nil {
+		return cleanup(err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return cleanup(err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return syncDir(dir)
+}
+
+// syncDir makes a rename durable by fsyncing the containing directory
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	// Some filesystems do not support fsync on directories
+	if err := d.Sync(); err != nil && !errors.Is(err, syscall.EINVAL) {
+		return err
+	}
+
+	return nil
+}
+
+func writeRecord(w io.Writer, op byte, payload []byte) error {
+	record := make([]byte, recordFrameSize+1+len(payload))
+	body := record[recordFrameSize:]
+	body[0] = op
+	copy(body[1:], payload)
+
+	binary.LittleEndian.PutUint32(record[0:4], uint32(len(body)))
+	binary.LittleEndian.PutUint32(record[4:8], crc32.Checksum(body, crcTable))
+
+	_, err := w.Write(record)
+	return err
+}
+
+// Record payload encoding
+
+func encodeDocument(doc Document) []byte {
+	buf := make([]byte, 0, len(doc.ID)+len(doc.Content)+len(doc.Vector)*4+16)
+	buf = encodeString(buf, doc.ID)
+	buf = encodeString(buf, doc.Content)
+	buf = encodeVector(buf, doc.Vector)
+	buf = encodeMetadata(buf, doc.Metadata)
+	return buf
+}
+
+func decodeDocument(data []byte) (Document, error) {
+	var doc Document
+	var err error
+
+	if doc.ID, data, err = decodeString(data); err != nil {
+		return doc, err
+	}
+	if doc.Content, data, err = decodeString(data); err != nil {
+		return doc, err
+	}
+	if doc.Vector, data, err = decodeVector(data); err != nil {
+		return doc, err
+	}
+
+	// Version 1 records end after the vector
+	if len(data) > 0 {
+		if doc.Metadata, _, err = decodeMetadata(data); err != nil {
+			return doc, err
+		}
+	}
+
+	return doc, nil
+}
+
+func encodeString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+func decodeString(data []byte) (string, []byte, error) {
+	n, size := binary.Uvarint(data)
+	if size <= 0 || uint64(len(data)-size) < n {
+		return "", nil, fmt.Errorf("%w: bad string field", ErrCorruptData)
+	}
+
+	end := size + int(n)
+	return string(data[size:end]), data[end:], nil
+}
+
+func encodeMetadata(buf []byte, metadata map[string]string) []byte {
+	keys := make([]string, 0, len(metadata))
+	for k := range metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	buf = binary.AppendUvarint(buf, uint64(len(keys)))
+	for _, k := range keys {
+		buf = encodeString(buf, k)
+		buf = encodeString(buf, metadata[k])
+	}
+	return buf
+}
+
+func decodeMetadata(data []byte) (map[string]string, []byte, error) {
+	n, size := binary.Uvarint(data)
+	if size <= 0 || n > uint64(len(data)) {
+		return nil, nil, fmt.Errorf("%w: bad metadata field", ErrCorruptData)
+	}
+	data = data[size:]
+
+	if n == 0 {
+		return nil, data, nil
+	}
+
+	metadata := make(map[string]string, n)
+	for i := uint64(0); i < n; i++ {
+		var k, v string
+		var err error
+		if k, data, err = decodeString(data); err != nil {
+			return nil, nil, err
+		}
+		if v, data, err = decodeString(data); err != nil {
+			return nil, nil, err
+		}
+		metadata[k] = v
+	}
+
+	return metadata, data, nil
+}
+
+func encodeVector(buf []byte, v Vector) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(v)))
+	for _, val := range v {
+		buf = binary.LittleEndian.AppendUint32(buf, math.Float32bits(val))
+	}
+	return buf
+}
+
+func decodeVector(data []byte) (Vector, []byte, error) {
+	n, size := binary.Uvarint(data)
+	if size <= 0 || uint64(len(data)-size) < n*4 {
+		return nil, nil, fmt.Errorf("%w: bad vector field", ErrCorruptData)
+	}
+
+	data = data[size:]
+	v := make(Vector, n)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+	}
+
+	return v, data[n*4:], nil
+}
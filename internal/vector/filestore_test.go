@@ -0,0 +1,56 @@
+package vector
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileStoreTornLengthTail(t *testing.T) {
+	config := DefaultConfig()
+	config.Dimension = 4
+	config.Path = t.TempDir()
+
+	store, err := NewFileStore(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Add(Document{ID: "a", Vector: Vector{1, 0, 0, 0}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A frame whose length claims far more than the file holds, as left by
+	// a crash in the middle of an append
+	logPath := filepath.Join(config.Path, logFileName)
+	before, err := os.Stat(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frame := make([]byte, recordFrameSize)
+	binary.LittleEndian.PutUint32(frame[0:4], 0xFFFFFFF0)
+	log, err := os.OpenFile(logPath, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := log.Write(frame); err != nil {
+		t.Fatal(err)
+	}
+	log.Close()
+
+	store, err = NewFileStore(config)
+	if err != nil {
+		t.Fatalf("reopen with torn tail: %v", err)
+	}
+	defer store.Close()
+
+	if _, err := store.Get("a"); err != nil {
+		t.Fatalf("record before the torn tail lost: %v", err)
+	}
+	if after, err := os.Stat(logPath); err != nil || after.Size() != before.Size() {
+		t.Fatalf("torn tail not truncated: size %d, want %d", after.Size(), before.Size())
+	}
+}
@@ -0,0 +1,548 @@
+package vector
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Filter is a predicate over document metadata evaluated during search.
+// Stores apply filters before ranking, so a filtered query still returns
+// up to top-k matching documents.
+type Filter interface {
+	// Match reports whether a document with the given metadata passes the filter
+	Match(metadata map[string]string) bool
+
+	// String returns the filter in expression syntax
+	String() string
+}
+
+// CompareOp is a comparison operator used by field conditions
+type CompareOp string
+
+const (
+	OpEq  CompareOp = "="
+	OpNe  CompareOp = "!="
+	OpGt  CompareOp = ">"
+	OpGte CompareOp = ">="
+	OpLt  CompareOp = "<"
+	OpLte CompareOp = "<="
+)
+
+// Eq matches documents whose metadata key equals value
+func Eq(key, value string) Filter {
+	return &compareFilter{key: key, op: OpEq, value: value}
+}
+
+// Compare matches documents whose metadata key compares to value with op.
+// Ordering comparisons treat both sides as timestamps when both parse as one,
+// then as numbers, and fall back to string ordering otherwise.
+func Compare(key string, op CompareOp, value string) Filter {
+	return &compareFilter{key: key, op: op, value: value}
+}
+
+// In matches documents whose metadata key equals any of values
+func In(key string, values ...string) Filter {
+	set := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		set[v] = struct{}{}
+	}
+	return &inFilter{key: key, values: values, set: set}
+}
+
+// TimeRange matches documents whose metadata key holds a timestamp within
+// [from, to]. A zero bound leaves that side open.
+func TimeRange(key string, from, to time.Time) Filter {
+	var parts []Filter
+	if !from.IsZero() {
+		parts = append(parts, Compare(key, OpGte, from.Format(time.RFC3339Nano)))
+	}
+	if !to.IsZero() {
+		parts = append(parts, Compare(key, OpLte, to.Format(time.RFC3339Nano)))
+	}
+	if len(parts) == 0 {
+		return Exists(key)
+	}
+	return And(parts...)
+}
+
+// Exists matches documents that carry the metadata key
+func Exists(key string) Filter {
+	return &existsFilter{key: key}
+}
+
+// And matches documents that pass every filter
+func And(filters ...Filter) Filter {
+	if len(filters) == 1 {
+		return filters[0]
+	}
+	return &logicalFilter{and: true, filters: filters}
+}
+
+// Or matches documents that pass at least one filter
+func Or(filters ...Filter) Filter {
+	if len(filters) == 1 {
+		return filters[0]
+	}
+	return &logicalFilter{and: false, filters: filters}
+}
+
+// Not inverts a filter
+func Not(filter Filter) Filter {
+	return &notFilter{filter: filter}
+}
+
+// MatchFilter evaluates an optional filter; a nil filter matches everything
+func MatchFilter(filter Filter, metadata map[string]string) bool {
+	return filter == nil || filter.Match(metadata)
+}
+
+type compareFilter struct {
+	key   string
+	op    CompareOp
+	value string
+}
+
+func (f *compareFilter) Match(metadata map[string]string) bool {
+	actual, ok := metadata[f.key]
+	if !ok {
+		return f.op == OpNe
+	}
+
+	switch f.op {
+	case OpEq:
+		return actual == f.value
+	case OpNe:
+		return actual != f.value
+	}
+
+	cmp := compareValues(actual, f.value)
+	switch f.op {
+	case OpGt:
+		return cmp > 0
+	case OpGte:
+		return cmp >= 0
+	case OpLt:
+		return cmp < 0
+	case OpLte:
+		return cmp <= 0
+	default:
+		return false
+	}
+}
+
+func (f *compareFilter) String() string {
+	return fmt.Sprintf("%s %s %s", quoteFilterKey(f.key), f.op, strconv.Quote(f.value))
+}
+
+type inFilter struct {
+	key    string
+	values []string
+	set    map[string]struct{}
+}
+
+func (f *inFilter) Match(metadata map[string]string) bool {
+	actual, ok := metadata[f.key]
+	if !ok {
+		return false
+	}
+	_, found := f.set[actual]
+	return found
+}
+
+func (f *inFilter) String() string {
+	quoted := make([]string, len(f.values))
+	for i, v := range f.values {
+		quoted[i] = strconv.Quote(v)
+	}
+	return fmt.Sprintf("%s IN (%s)", quoteFilterKey(f.key), strings.Join(quoted, ", "))
+}
+
+type existsFilter struct {
+	key string
+}
+
+func (f *existsFilter) Match(metadata map[string]string) bool {
+	_, ok := metadata[f.key]
+	return ok
+}
+
+func (f *existsFilter) String() string {
+	return fmt.Sprintf("EXISTS %s", quoteFilterKey(f.key))
+}
+
+type logicalFilter struct {
+	and     bool
+	filters []Filter
+}
+
+func (f *logicalFilter) Match(metadata map[string]string) bool {
+	for _, sub := range f.filters {
+		if sub.Match(metadata) != f.and {
+			return !f.and
+		}
+	}
+	return f.and
+}
+
+func (f *logicalFilter) String() string {
+	op := " OR "
+	if f.and {
+		op = " AND "
+	}
+
+	parts := make([]string, len(f.filters))
+	for i, sub := range f.filters {
+		parts[i] = sub.String()
+	}
+	return "(" + strings.Join(parts, op) + ")"
+}
+
+type notFilter struct {
+	filter Filter
+}
+
+func (f *notFilter) Match(metadata map[string]string) bool {
+	return !f.filter.Match(metadata)
+}
+
+func (f *notFilter) String() string {
+	return "NOT " + f.filter.String()
+}
+
+// quoteFilterKey quotes a metadata key that would not parse back as a bare
+// word
+func quoteFilterKey(key string) string {
+	if key == "" || strings.IndexFunc(key, func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune("()=!<>,\"\\", r)
+	}) >= 0 {
+		return strconv.Quote(key)
+	}
+	return key
+}
+
+// timeLayouts are the timestamp formats recognised in metadata values
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+func parseTimestamp(s string) (time.Time, bool) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// compareValues orders two metadata values as timestamps, numbers or strings
+func compareValues(a, b string) int {
+	if ta, ok := parseTimestamp(a); ok {
+		if tb, ok := parseTimestamp(b); ok {
+			return ta.Compare(tb)
+		}
+	}
+
+	if fa, err := strconv.ParseFloat(a, 64); err == nil {
+		if fb, err := strconv.ParseFloat(b, 64); err == nil {
+			switch {
+			case fa < fb:
+				return -1
+			case fa > fb:
+				return 1
+			default:
+				return 0
+			}
+		}
+	}
+
+	return strings.Compare(a, b)
+}
+
+// ParseFilter parses a filter expression such as
+//
+//	project = "mcprag" AND lang IN ("go", "ts") AND NOT source = "vendor"
+//	created_at >= "2024-01-01" AND (kind = "func" OR kind = "method")
+//	updated_at BETWEEN "2024-01-01" AND "2024-06-30"
+//
+// Keywords are case-insensitive. Values may be quoted with double quotes or
+// written bare when they contain no spaces or operator characters.
+// An empty expression yields a nil filter.
+func ParseFilter(expr string) (Filter, error) {
+	tokens, err := tokenizeFilter(expr)
+	if err != nil {
+		return nil, NewVectorErrorWithOp("parse_filter", err)
+	}
+
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+
+	p := &filterParser{tokens: tokens}
+	filter, err := p.parseOr()
+	if err != nil {
+		return nil, NewVectorErrorWithOp("parse_filter", err)
+	}
+
+	if !p.done() {
+		return nil, NewVectorErrorWithOp("parse_filter", fmt.Errorf("unexpected %q at position %d", p.peek().text, p.peek().pos))
+	}
+
+	return filter, nil
+}
+
+type filterTokenKind int
+
+const (
+	tokWord filterTokenKind = iota
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+type filterToken struct {
+	kind filterTokenKind
+	text string
+	pos  int
+}
+
+func tokenizeFilter(expr string) ([]filterToken, error) {
+	var tokens []filterToken
+	runes := []rune(expr)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, filterToken{kind: tokLParen, text: "(", pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, filterToken{kind: tokRParen, text: ")", pos: i})
+			i++
+		case r == ',':
+			tokens = append(tokens, filterToken{kind: tokComma, text: ",", pos: i})
+			i++
+		case r == '"':
+			start := i
+			var sb strings.Builder
+			i++
+			for i < len(runes) && runes[i] != '"' {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				sb.WriteRune(runes[i])
+				i++
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated string at position %d", start)
+			}
+			i++
+			tokens = append(tokens, filterToken{kind: tokString, text: sb.String(), pos: start})
+		case strings.ContainsRune("=!<>", r):
+			start := i
+			op := string(r)
+			if i+1 < len(runes) && runes[i+1] == '=' {
+				op += "="
+				i++
+			}
+			i++
+			if op == "!" {
+				return nil, fmt.Errorf("unexpected '!' at position %d", start)
+			}
+			if op == "==" {
+				op = "="
+			}
+			tokens = append(tokens, filterToken{kind: tokOp, text: op, pos: start})
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune("()=!<>,\"", runes[i]) {
+				i++
+			}
+			tokens = append(tokens, filterToken{kind: tokWord, text: string(runes[start:i]), pos: start})
+		}
+	}
+
+	return tokens, nil
+}
+
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+}
+
+func (p *filterParser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *filterParser) peek() filterToken {
+	if p.done() {
+		return filterToken{kind: -1, text: "end of expression", pos: -1}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *filterParser) next() filterToken {
+	tok := p.peek()
+	p.pos++
+	return tok
+}
+
+func (p *filterParser) keyword(word string) bool {
+	tok := p.peek()
+	if tok.kind == tokWord && strings.EqualFold(tok.text, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *filterParser) parseOr() (Filter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	filters := []Filter{left}
+	for p.keyword("OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, right)
+	}
+
+	return Or(filters...), nil
+}
+
+func (p *filterParser) parseAnd() (Filter, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	filters := []Filter{left}
+	for p.keyword("AND") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, right)
+	}
+
+	return And(filters...), nil
+}
+
+func (p *filterParser) parseUnary() (Filter, error) {
+	if p.keyword("NOT") {
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not(inner), nil
+	}
+
+	if p.keyword("EXISTS") {
+		key := p.next()
+		if key.kind != tokWord && key.kind != tokString {
+			return nil, fmt.Errorf("expected field name after EXISTS at position %d", key.pos)
+		}
+		return Exists(key.text), nil
+	}
+
+	if p.peek().kind == tokLParen {
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if tok := p.next(); tok.kind != tokRParen {
+			return nil, fmt.Errorf("expected ')' at position %d", tok.pos)
+		}
+		return inner, nil
+	}
+
+	return p.parseCondition()
+}
+
+func (p *filterParser) parseCondition() (Filter, error) {
+	key := p.next()
+	if key.kind != tokWord && key.kind != tokString {
+		return nil, fmt.Errorf("expected field name, got %q at position %d", key.text, key.pos)
+	}
+
+	if p.keyword("IN") {
+		values, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		return In(key.text, values...), nil
+	}
+
+	if p.keyword("BETWEEN") {
+		low, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		if !p.keyword("AND") {
+			return nil, fmt.Errorf("expected AND in BETWEEN at position %d", p.peek().pos)
+		}
+		high, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		return And(Compare(key.text, OpGte, low), Compare(key.text, OpLte, high)), nil
+	}
+
+	op := p.next()
+	if op.kind != tokOp {
+		return nil, fmt.Errorf("expected operator after %q, got %q", key.text, op.text)
+	}
+
+	value, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+
+	return Compare(key.text, CompareOp(op.text), value), nil
+}
+
+func (p *filterParser) parseList() ([]string, error) {
+	if tok := p.next(); tok.kind != tokLParen {
+		return nil, fmt.Errorf("expected '(' after IN at position %d", tok.pos)
+	}
+
+	var values []string
+	for {
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+
+		tok := p.next()
+		if tok.kind == tokRParen {
+			return values, nil
+		}
+		if tok.kind != tokComma {
+			return nil, fmt.Errorf("expected ',' or ')' in IN list at position %d", tok.pos)
+		}
+	}
+}
+
+func (p *filterParser) parseValue() (string, error) {
+	tok := p.next()
+	if tok.kind != tokWord && tok.kind != tokString {
+		return "", fmt.Errorf("expected value, got %q", tok.text)
+	}
+	return tok.text, nil
+}
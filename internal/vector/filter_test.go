@@ -0,0 +1,261 @@
+package vector
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		expr     string
+		match    []map[string]string
+		mismatch []map[string]string
+	}{
+		{
+			expr:     `project = "mcprag"`,
+			match:    []map[string]string{{"project": "mcprag"}},
+			mismatch: []map[string]string{{"project": "other"}, {}},
+		},
+		{
+			expr:     `project == mcprag`,
+			match:    []map[string]string{{"project": "mcprag"}},
+			mismatch: []map[string]string{{"project": "MCPRAG"}},
+		},
+		{
+			expr:     `lang != "go"`,
+			match:    []map[string]string{{"lang": "ts"}, {}},
+			mismatch: []map[string]string{{"lang": "go"}},
+		},
+		{
+			expr:     `lang IN ("go", "ts")`,
+			match:    []map[string]string{{"lang": "go"}, {"lang": "ts"}},
+			mismatch: []map[string]string{{"lang": "py"}, {}},
+		},
+		{
+			expr:     `project = "mcprag" AND lang in (go, ts) and not source = "vendor"`,
+			match:    []map[string]string{{"project": "mcprag", "lang": "go", "source": "src"}},
+			mismatch: []map[string]string{{"project": "mcprag", "lang": "go", "source": "vendor"}, {"project": "mcprag", "lang": "py"}},
+		},
+		{
+			expr:     `kind = "func" OR kind = "method" AND exported = "true"`,
+			match:    []map[string]string{{"kind": "func"}, {"kind": "method", "exported": "true"}},
+			mismatch: []map[string]string{{"kind": "method"}, {"kind": "type", "exported": "true"}},
+		},
+		{
+			expr:     `(kind = "func" OR kind = "method") AND exported = "true"`,
+			match:    []map[string]string{{"kind": "method", "exported": "true"}},
+			mismatch: []map[string]string{{"kind": "func"}},
+		},
+		{
+			expr:     `NOT (a = 1 OR b = 2)`,
+			match:    []map[string]string{{"a": "2", "b": "1"}, {}},
+			mismatch: []map[string]string{{"a": "1"}, {"b": "2"}},
+		},
+		{
+			expr:     `EXISTS author AND NOT EXISTS draft`,
+			match:    []map[string]string{{"author": ""}},
+			mismatch: []map[string]string{{"author": "x", "draft": "yes"}, {}},
+		},
+		{
+			expr:     `created_at >= "2024-01-01" AND created_at < "2024-07-01T00:00:00Z"`,
+			match:    []map[string]string{{"created_at": "2024-01-01"}, {"created_at": "2024-06-30T23:59:59+02:00"}, {"created_at": "2024-03-01 12:00:00"}},
+			mismatch: []map[string]string{{"created_at": "2023-12-31T23:59:59Z"}, {"created_at": "2024-07-01"}, {}},
+		},
+		{
+			expr:     `updated_at BETWEEN "2024-01-01" AND "2024-06-30"`,
+			match:    []map[string]string{{"updated_at": "2024-01-01"}, {"updated_at": "2024-06-30"}},
+			mismatch: []map[string]string{{"updated_at": "2024-06-30T00:00:01Z"}, {"updated_at": "2023-12-31"}},
+		},
+		{
+			// Numbers compare numerically, other values as strings
+			expr:     `size > 9 AND name <= "m"`,
+			match:    []map[string]string{{"size": "10", "name": "a"}},
+			mismatch: []map[string]string{{"size": "9", "name": "a"}, {"size": "10", "name": "z"}},
+		},
+		{
+			expr:     `"field with space" = "a \"quoted\" value" AND EXISTS "x=y"`,
+			match:    []map[string]string{{"field with space": `a "quoted" value`, "x=y": ""}},
+			mismatch: []map[string]string{{"field with space": `a "quoted" value`}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			filter, err := ParseFilter(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, metadata := range tt.match {
+				if !filter.Match(metadata) {
+					t.Errorf("%s does not match %v", filter, metadata)
+				}
+			}
+			for _, metadata := range tt.mismatch {
+				if filter.Match(metadata) {
+					t.Errorf("%s matches %v", filter, metadata)
+				}
+			}
+
+			// The string form parses back to an equivalent filter
+			again, err := ParseFilter(filter.String())
+			if err != nil {
+				t.Fatalf("reparse %s: %v", filter, err)
+			}
+			if again.String() != filter.String() {
+				t.Errorf("reparsed %s as %s", filter, again)
+			}
+		})
+	}
+}
+
+func TestParseFilterEmpty(t *testing.T) {
+	for _, expr := range []string{"", "   "} {
+		filter, err := ParseFilter(expr)
+		if filter != nil || err != nil {
+			t.Errorf("ParseFilter(%q) = %v, %v, want no filter", expr, filter, err)
+		}
+	}
+	if !MatchFilter(nil, map[string]string{"a": "b"}) {
+		t.Error("nil filter does not match")
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	for _, expr := range []string{
+		`a = "unterminated`,
+		`a ! b`,
+		`a b`,
+		`a =`,
+		`= b`,
+		`(a = b`,
+		`a = b)`,
+		`a = b AND`,
+		`a = b OR OR c = d`,
+		`NOT`,
+		`EXISTS`,
+		`EXISTS =`,
+		`a IN "x"`,
+		`a IN ("x" "y")`,
+		`a IN ("x",)`,
+		`a IN ()`,
+		`a BETWEEN 1 OR 2`,
+		`a BETWEEN 1 AND`,
+		`a = b c = d`,
+	} {
+		filter, err := ParseFilter(expr)
+		var vectorErr *VectorError
+		if !errors.As(err, &vectorErr) {
+			t.Errorf("ParseFilter(%q) = %v, %v, want a parse error", expr, filter, err)
+		}
+	}
+}
+
+func TestTimeRange(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		filter Filter
+		value  string
+		want   bool
+	}{
+		{"inside", TimeRange("t", from, to), "2024-03-01T10:00:00Z", true},
+		{"lower bound", TimeRange("t", from, to), "2024-01-01", true},
+		{"upper bound", TimeRange("t", from, to), "2024-06-30T00:00:00Z", true},
+		{"before", TimeRange("t", from, to), "2023-12-31T23:59:59Z", false},
+		{"after", TimeRange("t", from, to), "2024-06-30T00:00:00.001Z", false},
+		{"other zone", TimeRange("t", from, to), "2024-01-01T01:00:00+02:00", false},
+		{"open start", TimeRange("t", time.Time{}, to), "1999-01-01", true},
+		{"open end", TimeRange("t", from, time.Time{}), "2099-01-01", true},
+		{"open both", TimeRange("t", time.Time{}, time.Time{}), "anything", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Match(map[string]string{"t": tt.value}); got != tt.want {
+				t.Errorf("%s on %q = %v, want %v", tt.filter, tt.value, got, tt.want)
+			}
+		})
+	}
+
+	if TimeRange("t", from, to).Match(map[string]string{}) {
+		t.Error("time range matches a document without the key")
+	}
+}
+
+// filterSearchStores returns a MemoryStore and an HNSWStore holding the same
+// documents; every document has a "bucket" of i % buckets
+func filterSearchStores(t *testing.T, documents, buckets int) (Vector, []Store) {
+	t.Helper()
+
+	config := DefaultConfig()
+	config.Dimension = 32
+
+	memory, err := NewMemoryStore(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hnsw, err := NewHNSWStore(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	docs := make([]Document, documents)
+	for i := range docs {
+		docs[i] = Document{
+			ID:       fmt.Sprintf("doc-%d", i),
+			Vector:   randomVector(rng, config.Dimension, nil, 1.0),
+			Metadata: map[string]string{"bucket": fmt.Sprint(i % buckets)},
+		}
+	}
+	for _, store := range []Store{memory, hnsw} {
+		if err := store.AddBatch(docs); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return randomVector(rng, config.Dimension, nil, 1.0), []Store{memory, hnsw}
+}
+
+func TestSearchWithSelectiveFilter(t *testing.T) {
+	const documents, buckets = 3000, 100
+	query, stores := filterSearchStores(t, documents, buckets)
+
+	tests := []struct {
+		name   string
+		filter Filter
+		topK   int
+		want   int
+	}{
+		// 30 matches, answered by scoring the matching documents
+		{"selective", Eq("bucket", "7"), 10, 10},
+		{"fewer matches than top-k", Eq("bucket", "7"), 50, documents / buckets},
+		// 1500 matches, answered by traversing the graph
+		{"broad", Compare("bucket", OpLt, "50"), 100, 100},
+		{"no matches", Eq("bucket", "none"), 10, 0},
+	}
+
+	for _, store := range stores {
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("%T/%s", store, tt.name), func(t *testing.T) {
+				result, err := store.SearchWithFilter(query, tt.topK, NoThreshold, tt.filter)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(result.Documents) != tt.want {
+					t.Fatalf("got %d documents, want %d", len(result.Documents), tt.want)
+				}
+				for _, doc := range result.Documents {
+					if !tt.filter.Match(doc.Metadata) {
+						t.Fatalf("document %s with %v does not match %s", doc.ID, doc.Metadata, tt.filter)
+					}
+				}
+			})
+		}
+	}
+}
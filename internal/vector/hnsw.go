@@ -0,0 +1,685 @@
+package vector
+
+import (
+	"container/heap"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HNSWConfig tunes the hierarchical navigable small world graph
+type HNSWConfig struct {
+	M              int   `yaml:"m" json:"m"`                             // links per node on upper layers
+	EfConstruction int   `yaml:"ef_construction" json:"ef_construction"` // candidate list size while inserting
+	EfSearch       int   `yaml:"ef_search" json:"ef_search"`             // candidate list size while searching
+	Seed           int64 `yaml:"seed" json:"seed"`                       // level generator seed, for reproducible graphs
+}
+
+// DefaultHNSWConfig returns a configuration that favours recall over build speed
+func DefaultHNSWConfig() HNSWConfig {
+	return HNSWConfig{
+		M:              16,
+		EfConstruction: 200,
+		EfSearch:       64,
+		Seed:           42,
+	}
+}
+
+// hnswNode is a single vector in the graph. Deleted nodes stay in the graph as
+// tombstones so that paths through them remain navigable until the next rebuild.
+type hnswNode struct {
+	doc       Document
+	vector    Vector // prepared copy used for scoring
+	neighbors [][]int32
+	deleted   bool
+}
+
+// HNSWStore implements Store with an approximate nearest-neighbour graph index
+type HNSWStore struct {
+	mu         sync.RWMutex
+	config     HNSWConfig
+	dimension  int
+	nodes      []*hnswNode
+	ids        map[string]int32
+	entryPoint int32
+	maxLevel   int
+	levelMult  float64
+	rng        *rand.Rand
+	scorer     *scorer
+	tombstones int
+	visited    sync.Pool
+}
+
+// NewHNSWStore creates a new HNSW-indexed vector store
+func NewHNSWStore(config *Config) (*HNSWStore, error) {
+	if config == nil {
+		config = DefaultConfig()
+	}
+
+	if config.Dimension <= 0 {
+		return nil, NewVectorErrorWithOp("new_store", ErrInvalidDimension)
+	}
+
+	scorer, err := newScorer(config)
+	if err != nil {
+		return nil, NewVectorErrorWithOp("new_store", err)
+	}
+
+	if config.Quantization.enabled() {
+		return nil, NewVectorErrorWithOp("new_store", fmt.Errorf("quantization is only supported by the flat index"))
+	}
+
+	hnswConfig := config.HNSW
+	defaults := DefaultHNSWConfig()
+	if hnswConfig.M <= 1 {
+		hnswConfig.M = defaults.M
+	}
+	if hnswConfig.EfConstruction <= 0 {
+		hnswConfig.EfConstruction = defaults.EfConstruction
+	}
+	if hnswConfig.EfSearch <= 0 {
+		hnswConfig.EfSearch = defaults.EfSearch
+	}
+
+	s := &HNSWStore{
+		config:    hnswConfig,
+		dimension: config.Dimension,
+		levelMult: 1 / math.Log(float64(hnswConfig.M)),
+		rng:       rand.New(rand.NewSource(hnswConfig.Seed)),
+		scorer:    scorer,
+	}
+	s.reset()
+
+	return s, nil
+}
+
+// Add adds a document with its vector to the store
+func (s *HNSWStore) Add(doc Document) error {
+	return s.AddBatch([]Document{doc})
+}
+
+// AddBatch adds multiple documents in a single operation.
+// Re-adding an existing ID tombstones the old node and inserts a new one;
+// as with Delete, the graph is rebuilt once tombstones outnumber live nodes.
+func (s *HNSWStore) AddBatch(docs []Document) error {
+	if len(docs) == 0 {
+		return nil
+	}
+
+	for i, doc := range docs {
+		if doc.ID == "" {
+			return NewVectorErrorWithOp("add_batch", fmt.Errorf("document at index %d has empty ID", i))
+		}
+
+		if len(doc.Vector) != s.dimension {
+			return NewVectorErrorWithOp("add_batch", fmt.Errorf("document at index %d has vector dimension %d, expected %d", i, len(doc.Vector), s.dimension))
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, doc := range docs {
+		if old, exists := s.ids[doc.ID]; exists {
+			s.nodes[old].deleted = true
+			s.tombstones++
+		}
+		s.insert(doc)
+	}
+
+	s.compactTombstones()
+
+	return nil
+}
+
+// Search performs approximate similarity search and returns top-k documents
+func (s *HNSWStore) Search(queryVector Vector, topK int) (*SearchResult, error) {
+	return s.SearchWithThreshold(queryVector, topK, s.scorer.metric.DefaultThreshold())
+}
+
+// SearchWithThreshold performs approximate similarity search with a minimum similarity threshold
+func (s *HNSWStore) SearchWithThreshold(queryVector Vector, topK int, threshold float32) (*SearchResult, error) {
+	return s.SearchWithFilter(queryVector, topK, threshold, nil)
+}
+
+// SearchWithFilter performs approximate similarity search over documents
+// matching filter. Selective filters are answered by scoring the matching
+// nodes directly; broad ones traverse the graph and skip non-matching nodes,
+// widening the beam until topK matches are found.
+func (s *HNSWStore) SearchWithFilter(queryVector Vector, topK int, threshold float32, filter Filter) (*SearchResult, error) {
+	if topK <= 0 {
+		return nil, NewVectorErrorWithOp("search", ErrInvalidTopK)
+	}
+
+	if err := s.scorer.metric.ValidateThreshold(threshold); err != nil {
+		return nil, NewVectorErrorWithOp("search", err)
+	}
+
+	if len(queryVector) != s.dimension {
+		return nil, NewVectorErrorWithOp("search", fmt.Errorf("query vector dimension %d does not match store dimension %d", len(queryVector), s.dimension))
+	}
+
+	start := time.Now()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	results := make([]Document, 0, topK)
+	if len(s.ids) == 0 {
+		return &SearchResult{
+			Documents: results,
+			QueryTime: time.Since(start).Milliseconds(),
+		}, nil
+	}
+
+	query := s.scorer.prepare(queryVector)
+
+	ef := s.config.EfSearch
+	if ef < topK {
+		ef = topK
+	}
+
+	accept := func(node *hnswNode) bool {
+		return !node.deleted && MatchFilter(filter, node.doc.Metadata)
+	}
+
+	var candidates []hnswCandidate
+	if filter != nil {
+		matching := make([]int32, 0)
+		for _, idx := range s.ids {
+			if filter.Match(s.nodes[idx].doc.Metadata) {
+				matching = append(matching, idx)
+			}
+		}
+
+		// Graph traversal degrades when few nodes pass the filter, and
+		// scoring a small matching set directly is cheaper anyway
+		if len(matching) <= ef*4 || len(matching)*10 <= len(s.ids) {
+			candidates = make([]hnswCandidate, len(matching))
+			for i, idx := range matching {
+				candidates[i] = hnswCandidate{node: idx, score: s.score(query, s.nodes[idx].vector)}
+			}
+			sort.Slice(candidates, func(i, j int) bool {
+				if candidates[i].score != candidates[j].score {
+					return candidates[i].score > candidates[j].score
+				}
+				return candidates[i].node < candidates[j].node
+			})
+		}
+	}
+
+	if candidates == nil {
+		// Tombstones and filtered-out nodes occupy slots in the candidate list,
+		// so widen the beam until enough accepted nodes are found or the whole
+		// graph has been seen
+		for {
+			candidates = s.search(query, ef)
+
+			accepted := 0
+			for _, c := range candidates {
+				if accept(s.nodes[c.node]) {
+					accepted++
+				}
+			}
+
+			if accepted >= topK || ef >= len(s.nodes) {
+				break
+			}
+			ef *= 2
+		}
+	}
+
+	for _, c := range candidates {
+		if len(results) == topK {
+			break
+		}
+
+		node := s.nodes[c.node]
+		if !accept(node) || c.score < threshold {
+			continue
+		}
+
+		doc := node.doc
+		doc.Score = c.score
+		results = append(results, doc)
+	}
+
+	return &SearchResult{
+		Documents: results,
+		QueryTime: time.Since(start).Milliseconds(),
+	}, nil
+}
+
+// Get retrieves a document by its ID
+func (s *HNSWStore) Get(id string) (*Document, error) {
+	if id == "" {
+		return nil, NewVectorErrorWithOp("get", fmt.Errorf("document ID cannot be empty"))
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	idx, exists := s.ids[id]
+	if !exists {
+		return nil, NewVectorErrorWithOp("get", ErrDocumentNotFound)
+	}
+
+	doc := s.nodes[idx].doc
+	return &doc, nil
+}
+
+// Delete tombstones a document. The graph is rebuilt once tombstones
+// outnumber live nodes.
+func (s *HNSWStore) Delete(id string) error {
+	if id == "" {
+		return NewVectorErrorWithOp("delete", fmt.Errorf("document ID cannot be empty"))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx, exists := s.ids[id]
+	if !exists {
+		return NewVectorErrorWithOp("delete", ErrDocumentNotFound)
+	}
+
+	s.nodes[idx].deleted = true
+	delete(s.ids, id)
+	s.tombstones++
+
+	s.compactTombstones()
+
+	return nil
+}
+
+// compactTombstones rebuilds the graph once tombstones outnumber live nodes
+func (s *HNSWStore) compactTombstones() {
+	if s.tombstones > len(s.ids) && s.tombstones >= minCompactionDead {
+		s.rebuild()
+	}
+}
+
+// Size returns the number of live documents in the store
+func (s *HNSWStore) Size() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.ids)
+}
+
+// Clear removes all documents from the store
+func (s *HNSWStore) Clear() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reset()
+	return nil
+}
+
+// Close releases any resources held by the store
+func (s *HNSWStore) Close() error {
+	return s.Clear()
+}
+
+// GetDimension returns the vector dimension of the store
+func (s *HNSWStore) GetDimension() int {
+	return s.dimension
+}
+
+// GetMetric returns the similarity metric the store ranks by
+func (s *HNSWStore) GetMetric() Metric {
+	return s.scorer.metric
+}
+
+// ListIDs returns all live document IDs in the store
+func (s *HNSWStore) ListIDs() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ids := make([]string, 0, len(s.ids))
+	for _, node := range s.nodes {
+		if !node.deleted {
+			ids = append(ids, node.doc.ID)
+		}
+	}
+	return ids
+}
+
+// Snapshot writes a consistent copy of all live documents to w
+func (s *HNSWStore) Snapshot(w io.Writer) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ids := make([]string, 0, len(s.ids))
+	for id := range s.ids {
+		ids = append(ids, id)
+	}
+
+	get := func(id string) (Document, error) {
+		return s.nodes[s.ids[id]].doc, nil
+	}
+
+	if err := writeSnapshot(w, s.dimension, s.scorer.metric, ids, get); err != nil {
+		return NewVectorErrorWithOp("snapshot", err)
+	}
+
+	return nil
+}
+
+// Restore replaces the contents of the store with a snapshot
+func (s *HNSWStore) Restore(r io.Reader) error {
+	return restoreSnapshot(s, r)
+}
+
+// Rebuild reconstructs the graph from live nodes, dropping all tombstones
+func (s *HNSWStore) Rebuild() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rebuild()
+}
+
+// GetStats returns statistics about the store
+func (s *HNSWStore) GetStats() StoreStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	links := 0
+	for _, node := range s.nodes {
+		for _, layer := range node.neighbors {
+			links += len(layer)
+		}
+	}
+
+	// documents hold the original vector, nodes a prepared copy
+	memoryUsage := len(s.nodes)*(32+s.dimension*8) + links*4
+
+	return StoreStats{
+		DocumentCount: len(s.ids),
+		Dimension:     s.dimension,
+		Metric:        s.scorer.metric,
+		MemoryUsage:   memoryUsage,
+	}
+}
+
+// Graph construction and traversal
+
+type hnswCandidate struct {
+	node  int32
+	score float32
+}
+
+// bestFirst pops the highest scoring candidate first
+type bestFirst []hnswCandidate
+
+func (h bestFirst) Len() int            { return len(h) }
+func (h bestFirst) Less(i, j int) bool  { return h[i].score > h[j].score }
+func (h bestFirst) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *bestFirst) Push(x interface{}) { *h = append(*h, x.(hnswCandidate)) }
+func (h *bestFirst) Pop() interface{} {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
+
+// worstFirst pops the lowest scoring candidate first
+type worstFirst []hnswCandidate
+
+func (h worstFirst) Len() int            { return len(h) }
+func (h worstFirst) Less(i, j int) bool  { return h[i].score < h[j].score }
+func (h worstFirst) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *worstFirst) Push(x interface{}) { *h = append(*h, x.(hnswCandidate)) }
+func (h *worstFirst) Pop() interface{} {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
+
+// visitedSet marks nodes seen during one traversal; bumping the epoch
+// clears it without touching the backing slice
+type visitedSet struct {
+	marks []uint32
+	epoch uint32
+}
+
+func (v *visitedSet) reset(size int) {
+	if len(v.marks) < size {
+		v.marks = make([]uint32, size)
+		v.epoch = 0
+	}
+
+	v.epoch++
+	if v.epoch == 0 {
+		for i := range v.marks {
+			v.marks[i] = 0
+		}
+		v.epoch = 1
+	}
+}
+
+func (v *visitedSet) visit(node int32) bool {
+	if v.marks[node] == v.epoch {
+		return false
+	}
+	v.marks[node] = v.epoch
+	return true
+}
+
+func (s *HNSWStore) reset() {
+	s.nodes = make([]*hnswNode, 0)
+	s.ids = make(map[string]int32)
+	s.entryPoint = -1
+	s.maxLevel = -1
+	s.tombstones = 0
+}
+
+func (s *HNSWStore) score(a, b Vector) float32 {
+	return s.scorer.score(a, b)
+}
+
+func (s *HNSWStore) randomLevel() int {
+	return int(math.Floor(-math.Log(1-s.rng.Float64()) * s.levelMult))
+}
+
+func (s *HNSWStore) maxNeighbors(level int) int {
+	if level == 0 {
+		return s.config.M * 2
+	}
+	return s.config.M
+}
+
+func (s *HNSWStore) insert(doc Document) {
+	level := s.randomLevel()
+	idx := int32(len(s.nodes))
+
+	node := &hnswNode{
+		doc:       doc,
+		vector:    s.scorer.prepare(doc.Vector),
+		neighbors: make([][]int32, level+1),
+	}
+	s.nodes = append(s.nodes, node)
+	s.ids[doc.ID] = idx
+
+	if s.entryPoint < 0 {
+		s.entryPoint = idx
+		s.maxLevel = level
+		return
+	}
+
+	entry := []hnswCandidate{{node: s.entryPoint, score: s.score(node.vector, s.nodes[s.entryPoint].vector)}}
+
+	// Greedy descent through the layers above the new node
+	for l := s.maxLevel; l > level; l-- {
+		entry = s.searchLayer(node.vector, entry, 1, l)[:1]
+	}
+
+	top := level
+	if top > s.maxLevel {
+		top = s.maxLevel
+	}
+
+	for l := top; l >= 0; l-- {
+		candidates := s.searchLayer(node.vector, entry, s.config.EfConstruction, l)
+		node.neighbors[l] = s.selectNeighbors(candidates, s.config.M)
+
+		for _, nb := range node.neighbors[l] {
+			s.link(nb, idx, l)
+		}
+
+		entry = candidates
+	}
+
+	if level > s.maxLevel {
+		s.entryPoint = idx
+		s.maxLevel = level
+	}
+}
+
+// link adds a back-reference from node to target on the given layer,
+// pruning the neighbour list when it grows past its limit
+func (s *HNSWStore) link(node, target int32, level int) {
+	n := s.nodes[node]
+	n.neighbors[level] = append(n.neighbors[level], target)
+
+	limit := s.maxNeighbors(level)
+	if len(n.neighbors[level]) <= limit {
+		return
+	}
+
+	candidates := make([]hnswCandidate, len(n.neighbors[level]))
+	for i, nb := range n.neighbors[level] {
+		candidates[i] = hnswCandidate{node: nb, score: s.score(n.vector, s.nodes[nb].vector)}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+
+	n.neighbors[level] = s.selectNeighbors(candidates, limit)
+}
+
+// selectNeighbors applies the diversity heuristic from the HNSW paper: a
+// candidate is kept only if it is closer to the base node than to any
+// neighbour already selected. Candidates must be sorted best first.
+func (s *HNSWStore) selectNeighbors(candidates []hnswCandidate, m int) []int32 {
+	selected := make([]int32, 0, m)
+	pruned := make([]int32, 0)
+
+	for _, c := range candidates {
+		if len(selected) == m {
+			break
+		}
+
+		keep := true
+		for _, other := range selected {
+			if s.score(s.nodes[c.node].vector, s.nodes[other].vector) > c.score {
+				keep = false
+				break
+			}
+		}
+
+		if keep {
+			selected = append(selected, c.node)
+		} else {
+			pruned = append(pruned, c.node)
+		}
+	}
+
+	// Top up with pruned candidates so sparse regions stay connected
+	for _, nb := range pruned {
+		if len(selected) == m {
+			break
+		}
+		selected = append(selected, nb)
+	}
+
+	return selected
+}
+
+// search runs the full top-down traversal and returns up to ef candidates
+// sorted best first
+func (s *HNSWStore) search(query Vector, ef int) []hnswCandidate {
+	entry := []hnswCandidate{{node: s.entryPoint, score: s.score(query, s.nodes[s.entryPoint].vector)}}
+
+	for l := s.maxLevel; l > 0; l-- {
+		entry = s.searchLayer(query, entry, 1, l)[:1]
+	}
+
+	return s.searchLayer(query, entry, ef, 0)
+}
+
+// searchLayer performs a beam search on one layer and returns the ef best
+// candidates found, sorted best first
+func (s *HNSWStore) searchLayer(query Vector, entry []hnswCandidate, ef int, level int) []hnswCandidate {
+	visited, _ := s.visited.Get().(*visitedSet)
+	if visited == nil {
+		visited = &visitedSet{}
+	}
+	visited.reset(len(s.nodes))
+	defer s.visited.Put(visited)
+
+	candidates := make(bestFirst, 0, ef)
+	results := make(worstFirst, 0, ef+1)
+
+	for _, e := range entry {
+		if visited.visit(e.node) {
+			heap.Push(&candidates, e)
+			heap.Push(&results, e)
+		}
+	}
+	for results.Len() > ef {
+		heap.Pop(&results)
+	}
+
+	for candidates.Len() > 0 {
+		c := heap.Pop(&candidates).(hnswCandidate)
+		if results.Len() >= ef && c.score < results[0].score {
+			break
+		}
+
+		node := s.nodes[c.node]
+		if level >= len(node.neighbors) {
+			continue
+		}
+
+		for _, nb := range node.neighbors[level] {
+			if !visited.visit(nb) {
+				continue
+			}
+
+			score := s.score(query, s.nodes[nb].vector)
+			if results.Len() < ef || score > results[0].score {
+				heap.Push(&candidates, hnswCandidate{node: nb, score: score})
+				heap.Push(&results, hnswCandidate{node: nb, score: score})
+				if results.Len() > ef {
+					heap.Pop(&results)
+				}
+			}
+		}
+	}
+
+	sorted := make([]hnswCandidate, results.Len())
+	for i := len(sorted) - 1; i >= 0; i-- {
+		sorted[i] = heap.Pop(&results).(hnswCandidate)
+	}
+
+	return sorted
+}
+
+func (s *HNSWStore) rebuild() {
+	live := make([]Document, 0, len(s.ids))
+	for _, node := range s.nodes {
+		if !node.deleted {
+			live = append(live, node.doc)
+		}
+	}
+
+	s.reset()
+	for _, doc := range live {
+		s.insert(doc)
+	}
+}
@@ -0,0 +1,168 @@
+package vector
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// randomVector draws a gaussian vector around center (or the origin)
+func randomVector(rng *rand.Rand, dim int, center Vector, spread float64) Vector {
+	v := make(Vector, dim)
+	for i := range v {
+		val := rng.NormFloat64() * spread
+		if center != nil {
+			val += float64(center[i])
+		}
+		v[i] = float32(val)
+	}
+
+	return v
+}
+
+func TestHNSWOverwriteCompacts(t *testing.T) {
+	config := DefaultConfig()
+	config.Dimension = 16
+	store, err := NewHNSWStore(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	const documents = 10
+	for round := 0; round < 50; round++ {
+		for i := 0; i < documents; i++ {
+			doc := Document{ID: fmt.Sprintf("doc-%d", i), Vector: randomVector(rng, config.Dimension, nil, 1.0)}
+			if err := store.Add(doc); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if size := store.Size(); size != documents {
+		t.Fatalf("size = %d, want %d", size, documents)
+	}
+
+	// Overwrites leave tombstones, which must not outgrow the rebuild bound
+	if limit := documents + minCompactionDead; len(store.nodes) > limit {
+		t.Fatalf("graph holds %d nodes for %d documents, want at most %d", len(store.nodes), documents, limit)
+	}
+}
+
+// recallFixture is an HNSW store and an exact MemoryStore over the same
+// synthetic clustered data, with queries drawn from the same clusters
+type recallFixture struct {
+	approx  *HNSWStore
+	exact   *MemoryStore
+	queries []Vector
+}
+
+// newRecallFixture builds a recall fixture of documents vectors around
+// clusters centroids
+func newRecallFixture(tb testing.TB, documents, queries, dimension, clusters int) *recallFixture {
+	tb.Helper()
+
+	config := DefaultConfig()
+	config.Dimension = dimension
+
+	exact, err := NewMemoryStore(config)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	approx, err := NewHNSWStore(config)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	centroids := make([]Vector, clusters)
+	for i := range centroids {
+		centroids[i] = randomVector(rng, dimension, nil, 1.0)
+	}
+
+	docs := make([]Document, documents)
+	for i := range docs {
+		docs[i] = Document{
+			ID:     fmt.Sprintf("doc-%d", i),
+			Vector: randomVector(rng, dimension, centroids[rng.Intn(len(centroids))], 0.3),
+		}
+	}
+	if err := exact.AddBatch(docs); err != nil {
+		tb.Fatal(err)
+	}
+	if err := approx.AddBatch(docs); err != nil {
+		tb.Fatal(err)
+	}
+
+	fixture := &recallFixture{approx: approx, exact: exact, queries: make([]Vector, queries)}
+	for i := range fixture.queries {
+		fixture.queries[i] = randomVector(rng, dimension, centroids[rng.Intn(len(centroids))], 0.3)
+	}
+
+	return fixture
+}
+
+// recall returns the fraction of the exact top-k of query found by the HNSW
+// store
+func (f *recallFixture) recall(tb testing.TB, query Vector, topK int) float64 {
+	truth, err := f.exact.Search(query, topK)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	found, err := f.approx.Search(query, topK)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	if len(truth.Documents) == 0 {
+		return 1.0
+	}
+
+	expected := make(map[string]bool, len(truth.Documents))
+	for _, doc := range truth.Documents {
+		expected[doc.ID] = true
+	}
+	hits := 0
+	for _, doc := range found.Documents {
+		if expected[doc.ID] {
+			hits++
+		}
+	}
+
+	return float64(hits) / float64(len(truth.Documents))
+}
+
+func TestHNSWRecall(t *testing.T) {
+	const topK, minRecall = 10, 0.95
+
+	fixture := newRecallFixture(t, 5000, 100, 64, 32)
+
+	var sum float64
+	for _, query := range fixture.queries {
+		sum += fixture.recall(t, query, topK)
+	}
+
+	if recall := sum / float64(len(fixture.queries)); recall < minRecall {
+		t.Fatalf("recall@%d = %.4f, want at least %.2f", topK, recall, minRecall)
+	}
+}
+
+func BenchmarkHNSWRecall(b *testing.B) {
+	const topK = 10
+
+	fixture := newRecallFixture(b, 20000, 200, 128, 64)
+
+	var sum float64
+	for _, query := range fixture.queries {
+		sum += fixture.recall(b, query, topK)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := fixture.approx.Search(fixture.queries[i%len(fixture.queries)], topK); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.ReportMetric(sum/float64(len(fixture.queries)), "recall@10")
+}
@@ -0,0 +1,160 @@
+package vector
+
+import (
+	"fmt"
+	"math"
+)
+
+// Metric names the similarity measure a store ranks documents by
+type Metric string
+
+const (
+	// MetricCosine ranks by cosine similarity; scores lie in [-1, 1]
+	MetricCosine Metric = "cosine"
+
+	// MetricDotProduct ranks by raw inner product, for models trained with
+	// maximum inner product search; scores are unbounded
+	MetricDotProduct Metric = "dot"
+
+	// MetricEuclidean ranks by L2 distance, reported as the similarity
+	// 1 / (1 + distance) so that scores lie in (0, 1] and higher is better
+	MetricEuclidean Metric = "l2"
+
+	// MetricCustom ranks by Config.SimilarityFunc; higher scores must mean
+	// more similar and scores are treated as unbounded
+	MetricCustom Metric = "custom"
+)
+
+// NoThreshold disables the similarity threshold for any metric
+var NoThreshold = float32(math.Inf(-1))
+
+// Bounded reports whether scores of the metric lie in [0, 1] for the
+// thresholds that make sense, so thresholds outside that range are invalid
+func (m Metric) Bounded() bool {
+	switch m {
+	case MetricCosine, MetricEuclidean, "":
+		return true
+	default:
+		return false
+	}
+}
+
+// ValidateThreshold checks that threshold is meaningful for the metric.
+// Bounded metrics accept [0, 1]; unbounded ones accept any finite value.
+// NoThreshold is accepted by every metric.
+func (m Metric) ValidateThreshold(threshold float32) error {
+	if threshold == NoThreshold {
+		return nil
+	}
+
+	if math.IsNaN(float64(threshold)) || math.IsInf(float64(threshold), 0) {
+		return ErrInvalidThreshold
+	}
+
+	if m.Bounded() && (threshold < 0.0 || threshold > 1.0) {
+		return ErrInvalidThreshold
+	}
+
+	return nil
+}
+
+// DefaultThreshold is the threshold used by Search: bounded metrics keep
+// their historical cut-off at zero, unbounded ones return every candidate
+func (m Metric) DefaultThreshold() float32 {
+	if m.Bounded() {
+		return 0.0
+	}
+	return NoThreshold
+}
+
+// EuclideanSimilarity converts L2 distance into a similarity in (0, 1]
+func EuclideanSimilarity(a, b Vector) float32 {
+	return 1 / (1 + EuclideanDistance(a, b))
+}
+
+// scorer computes metric scores for a store. Vectors are passed through
+// prepare once on insert and on every query so that cosine can be served by
+// a plain dot product over normalized vectors.
+type scorer struct {
+	metric Metric
+	custom SimilarityFunc
+	simd   bool
+}
+
+func newScorer(config *Config) (*scorer, error) {
+	metric := config.Metric
+	if metric == "" {
+		metric = MetricCosine
+	}
+
+	switch metric {
+	case MetricCosine, MetricDotProduct, MetricEuclidean:
+	case MetricCustom:
+		if config.SimilarityFunc == nil {
+			return nil, fmt.Errorf("similarity function is required for the custom metric")
+		}
+	default:
+		return nil, fmt.Errorf("unknown similarity metric %q", metric)
+	}
+
+	return &scorer{
+		metric: metric,
+		custom: config.SimilarityFunc,
+		simd:   config.EnableSIMD && isSIMDAvailable(),
+	}, nil
+}
+
+// prepare returns the representation of v that score expects. On the SIMD
+// path the copy is aligned for the kernels.
+func (s *scorer) prepare(v Vector) Vector {
+	if s.metric != MetricCosine {
+		return v
+	}
+
+	if !s.simd {
+		return Normalize(v)
+	}
+
+	normalized := alignedAlloc(len(v))
+	if mag := MagnitudeSIMD(v); mag != 0.0 {
+		for i, val := range v {
+			normalized[i] = val / mag
+		}
+	}
+	return normalized
+}
+
+// score compares two prepared vectors; higher is always more similar
+func (s *scorer) score(a, b Vector) float32 {
+	switch s.metric {
+	case MetricCosine, MetricDotProduct:
+		if s.simd {
+			return DotProductSIMD(a, b)
+		}
+		return DotProduct(a, b)
+	case MetricEuclidean:
+		if s.simd {
+			return 1 / (1 + EuclideanDistanceSIMD(a, b))
+		}
+		return EuclideanSimilarity(a, b)
+	default:
+		return s.custom(a, b)
+	}
+}
+
+// batch scores query against raw (unprepared) vectors
+func (s *scorer) batch(query Vector, vectors []Vector) []float32 {
+	switch s.metric {
+	case MetricCosine:
+		if s.simd {
+			return BatchCosineSimilaritySIMD(query, vectors)
+		}
+		return BatchCosineSimilarity(query, vectors)
+	default:
+		scores := make([]float32, len(vectors))
+		for i, v := range vectors {
+			scores[i] = s.score(query, v)
+		}
+		return scores
+	}
+}
@@ -0,0 +1,409 @@
+package vector
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime"
+	"sync"
+)
+
+// QuantizationType selects how the flat index compresses stored vectors
+type QuantizationType string
+
+const (
+	QuantizationNone    QuantizationType = "none"
+	QuantizationInt8    QuantizationType = "int8" // scalar quantization, one byte per dimension
+	QuantizationProduct QuantizationType = "pq"   // product quantization, one byte per subspace
+)
+
+// QuantizationConfig configures vector compression for the flat index.
+// Codebooks are trained once TrainingSize vectors have been added; until then
+// search is exact. Afterwards search scores the compressed codes, and the
+// full-precision vectors are dropped unless Rescore is set.
+type QuantizationConfig struct {
+	Type QuantizationType `yaml:"type" json:"type"`
+
+	// Subspaces is the number of PQ sub-vectors and must divide the
+	// dimension; zero picks sub-vectors of 8 dimensions where possible
+	Subspaces int `yaml:"subspaces" json:"subspaces"`
+
+	// Centroids is the PQ codebook size per subspace, at most 256
+	Centroids int `yaml:"centroids" json:"centroids"`
+
+	// TrainingSize is how many vectors are collected before training
+	TrainingSize int `yaml:"training_size" json:"training_size"`
+
+	// Rescore, when positive, keeps the full-precision vectors and re-ranks
+	// the best topK*Rescore compressed candidates with exact scores
+	Rescore int `yaml:"rescore" json:"rescore"`
+}
+
+// DefaultQuantizationConfig returns quantization settings with compression disabled
+func DefaultQuantizationConfig() QuantizationConfig {
+	return QuantizationConfig{
+		Type:         QuantizationNone,
+		Centroids:    256,
+		TrainingSize: 2048,
+	}
+}
+
+// enabled reports whether the config asks for compression
+func (c QuantizationConfig) enabled() bool {
+	return c.Type != "" && c.Type != QuantizationNone
+}
+
+// validate checks the config against the store dimension
+func (c QuantizationConfig) validate(dimension int) error {
+	switch c.Type {
+	case "", QuantizationNone, QuantizationInt8:
+	case QuantizationProduct:
+		if c.Centroids <= 0 || c.Centroids > 256 {
+			return fmt.Errorf("product quantization centroids must be between 1 and 256")
+		}
+		if c.Subspaces < 0 || (c.Subspaces > 0 && dimension%c.Subspaces != 0) {
+			return fmt.Errorf("product quantization subspaces %d must divide dimension %d", c.Subspaces, dimension)
+		}
+	default:
+		return fmt.Errorf("unknown quantization type %q", c.Type)
+	}
+
+	if c.TrainingSize < 0 || c.Rescore < 0 {
+		return fmt.Errorf("quantization training size and rescore must not be negative")
+	}
+
+	return nil
+}
+
+// quantizer compresses prepared vectors into fixed-size codes
+type quantizer interface {
+	// train fits the codebooks to a sample of prepared vectors
+	train(vectors []Vector)
+
+	encode(v Vector) []byte
+	decode(code []byte) Vector
+
+	// querier returns a function scoring codes against a prepared query
+	// with the store metric; higher is more similar
+	querier(query Vector) func(code []byte) float32
+
+	// codeSize is the number of bytes per encoded vector
+	codeSize() int
+
+	// codebookSize is the number of bytes held by the trained codebooks
+	codebookSize() int
+}
+
+func newQuantizer(config QuantizationConfig, dimension int, scorer *scorer) quantizer {
+	if config.Type == QuantizationProduct {
+		subspaces := config.Subspaces
+		if subspaces == 0 {
+			subspaces = defaultSubspaces(dimension)
+		}
+		return &productQuantizer{
+			scorer:    scorer,
+			subspaces: subspaces,
+			subDim:    dimension / subspaces,
+			centroids: config.Centroids,
+		}
+	}
+
+	return &scalarQuantizer{scorer: scorer, dimension: dimension}
+}
+
+// defaultSubspaces picks the largest sub-vector size of at most 8 dimensions
+// that divides the dimension
+func defaultSubspaces(dimension int) int {
+	for subDim := 8; subDim > 1; subDim-- {
+		if dimension%subDim == 0 {
+			return dimension / subDim
+		}
+	}
+	return dimension
+}
+
+// scalarQuantizer maps every dimension linearly onto 256 levels between the
+// minimum and maximum seen during training
+type scalarQuantizer struct {
+	scorer    *scorer
+	dimension int
+	min       []float32
+	scale     []float32
+}
+
+func (q *scalarQuantizer) train(vectors []Vector) {
+	q.min = make([]float32, q.dimension)
+	q.scale = make([]float32, q.dimension)
+	if len(vectors) == 0 {
+		return
+	}
+
+	max := make([]float32, q.dimension)
+	copy(q.min, vectors[0])
+	copy(max, vectors[0])
+	for _, v := range vectors[1:] {
+		for d, val := range v {
+			if val < q.min[d] {
+				q.min[d] = val
+			}
+			if val > max[d] {
+				max[d] = val
+			}
+		}
+	}
+
+	for d := range q.scale {
+		q.scale[d] = (max[d] - q.min[d]) / 255
+	}
+}
+
+func (q *scalarQuantizer) encode(v Vector) []byte {
+	code := make([]byte, q.dimension)
+	for d, val := range v {
+		if q.scale[d] == 0 {
+			continue
+		}
+		level := math.Round(float64((val - q.min[d]) / q.scale[d]))
+		code[d] = byte(math.Max(0, math.Min(255, level)))
+	}
+	return code
+}
+
+func (q *scalarQuantizer) decode(code []byte) Vector {
+	v := make(Vector, q.dimension)
+	for d, c := range code {
+		v[d] = q.min[d] + q.scale[d]*float32(c)
+	}
+	return v
+}
+
+func (q *scalarQuantizer) querier(query Vector) func(code []byte) float32 {
+	switch q.scorer.metric {
+	case MetricCosine, MetricDotProduct:
+		// q·x = Σ q·min + Σ (q·scale)·c
+		weights := make([]float32, q.dimension)
+		var offset float32
+		for d, val := range query {
+			weights[d] = val * q.scale[d]
+			offset += val * q.min[d]
+		}
+		return func(code []byte) float32 {
+			sum := offset
+			for d, c := range code {
+				sum += weights[d] * float32(c)
+			}
+			return sum
+		}
+	case MetricEuclidean:
+		shifted := make([]float32, q.dimension)
+		for d, val := range query {
+			shifted[d] = val - q.min[d]
+		}
+		return func(code []byte) float32 {
+			var sum float32
+			for d, c := range code {
+				diff := shifted[d] - q.scale[d]*float32(c)
+				sum += diff * diff
+			}
+			return 1 / (1 + float32(math.Sqrt(float64(sum))))
+		}
+	default:
+		return func(code []byte) float32 {
+			return q.scorer.score(query, q.decode(code))
+		}
+	}
+}
+
+func (q *scalarQuantizer) codeSize() int {
+	return q.dimension
+}
+
+func (q *scalarQuantizer) codebookSize() int {
+	return q.dimension * 8
+}
+
+// productQuantizer splits vectors into subspaces and replaces every
+// sub-vector by the index of its nearest k-means centroid
+type productQuantizer struct {
+	scorer    *scorer
+	subspaces int
+	subDim    int
+	centroids int
+	codebooks [][]float32 // per subspace, centroids*subDim values
+}
+
+// pqIterations bounds the Lloyd iterations per subspace
+const pqIterations = 10
+
+func (q *productQuantizer) train(vectors []Vector) {
+	if len(vectors) < q.centroids {
+		q.centroids = len(vectors)
+	}
+	if q.centroids == 0 {
+		q.centroids = 1
+	}
+
+	q.codebooks = make([][]float32, q.subspaces)
+
+	// Subspaces are independent, so they train in parallel
+	var wg sync.WaitGroup
+	work := make(chan int)
+	for w := 0; w < runtime.GOMAXPROCS(0); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for m := range work {
+				points := make([]float32, len(vectors)*q.subDim)
+				for i, v := range vectors {
+					copy(points[i*q.subDim:], v[m*q.subDim:(m+1)*q.subDim])
+				}
+				rng := rand.New(rand.NewSource(int64(m) + 1))
+				q.codebooks[m] = kmeans(points, q.subDim, q.centroids, pqIterations, rng)
+			}
+		}()
+	}
+	for m := 0; m < q.subspaces; m++ {
+		work <- m
+	}
+	close(work)
+	wg.Wait()
+}
+
+func (q *productQuantizer) encode(v Vector) []byte {
+	code := make([]byte, q.subspaces)
+	for m := range code {
+		code[m] = byte(nearestCentroid(q.codebooks[m], v[m*q.subDim:(m+1)*q.subDim], q.subDim))
+	}
+	return code
+}
+
+func (q *productQuantizer) decode(code []byte) Vector {
+	v := make(Vector, q.subspaces*q.subDim)
+	for m, c := range code {
+		copy(v[m*q.subDim:], q.codebooks[m][int(c)*q.subDim:(int(c)+1)*q.subDim])
+	}
+	return v
+}
+
+// querier precomputes the asymmetric distance table: the partial score of
+// the query against every centroid of every subspace
+func (q *productQuantizer) querier(query Vector) func(code []byte) float32 {
+	metric := q.scorer.metric
+	if metric != MetricCosine && metric != MetricDotProduct && metric != MetricEuclidean {
+		return func(code []byte) float32 {
+			return q.scorer.score(query, q.decode(code))
+		}
+	}
+
+	table := make([]float32, q.subspaces*q.centroids)
+	for m := 0; m < q.subspaces; m++ {
+		sub := query[m*q.subDim : (m+1)*q.subDim]
+		for k := 0; k < q.centroids; k++ {
+			centroid := q.codebooks[m][k*q.subDim : (k+1)*q.subDim]
+			if metric == MetricEuclidean {
+				table[m*q.centroids+k] = squaredDistance(sub, centroid)
+			} else {
+				table[m*q.centroids+k] = DotProduct(sub, centroid)
+			}
+		}
+	}
+
+	return func(code []byte) float32 {
+		var sum float32
+		for m, c := range code {
+			sum += table[m*q.centroids+int(c)]
+		}
+		if metric == MetricEuclidean {
+			return 1 / (1 + float32(math.Sqrt(float64(sum))))
+		}
+		return sum
+	}
+}
+
+func (q *productQuantizer) codeSize() int {
+	return q.subspaces
+}
+
+func (q *productQuantizer) codebookSize() int {
+	return q.subspaces * q.centroids * q.subDim * 4
+}
+
+// kmeans clusters n points of dim values stored back to back and returns
+// k centroids in the same layout
+func kmeans(points []float32, dim, k, iterations int, rng *rand.Rand) []float32 {
+	n := len(points) / dim
+	centroids := make([]float32, k*dim)
+
+	// Seed with distinct random points
+	for c, p := range rng.Perm(n)[:k] {
+		copy(centroids[c*dim:(c+1)*dim], points[p*dim:(p+1)*dim])
+	}
+
+	assignment := make([]int, n)
+	sums := make([]float64, k*dim)
+	counts := make([]int, k)
+
+	for iter := 0; iter < iterations; iter++ {
+		changed := false
+		for i := 0; i < n; i++ {
+			c := nearestCentroid(centroids, points[i*dim:(i+1)*dim], dim)
+			if c != assignment[i] || iter == 0 {
+				changed = true
+				assignment[i] = c
+			}
+		}
+
+		if !changed {
+			break
+		}
+
+		for i := range sums {
+			sums[i] = 0
+		}
+		for i := range counts {
+			counts[i] = 0
+		}
+
+		for i, c := range assignment {
+			counts[c]++
+			for d := 0; d < dim; d++ {
+				sums[c*dim+d] += float64(points[i*dim+d])
+			}
+		}
+
+		for c := 0; c < k; c++ {
+			if counts[c] == 0 {
+				// Re-seed empty clusters with a random point
+				p := rng.Intn(n)
+				copy(centroids[c*dim:(c+1)*dim], points[p*dim:(p+1)*dim])
+				continue
+			}
+			for d := 0; d < dim; d++ {
+				centroids[c*dim+d] = float32(sums[c*dim+d] / float64(counts[c]))
+			}
+		}
+	}
+
+	return centroids
+}
+
+// nearestCentroid returns the index of the centroid closest to v in L2
+func nearestCentroid(centroids []float32, v []float32, dim int) int {
+	best, bestDist := 0, float32(math.Inf(1))
+	for c := 0; c*dim < len(centroids); c++ {
+		if dist := squaredDistance(v, centroids[c*dim:(c+1)*dim]); dist < bestDist {
+			best, bestDist = c, dist
+		}
+	}
+	return best
+}
+
+// squaredDistance returns the squared L2 distance of two equal-length slices
+func squaredDistance(a, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		diff := a[i] - b[i]
+		sum += diff * diff
+	}
+	return sum
+}
@@ -0,0 +1,52 @@
+//go:build amd64 && !purego
+
+package vector
+
+//go:noescape
+func cpuid(eaxArg, ecxArg uint32) (eax, ebx, ecx, edx uint32)
+
+//go:noescape
+func xgetbv() (eax, edx uint32)
+
+//go:noescape
+func dotAVX2(a, b *float32, n int) float32
+
+//go:noescape
+func squaredL2AVX2(a, b *float32, n int) float32
+
+//go:noescape
+func dotAVX512(a, b *float32, n int) float32
+
+//go:noescape
+func squaredL2AVX512(a, b *float32, n int) float32
+
+func init() {
+	maxLeaf, _, _, _ := cpuid(0, 0)
+	if maxLeaf < 7 {
+		return
+	}
+
+	_, _, ecx1, _ := cpuid(1, 0)
+	hasFMA := ecx1&(1<<12) != 0
+	hasOSXSAVE := ecx1&(1<<27) != 0
+	hasAVX := ecx1&(1<<28) != 0
+	if !hasFMA || !hasOSXSAVE || !hasAVX {
+		return
+	}
+
+	// The OS must save the YMM (and for AVX-512 the opmask and ZMM) state
+	xcr0, _ := xgetbv()
+	if xcr0&0x6 != 0x6 {
+		return
+	}
+
+	_, ebx7, _, _ := cpuid(7, 0)
+	if ebx7&(1<<16) != 0 && xcr0&0xe6 == 0xe6 {
+		simdKernels = kernelSet{name: "avx512", dot: dotAVX512, squaredL2: squaredL2AVX512}
+		return
+	}
+
+	if ebx7&(1<<5) != 0 {
+		simdKernels = kernelSet{name: "avx2", dot: dotAVX2, squaredL2: squaredL2AVX2}
+	}
+}
@@ -0,0 +1,15 @@
+//go:build arm64 && !purego
+
+package vector
+
+//go:noescape
+func dotNEON(a, b *float32, n int) float32
+
+//go:noescape
+func squaredL2NEON(a, b *float32, n int) float32
+
+// Advanced SIMD is mandatory on every arm64 target Go supports, so the NEON
+// kernels need no runtime probe
+func init() {
+	simdKernels = kernelSet{name: "neon", dot: dotNEON, squaredL2: squaredL2NEON}
+}
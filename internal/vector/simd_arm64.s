@@ -0,0 +1,130 @@
+//go:build arm64 && !purego
+
+#include "textflag.h"
+
+// func dotNEON(a, b *float32, n int) float32
+TEXT ·dotNEON(SB), NOSPLIT, $0-28
+	MOVD a+0(FP), R0
+	MOVD b+8(FP), R1
+	MOVD n+16(FP), R2
+	VEOR V0.B16, V0.B16, V0.B16
+	VEOR V1.B16, V1.B16, V1.B16
+	VEOR V2.B16, V2.B16, V2.B16
+	VEOR V3.B16, V3.B16, V3.B16
+
+dot16:
+	CMP    $16, R2
+	BLT    dot4
+	VLD1.P 64(R0), [V4.S4, V5.S4, V6.S4, V7.S4]
+	VLD1.P 64(R1), [V8.S4, V9.S4, V10.S4, V11.S4]
+	VFMLA  V8.S4, V4.S4, V0.S4
+	VFMLA  V9.S4, V5.S4, V1.S4
+	VFMLA  V10.S4, V6.S4, V2.S4
+	VFMLA  V11.S4, V7.S4, V3.S4
+	SUB    $16, R2
+	B      dot16
+
+dot4:
+	CMP    $4, R2
+	BLT    dotReduce
+	VLD1.P 16(R0), [V4.S4]
+	VLD1.P 16(R1), [V8.S4]
+	VFMLA  V8.S4, V4.S4, V0.S4
+	SUB    $4, R2
+	B      dot4
+
+dotReduce:
+	// Fold the accumulators by multiply-adding them with a vector of ones
+	MOVW  $0x3f800000, R4
+	VDUP  R4, V16.S4
+	VFMLA V16.S4, V1.S4, V0.S4
+	VFMLA V16.S4, V2.S4, V0.S4
+	VFMLA V16.S4, V3.S4, V0.S4
+	VMOV  V0.S[1], R5
+	VMOV  V0.S[2], R6
+	VMOV  V0.S[3], R7
+	FMOVS R5, F1
+	FMOVS R6, F2
+	FMOVS R7, F3
+	FADDS F1, F0, F0
+	FADDS F3, F2, F2
+	FADDS F2, F0, F0
+
+dotTail:
+	CBZ     R2, dotDone
+	FMOVS.P 4(R0), F4
+	FMOVS.P 4(R1), F5
+	FMADDS  F5, F0, F4, F0
+	SUB     $1, R2
+	B       dotTail
+
+dotDone:
+	FMOVS F0, ret+24(FP)
+	RET
+
+// func squaredL2NEON(a, b *float32, n int) float32
+TEXT ·squaredL2NEON(SB), NOSPLIT, $0-28
+	MOVD a+0(FP), R0
+	MOVD b+8(FP), R1
+	MOVD n+16(FP), R2
+	VEOR V0.B16, V0.B16, V0.B16
+	VEOR V1.B16, V1.B16, V1.B16
+	VEOR V2.B16, V2.B16, V2.B16
+	VEOR V3.B16, V3.B16, V3.B16
+
+	// Differences are formed as a - b*1 so that no copy is needed
+	MOVW $0x3f800000, R4
+	VDUP R4, V16.S4
+
+l2x16:
+	CMP    $16, R2
+	BLT    l2x4
+	VLD1.P 64(R0), [V4.S4, V5.S4, V6.S4, V7.S4]
+	VLD1.P 64(R1), [V8.S4, V9.S4, V10.S4, V11.S4]
+	VFMLS  V16.S4, V8.S4, V4.S4
+	VFMLS  V16.S4, V9.S4, V5.S4
+	VFMLS  V16.S4, V10.S4, V6.S4
+	VFMLS  V16.S4, V11.S4, V7.S4
+	VFMLA  V4.S4, V4.S4, V0.S4
+	VFMLA  V5.S4, V5.S4, V1.S4
+	VFMLA  V6.S4, V6.S4, V2.S4
+	VFMLA  V7.S4, V7.S4, V3.S4
+	SUB    $16, R2
+	B      l2x16
+
+l2x4:
+	CMP    $4, R2
+	BLT    l2Reduce
+	VLD1.P 16(R0), [V4.S4]
+	VLD1.P 16(R1), [V8.S4]
+	VFMLS  V16.S4, V8.S4, V4.S4
+	VFMLA  V4.S4, V4.S4, V0.S4
+	SUB    $4, R2
+	B      l2x4
+
+l2Reduce:
+	VFMLA V16.S4, V1.S4, V0.S4
+	VFMLA V16.S4, V2.S4, V0.S4
+	VFMLA V16.S4, V3.S4, V0.S4
+	VMOV  V0.S[1], R5
+	VMOV  V0.S[2], R6
+	VMOV  V0.S[3], R7
+	FMOVS R5, F1
+	FMOVS R6, F2
+	FMOVS R7, F3
+	FADDS F1, F0, F0
+	FADDS F3, F2, F2
+	FADDS F2, F0, F0
+
+l2Tail:
+	CBZ     R2, l2Done
+	FMOVS.P 4(R0), F4
+	FMOVS.P 4(R1), F5
+	FSUBS   F5, F4, F4
+	FMADDS  F4, F0, F4, F0
+	SUB     $1, R2
+	B       l2Tail
+
+l2Done:
+	FMOVS F0, ret+24(FP)
+	RET
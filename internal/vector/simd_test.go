@@ -0,0 +1,149 @@
+package vector
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// simdSeedLengths are the lengths around every kernel block size: 4 and 16
+// for NEON, 8 and 32 for AVX2, 16 and 64 for AVX-512
+var simdSeedLengths = []int{0, 1, 3, 4, 5, 7, 8, 9, 15, 16, 17, 31, 32, 33, 63, 64, 65, 127, 128, 129, 384, 1536}
+
+// maxFuzzLength bounds the vectors built from fuzz input
+const maxFuzzLength = 4096
+
+// addSIMDSeeds seeds a fuzz target with every length in simdSeedLengths
+func addSIMDSeeds(f *testing.F) {
+	for i, n := range simdSeedLengths {
+		f.Add(uint16(n), int64(i))
+	}
+}
+
+// fuzzVectors returns two random vectors of the fuzzed length, skipping the
+// test when the SIMD path is unavailable and the functions compared are the
+// same
+func fuzzVectors(t *testing.T, length uint16, seed int64) (Vector, Vector) {
+	if !isSIMDAvailable() {
+		t.Skip("SIMD kernels are not available")
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	n := int(length) % (maxFuzzLength + 1)
+	scale := rng.Float64() * 10
+	return randomVector(rng, n, nil, scale), randomVector(rng, n, nil, scale)
+}
+
+// checkKernel fails t when a SIMD result differs from the scalar one by more
+// than the rounding error the different summation order can introduce;
+// scale bounds the magnitude of the partial sums both were accumulated from
+func checkKernel(t *testing.T, name string, n int, scalar, simd float32, scale float64) {
+	t.Helper()
+
+	tolerance := 1e-5*scale + 1e-6
+	if math.Abs(float64(scalar)-float64(simd)) > tolerance {
+		t.Fatalf("%s kernel %s mismatch for length %d: scalar %g, simd %g", SIMDLevel(), name, n, scalar, simd)
+	}
+}
+
+func FuzzDotProductSIMD(f *testing.F) {
+	addSIMDSeeds(f)
+	f.Fuzz(func(t *testing.T, length uint16, seed int64) {
+		a, b := fuzzVectors(t, length, seed)
+
+		var scale, normA, normB float64
+		for i := range a {
+			scale += math.Abs(float64(a[i]) * float64(b[i]))
+			normA += float64(a[i]) * float64(a[i])
+			normB += float64(b[i]) * float64(b[i])
+		}
+		checkKernel(t, "dot product", len(a), DotProduct(a, b), DotProductSIMD(a, b), scale)
+
+		cosineScale := 1.0
+		if normA > 0 && normB > 0 {
+			cosineScale = scale / math.Sqrt(normA*normB)
+		}
+		checkKernel(t, "cosine similarity", len(a), CosineSimilarity(a, b), CosineSimilaritySIMD(a, b), cosineScale)
+	})
+}
+
+func FuzzEuclideanSIMD(f *testing.F) {
+	addSIMDSeeds(f)
+	f.Fuzz(func(t *testing.T, length uint16, seed int64) {
+		a, b := fuzzVectors(t, length, seed)
+
+		var scale float64
+		for i := range a {
+			diff := float64(a[i]) - float64(b[i])
+			scale += diff * diff
+		}
+		checkKernel(t, "euclidean distance", len(a), EuclideanDistance(a, b), EuclideanDistanceSIMD(a, b), math.Sqrt(scale))
+	})
+}
+
+func FuzzMagnitudeSIMD(f *testing.F) {
+	addSIMDSeeds(f)
+	f.Fuzz(func(t *testing.T, length uint16, seed int64) {
+		a, _ := fuzzVectors(t, length, seed)
+
+		var scale float64
+		for _, v := range a {
+			scale += float64(v) * float64(v)
+		}
+		checkKernel(t, "magnitude", len(a), Magnitude(a), MagnitudeSIMD(a), math.Sqrt(scale))
+	})
+}
+
+func TestBatchCosineSimilaritySIMD(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	query := randomVector(rng, 384, nil, 1.0)
+	vectors := make([]Vector, 64)
+	for i := range vectors {
+		vectors[i] = randomVector(rng, 384, nil, 1.0)
+	}
+	vectors[7] = make(Vector, 384) // zero vector
+	vectors[9] = vectors[9][:100]  // dimension mismatch
+
+	// Batch results must match the single-pair path element for element
+	batch := BatchCosineSimilaritySIMD(query, vectors)
+	for i, vec := range vectors {
+		checkKernel(t, "batch cosine similarity", len(vec), CosineSimilarity(query, vec), batch[i], 1.0)
+	}
+}
+
+// benchmarkDimensions are common embedding sizes
+var benchmarkDimensions = []int{128, 384, 1536}
+
+// benchmarkSink keeps benchmark results alive so the loops are not optimized away
+var benchmarkSink float32
+
+// benchmarkKernel times fn over a pair of random vectors at every benchmark
+// dimension
+func benchmarkKernel(b *testing.B, fn func(a, b Vector) float32) {
+	for _, dim := range benchmarkDimensions {
+		b.Run(fmt.Sprintf("dim=%d", dim), func(b *testing.B) {
+			rng := rand.New(rand.NewSource(1))
+			x := randomVector(rng, dim, nil, 1.0)
+			y := randomVector(rng, dim, nil, 1.0)
+
+			b.SetBytes(int64(2 * 4 * dim))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				benchmarkSink += fn(x, y)
+			}
+		})
+	}
+}
+
+func BenchmarkDotProductScalar(b *testing.B) { benchmarkKernel(b, DotProduct) }
+
+func BenchmarkDotProductSIMD(b *testing.B) { benchmarkKernel(b, DotProductSIMD) }
+
+func BenchmarkEuclideanScalar(b *testing.B) { benchmarkKernel(b, EuclideanDistance) }
+
+func BenchmarkEuclideanSIMD(b *testing.B) { benchmarkKernel(b, EuclideanDistanceSIMD) }
+
+func BenchmarkCosineScalar(b *testing.B) { benchmarkKernel(b, CosineSimilarity) }
+
+func BenchmarkCosineSIMD(b *testing.B) { benchmarkKernel(b, CosineSimilaritySIMD) }
@@ -135,7 +135,10 @@ func BatchCosineSimilarity(query Vector, vectors []Vector) []float32 {
 	return similarities
 }
 
-// TopKIndices returns the indices of the top-k highest values in scores
+// TopKIndices returns the indices of the top-k highest scores, best first.
+// Every metric reports scores where higher means more similar, so this single
+// ordering serves all of them. Equal scores keep their original order and NaN
+// scores rank below every real score.
 func TopKIndices(scores []float32, k int) []int {
 	if k <= 0 || len(scores) == 0 {
 		return nil
@@ -145,34 +148,72 @@ func TopKIndices(scores []float32, k int) []int {
 		k = len(scores)
 	}
 	
-	// Create index-score pairs
-	type indexScore struct {
-		index int
-		score float32
+	// Keep the best k seen so far in a min-heap rooted at the worst of them
+	heap := make([]int, 0, k)
+	worse := func(a, b int) bool {
+		return rankBefore(scores, b, a)
 	}
 	
-	pairs := make([]indexScore, len(scores))
-	for i, score := range scores {
-		pairs[i] = indexScore{index: i, score: score}
+	siftDown := func(i int) {
+		for {
+			child := 2*i + 1
+			if child >= len(heap) {
+				return
+			}
+			if child+1 < len(heap) && worse(heap[child+1], heap[child]) {
+				child++
+			}
+			if !worse(heap[child], heap[i]) {
+				return
+			}
+			heap[i], heap[child] = heap[child], heap[i]
+			i = child
+		}
 	}
 	
-	// Partial sort to get top-k elements
-	// Using simple selection sort for small k, heap sort would be better for large k
-	for i := 0; i < k; i++ {
-		maxIdx := i
-		for j := i + 1; j < len(pairs); j++ {
-			if pairs[j].score > pairs[maxIdx].score {
-				maxIdx = j
+	for idx := range scores {
+		if len(heap) < k {
+			heap = append(heap, idx)
+			for i := len(heap) - 1; i > 0; {
+				parent := (i - 1) / 2
+				if !worse(heap[i], heap[parent]) {
+					break
+				}
+				heap[i], heap[parent] = heap[parent], heap[i]
+				i = parent
 			}
+			continue
+		}
+		
+		if rankBefore(scores, idx, heap[0]) {
+			heap[0] = idx
+			siftDown(0)
 		}
-		pairs[i], pairs[maxIdx] = pairs[maxIdx], pairs[i]
 	}
 	
-	// Extract indices
-	indices := make([]int, k)
-	for i := 0; i < k; i++ {
-		indices[i] = pairs[i].index
+	// Drain the heap worst-first into the result from the back
+	indices := make([]int, len(heap))
+	for i := len(indices) - 1; i >= 0; i-- {
+		indices[i] = heap[0]
+		last := len(heap) - 1
+		heap[0] = heap[last]
+		heap = heap[:last]
+		siftDown(0)
 	}
 	
 	return indices
+}
+
+// rankBefore reports whether scores[a] ranks ahead of scores[b]
+func rankBefore(scores []float32, a, b int) bool {
+	sa, sb := scores[a], scores[b]
+	nanA, nanB := sa != sa, sb != sb
+	switch {
+	case nanA != nanB:
+		return nanB
+	case !nanA && sa != sb:
+		return sa > sb
+	default:
+		return a < b
+	}
 }
\ No newline at end of file
@@ -0,0 +1,382 @@
+package vector
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"sort"
+)
+
+// Snapshot format, little endian:
+//
+//	header: "MRSN" | u16 version | u32 dimension | u8 metric length | metric |
+//	        u64 document count | u32 crc32c of the preceding header bytes
+//	block:  u32 payload length | u32 crc32c of payload | payload
+//	end:    u32 zero length | u32 crc32c of all block payloads
+//
+// A block payload holds up to snapshotBlockSize documents: a uvarint count,
+// then the document records (id, content, metadata) and finally one vector
+// block with count*dimension float32 values in record order.
+const (
+	snapshotMagic     = "MRSN"
+	snapshotVersion   = 1
+	snapshotBlockSize = 256
+
+	// snapshotMaxDimension bounds the dimension read from a snapshot header,
+	// far above any embedding model, so vector block sizes cannot overflow
+	snapshotMaxDimension = 1 << 16
+)
+
+// Snapshotter is implemented by stores that can stream their contents to a
+// snapshot and replace them from one. SaveSnapshot and LoadSnapshot fall back
+// to the plain Store methods for stores that do not implement it.
+type Snapshotter interface {
+	Snapshot(w io.Writer) error
+	Restore(r io.Reader) error
+}
+
+// SnapshotHeader describes the contents of a snapshot
+type SnapshotHeader struct {
+	Version   uint16 `json:"version"`
+	Dimension int    `json:"dimension"`
+	Metric    Metric `json:"metric"`
+	Count     int    `json:"count"`
+}
+
+// SaveSnapshot writes every document of store to w
+func SaveSnapshot(store Store, w io.Writer) error {
+	if s, ok := store.(Snapshotter); ok {
+		return s.Snapshot(w)
+	}
+
+	lister, ok := store.(interface{ ListIDs() []string })
+	if !ok {
+		return NewVectorErrorWithOp("snapshot", fmt.Errorf("store cannot list its documents"))
+	}
+
+	get := func(id string) (Document, error) {
+		doc, err := store.Get(id)
+		if err != nil {
+			return Document{}, err
+		}
+		return *doc, nil
+	}
+
+	ids := lister.ListIDs()
+
+	dimension, ok := storeDimension(store)
+	if !ok && len(ids) > 0 {
+		first, err := get(ids[0])
+		if err != nil {
+			return NewVectorErrorWithOp("snapshot", err)
+		}
+		dimension = len(first.Vector)
+	}
+
+	if err := writeSnapshot(w, dimension, storeMetric(store), ids, get); err != nil {
+		return NewVectorErrorWithOp("snapshot", err)
+	}
+
+	return nil
+}
+
+// LoadSnapshot replaces the contents of store with the documents in r. The
+// snapshot is read and verified completely before the store is cleared, so a
+// corrupt snapshot leaves the store untouched.
+func LoadSnapshot(store Store, r io.Reader) error {
+	if s, ok := store.(Snapshotter); ok {
+		return s.Restore(r)
+	}
+
+	return restoreSnapshot(store, r)
+}
+
+// ReadSnapshotHeader reads and verifies only the header of a snapshot
+func ReadSnapshotHeader(r io.Reader) (*SnapshotHeader, error) {
+	header, err := readSnapshotHeader(bufio.NewReader(r))
+	if err != nil {
+		return nil, NewVectorErrorWithOp("read_snapshot", err)
+	}
+	return header, nil
+}
+
+// ReadSnapshot reads and verifies a whole snapshot
+func ReadSnapshot(r io.Reader) (*SnapshotHeader, []Document, error) {
+	br := bufio.NewReader(r)
+
+	header, err := readSnapshotHeader(br)
+	if err != nil {
+		return nil, nil, NewVectorErrorWithOp("read_snapshot", err)
+	}
+
+	docs, err := readSnapshotBlocks(br, header)
+	if err != nil {
+		return nil, nil, NewVectorErrorWithOp("read_snapshot", err)
+	}
+
+	return header, docs, nil
+}
+
+// storeDimension returns the dimension of stores that report one
+func storeDimension(store Store) (int, bool) {
+	if d, ok := store.(interface{ GetDimension() int }); ok {
+		return d.GetDimension(), true
+	}
+	return 0, false
+}
+
+// storeMetric returns the metric of stores that report one
+func storeMetric(store Store) Metric {
+	if m, ok := store.(interface{ GetMetric() Metric }); ok {
+		return m.GetMetric()
+	}
+	return MetricCosine
+}
+
+// restoreSnapshot loads a verified snapshot into any store
+func restoreSnapshot(store Store, r io.Reader) error {
+	header, docs, err := ReadSnapshot(r)
+	if err != nil {
+		return err
+	}
+
+	if dimension, ok := storeDimension(store); ok && header.Dimension != dimension {
+		return NewVectorErrorWithOp("restore", fmt.Errorf("%w: snapshot dimension %d does not match store dimension %d",
+			ErrInvalidDimension, header.Dimension, dimension))
+	}
+
+	if metric := storeMetric(store); header.Metric != metric {
+		return NewVectorErrorWithOp("restore", fmt.Errorf("snapshot metric %q does not match store metric %q", header.Metric, metric))
+	}
+
+	if err := store.Clear(); err != nil {
+		return err
+	}
+
+	for start := 0; start < len(docs); start += snapshotBlockSize {
+		end := min(start+snapshotBlockSize, len(docs))
+		if err := store.AddBatch(docs[start:end]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeSnapshot streams the documents named by ids, sorted for a
+// deterministic output, fetching each through get
+func writeSnapshot(w io.Writer, dimension int, metric Metric, ids []string, get func(id string) (Document, error)) error {
+	if len(metric) > math.MaxUint8 {
+		return fmt.Errorf("metric name %q is too long", metric)
+	}
+
+	ids = append([]string(nil), ids...)
+	sort.Strings(ids)
+
+	bw := bufio.NewWriter(w)
+
+	header := make([]byte, 0, 32)
+	header = append(header, snapshotMagic...)
+	header = binary.LittleEndian.AppendUint16(header, snapshotVersion)
+	header = binary.LittleEndian.AppendUint32(header, uint32(dimension))
+	header = append(header, byte(len(metric)))
+	header = append(header, metric...)
+	header = binary.LittleEndian.AppendUint64(header, uint64(len(ids)))
+	header = binary.LittleEndian.AppendUint32(header, crc32.Checksum(header, crcTable))
+	if _, err := bw.Write(header); err != nil {
+		return err
+	}
+
+	var total uint32
+	block := make([]Document, 0, snapshotBlockSize)
+
+	flush := func() error {
+		if len(block) == 0 {
+			return nil
+		}
+
+		payload := binary.AppendUvarint(nil, uint64(len(block)))
+		for _, doc := range block {
+			payload = encodeString(payload, doc.ID)
+			payload = encodeString(payload, doc.Content)
+			payload = encodeMetadata(payload, doc.Metadata)
+		}
+		for _, doc := range block {
+			if len(doc.Vector) != dimension {
+				return fmt.Errorf("document %q has vector dimension %d, expected %d", doc.ID, len(doc.Vector), dimension)
+			}
+			for _, val := range doc.Vector {
+				payload = binary.LittleEndian.AppendUint32(payload, math.Float32bits(val))
+			}
+		}
+
+		checksum := crc32.Checksum(payload, crcTable)
+		total = crc32.Update(total, crcTable, payload)
+
+		var frame [8]byte
+		binary.LittleEndian.PutUint32(frame[0:4], uint32(len(payload)))
+		binary.LittleEndian.PutUint32(frame[4:8], checksum)
+		if _, err := bw.Write(frame[:]); err != nil {
+			return err
+		}
+		if _, err := bw.Write(payload); err != nil {
+			return err
+		}
+
+		block = block[:0]
+		return nil
+	}
+
+	for _, id := range ids {
+		doc, err := get(id)
+		if err != nil {
+			return err
+		}
+
+		block = append(block, doc)
+		if len(block) == snapshotBlockSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return err
+	}
+
+	var end [8]byte
+	binary.LittleEndian.PutUint32(end[4:8], total)
+	if _, err := bw.Write(end[:]); err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+func readSnapshotHeader(r *bufio.Reader) (*SnapshotHeader, error) {
+	fixed := make([]byte, 11)
+	if _, err := io.ReadFull(r, fixed); err != nil {
+		return nil, fmt.Errorf("%w: short snapshot header", ErrCorruptData)
+	}
+
+	if string(fixed[0:4]) != snapshotMagic {
+		return nil, fmt.Errorf("%w: not a vector snapshot", ErrCorruptData)
+	}
+
+	version := binary.LittleEndian.Uint16(fixed[4:6])
+	if version != snapshotVersion {
+		return nil, fmt.Errorf("%w: unsupported snapshot version %d", ErrCorruptData, version)
+	}
+
+	rest := make([]byte, int(fixed[10])+12)
+	if _, err := io.ReadFull(r, rest); err != nil {
+		return nil, fmt.Errorf("%w: short snapshot header", ErrCorruptData)
+	}
+
+	body := append(fixed, rest[:len(rest)-4]...)
+	if crc32.Checksum(body, crcTable) != binary.LittleEndian.Uint32(rest[len(rest)-4:]) {
+		return nil, fmt.Errorf("%w: snapshot header checksum mismatch", ErrCorruptData)
+	}
+
+	dimension := binary.LittleEndian.Uint32(fixed[6:10])
+	if dimension > snapshotMaxDimension {
+		return nil, fmt.Errorf("%w: snapshot dimension %d is too large", ErrCorruptData, dimension)
+	}
+
+	metricLen := int(fixed[10])
+	count := binary.LittleEndian.Uint64(rest[metricLen : metricLen+8])
+	if count > math.MaxInt32 {
+		return nil, fmt.Errorf("%w: snapshot document count %d is too large", ErrCorruptData, count)
+	}
+
+	return &SnapshotHeader{
+		Version:   version,
+		Dimension: int(dimension),
+		Metric:    Metric(rest[:metricLen]),
+		Count:     int(count),
+	}, nil
+}
+
+func readSnapshotBlocks(r *bufio.Reader, header *SnapshotHeader) ([]Document, error) {
+	// The count is only trusted once the documents are there
+	docs := make([]Document, 0, min(header.Count, snapshotBlockSize))
+	var total uint32
+	frame := make([]byte, 8)
+
+	for {
+		if _, err := io.ReadFull(r, frame); err != nil {
+			return nil, fmt.Errorf("%w: truncated snapshot", ErrCorruptData)
+		}
+
+		length := binary.LittleEndian.Uint32(frame[0:4])
+		checksum := binary.LittleEndian.Uint32(frame[4:8])
+
+		if length == 0 {
+			if checksum != total {
+				return nil, fmt.Errorf("%w: snapshot checksum mismatch", ErrCorruptData)
+			}
+			if len(docs) != header.Count {
+				return nil, fmt.Errorf("%w: snapshot holds %d documents, header says %d", ErrCorruptData, len(docs), header.Count)
+			}
+			return docs, nil
+		}
+
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return nil, fmt.Errorf("%w: truncated snapshot block", ErrCorruptData)
+		}
+		if crc32.Checksum(payload, crcTable) != checksum {
+			return nil, fmt.Errorf("%w: snapshot block checksum mismatch", ErrCorruptData)
+		}
+		total = crc32.Update(total, crcTable, payload)
+
+		block, err := decodeSnapshotBlock(payload, header.Dimension)
+		if err != nil {
+			return nil, err
+		}
+		if len(docs)+len(block) > header.Count {
+			return nil, fmt.Errorf("%w: snapshot holds more documents than its header says", ErrCorruptData)
+		}
+		docs = append(docs, block...)
+	}
+}
+
+func decodeSnapshotBlock(payload []byte, dimension int) ([]Document, error) {
+	count, size := binary.Uvarint(payload)
+	if size <= 0 || count > uint64(len(payload)) {
+		return nil, fmt.Errorf("%w: bad snapshot block", ErrCorruptData)
+	}
+	data := payload[size:]
+
+	docs := make([]Document, count)
+	for i := range docs {
+		var err error
+		if docs[i].ID, data, err = decodeString(data); err != nil {
+			return nil, err
+		}
+		if docs[i].Content, data, err = decodeString(data); err != nil {
+			return nil, err
+		}
+		if docs[i].Metadata, data, err = decodeMetadata(data); err != nil {
+			return nil, err
+		}
+	}
+
+	if dimension < 0 || dimension > snapshotMaxDimension || uint64(len(data)) != count*uint64(dimension)*4 {
+		return nil, fmt.Errorf("%w: bad snapshot vector block", ErrCorruptData)
+	}
+
+	values := make([]float32, int(count)*dimension)
+	for i := range values {
+		values[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+	}
+	for i := range docs {
+		docs[i].Vector = values[i*dimension : (i+1)*dimension : (i+1)*dimension]
+	}
+
+	return docs, nil
+}
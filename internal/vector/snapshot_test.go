@@ -0,0 +1,195 @@
+package vector
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// snapshotTestDimension is the vector dimension of the snapshot tests
+const snapshotTestDimension = 8
+
+// newSnapshotSource returns a MemoryStore with enough documents to span
+// several snapshot blocks, and a snapshot of it
+func newSnapshotSource(t *testing.T) (*MemoryStore, []byte) {
+	t.Helper()
+
+	config := DefaultConfig()
+	config.Dimension = snapshotTestDimension
+	store, err := NewMemoryStore(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	docs := make([]Document, 2*snapshotBlockSize+17)
+	for i := range docs {
+		docs[i] = Document{
+			ID:       fmt.Sprintf("doc-%04d", i),
+			Content:  fmt.Sprintf("content of document %d", i),
+			Vector:   randomVector(rng, snapshotTestDimension, nil, 1.0),
+			Metadata: map[string]string{"n": fmt.Sprint(i), "parity": fmt.Sprint(i % 2)},
+		}
+	}
+	if err := store.AddBatch(docs); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := store.Snapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return store, buf.Bytes()
+}
+
+// snapshotHeaderSize returns the size of the header of a snapshot
+func snapshotHeaderSize(data []byte) int {
+	return 11 + int(data[10]) + 12
+}
+
+// rewriteSnapshotHeader returns a copy of a snapshot with the header
+// changed by edit and its checksum recomputed, as a crafted file would have
+func rewriteSnapshotHeader(data []byte, edit func(header []byte)) []byte {
+	data = bytes.Clone(data)
+	end := snapshotHeaderSize(data) - 4
+	edit(data[:end])
+	binary.LittleEndian.PutUint32(data[end:end+4], crc32.Checksum(data[:end], crcTable))
+	return data
+}
+
+// setSnapshotCount sets the document count of a snapshot header
+func setSnapshotCount(count uint64) func(header []byte) {
+	return func(header []byte) {
+		binary.LittleEndian.PutUint64(header[11+int(header[10]):], count)
+	}
+}
+
+func TestSnapshotRoundTripMemoryToFileStore(t *testing.T) {
+	source, data := newSnapshotSource(t)
+
+	header, err := ReadSnapshotHeader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.Count != source.Size() || header.Dimension != snapshotTestDimension || header.Metric != MetricCosine {
+		t.Fatalf("header = %+v", header)
+	}
+
+	config := DefaultConfig()
+	config.Dimension = snapshotTestDimension
+	config.Path = t.TempDir()
+	target, err := NewFileStore(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := target.Add(Document{ID: "stale", Vector: make(Vector, snapshotTestDimension)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := target.Restore(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := target.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The restored contents must survive reopening the store
+	target, err = NewFileStore(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer target.Close()
+
+	if target.Size() != source.Size() {
+		t.Fatalf("restored %d documents, want %d", target.Size(), source.Size())
+	}
+	if _, err := target.Get("stale"); err == nil {
+		t.Fatal("document from before the restore survived")
+	}
+	for _, id := range source.ListIDs() {
+		want, err := source.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := target.Get(id)
+		if err != nil {
+			t.Fatalf("document %s not restored: %v", id, err)
+		}
+		if got.Content != want.Content || !reflect.DeepEqual(got.Metadata, want.Metadata) || !reflect.DeepEqual(got.Vector, want.Vector) {
+			t.Fatalf("document %s = %+v, want %+v", id, got, want)
+		}
+	}
+
+	// A snapshot of the restored store is byte for byte the same
+	var again bytes.Buffer
+	if err := target.Snapshot(&again); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(again.Bytes(), data) {
+		t.Fatal("snapshot of the restored store differs from the original")
+	}
+}
+
+func TestSnapshotRejectsCorruptData(t *testing.T) {
+	_, data := newSnapshotSource(t)
+	headerSize := snapshotHeaderSize(data)
+
+	flipped := bytes.Clone(data)
+	flipped[headerSize+8+20] ^= 0x01 // inside the first block payload
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"truncated header", data[:headerSize-1]},
+		{"truncated block", data[:headerSize+100]},
+		{"missing end marker", data[:len(data)-8]},
+		{"flipped payload byte", flipped},
+		{"flipped header byte", func() []byte { d := bytes.Clone(data); d[7] ^= 0x01; return d }()},
+		{"count too small", rewriteSnapshotHeader(data, setSnapshotCount(1))},
+		{"count too large", rewriteSnapshotHeader(data, setSnapshotCount(2*snapshotBlockSize+18))},
+		{"oversized count", rewriteSnapshotHeader(data, setSnapshotCount(1<<62))},
+		{"negative count", rewriteSnapshotHeader(data, setSnapshotCount(1<<63))},
+		{"oversized dimension", rewriteSnapshotHeader(data, func(header []byte) {
+			binary.LittleEndian.PutUint32(header[6:10], 0xFFFFFFFF)
+		})},
+		{"wrong dimension", rewriteSnapshotHeader(data, func(header []byte) {
+			binary.LittleEndian.PutUint32(header[6:10], 1<<14)
+		})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := ReadSnapshot(bytes.NewReader(tt.data))
+			if !errors.Is(err, ErrCorruptData) {
+				t.Fatalf("ReadSnapshot = %v, want ErrCorruptData", err)
+			}
+		})
+	}
+}
+
+func TestSnapshotRestoreFailureKeepsStore(t *testing.T) {
+	_, data := newSnapshotSource(t)
+
+	config := DefaultConfig()
+	config.Dimension = snapshotTestDimension
+	store, err := NewMemoryStore(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Add(Document{ID: "kept", Vector: Vector{1, 0, 0, 0, 0, 0, 0, 0}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Restore(bytes.NewReader(data[:len(data)-1])); !errors.Is(err, ErrCorruptData) {
+		t.Fatalf("Restore of a truncated snapshot = %v, want ErrCorruptData", err)
+	}
+	if _, err := store.Get("kept"); err != nil || store.Size() != 1 {
+		t.Fatalf("failed restore changed the store: size %d, %v", store.Size(), err)
+	}
+}
@@ -2,10 +2,46 @@ package vector
 
 import (
 	"fmt"
+	"io"
+	"math"
+	"math/rand"
 	"sort"
 	"time"
 )
 
+// NewStore creates a vector store for the backend selected in config
+func NewStore(config *Config) (Store, error) {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	
+	index, err := newIndex(config)
+	if err != nil {
+		return nil, err
+	}
+	
+	switch config.Backend {
+	case BackendMemory, "":
+		return index, nil
+	case BackendFile:
+		return openFileStore(config, index)
+	default:
+		return nil, NewVectorErrorWithOp("new_store", fmt.Errorf("%w: %q", ErrUnknownBackend, config.Backend))
+	}
+}
+
+// newIndex creates the in-memory search structure selected in config
+func newIndex(config *Config) (Store, error) {
+	switch config.Index {
+	case IndexFlat, "":
+		return NewMemoryStore(config)
+	case IndexHNSW:
+		return NewHNSWStore(config)
+	default:
+		return nil, NewVectorErrorWithOp("new_store", fmt.Errorf("%w: %q", ErrUnknownIndex, config.Index))
+	}
+}
+
 // NewMemoryStore creates a new in-memory vector store
 func NewMemoryStore(config *Config) (*MemoryStore, error) {
 	if config == nil {
@@ -16,11 +52,22 @@ func NewMemoryStore(config *Config) (*MemoryStore, error) {
 		return nil, NewVectorErrorWithOp("new_store", ErrInvalidDimension)
 	}
 	
+	scorer, err := newScorer(config)
+	if err != nil {
+		return nil, NewVectorErrorWithOp("new_store", err)
+	}
+	
+	if err := config.Quantization.validate(config.Dimension); err != nil {
+		return nil, NewVectorErrorWithOp("new_store", err)
+	}
+	
 	return &MemoryStore{
-		documents: make(map[string]Document),
-		vectors:   make([]Vector, 0),
-		ids:       make([]string, 0),
-		dimension: config.Dimension,
+		documents:    make(map[string]Document),
+		vectors:      make([]Vector, 0),
+		ids:          make([]string, 0),
+		dimension:    config.Dimension,
+		scorer:       scorer,
+		quantization: config.Quantization,
 	}, nil
 }
 
@@ -41,22 +88,8 @@ func (s *MemoryStore) Add(doc Document) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	
-	// Check if document already exists
-	if _, exists := s.documents[doc.ID]; exists {
-		// Update existing document
-		for i, id := range s.ids {
-			if id == doc.ID {
-				s.vectors[i] = doc.Vector
-				break
-			}
-		}
-	} else {
-		// Add new document
-		s.vectors = append(s.vectors, doc.Vector)
-		s.ids = append(s.ids, doc.ID)
-	}
-	
-	s.documents[doc.ID] = doc
+	s.put(doc)
+	s.maybeTrain()
 	return nil
 }
 
@@ -85,40 +118,67 @@ func (s *MemoryStore) AddBatch(docs []Document) error {
 	defer s.mu.Unlock()
 	
 	for _, doc := range docs {
-		// Check if document already exists
-		if _, exists := s.documents[doc.ID]; exists {
-			// Update existing document
-			for i, id := range s.ids {
-				if id == doc.ID {
-					s.vectors[i] = doc.Vector
-					break
+		s.put(doc)
+	}
+	
+	s.maybeTrain()
+	return nil
+}
+
+// put inserts or replaces a document; the caller holds the write lock
+func (s *MemoryStore) put(doc Document) {
+	var code []byte
+	if s.quantizer != nil {
+		code = s.quantizer.encode(s.scorer.prepare(doc.Vector))
+		if s.quantization.Rescore == 0 {
+			doc.Vector = nil
+		}
+	}
+	
+	// Check if document already exists
+	if _, exists := s.documents[doc.ID]; exists {
+		// Update existing document
+		for i, id := range s.ids {
+			if id == doc.ID {
+				s.vectors[i] = doc.Vector
+				if s.quantizer != nil {
+					s.codes[i] = code
 				}
+				break
 			}
-		} else {
-			// Add new document
-			s.vectors = append(s.vectors, doc.Vector)
-			s.ids = append(s.ids, doc.ID)
 		}
-		
-		s.documents[doc.ID] = doc
+	} else {
+		// Add new document
+		s.vectors = append(s.vectors, doc.Vector)
+		s.ids = append(s.ids, doc.ID)
+		if s.quantizer != nil {
+			s.codes = append(s.codes, code)
+		}
 	}
 	
-	return nil
+	s.documents[doc.ID] = doc
 }
 
 // Search performs similarity search and returns top-k most similar documents
 func (s *MemoryStore) Search(queryVector Vector, topK int) (*SearchResult, error) {
-	return s.SearchWithThreshold(queryVector, topK, 0.0)
+	return s.SearchWithThreshold(queryVector, topK, s.scorer.metric.DefaultThreshold())
 }
 
 // SearchWithThreshold performs similarity search with a minimum similarity threshold
 func (s *MemoryStore) SearchWithThreshold(queryVector Vector, topK int, threshold float32) (*SearchResult, error) {
+	return s.SearchWithFilter(queryVector, topK, threshold, nil)
+}
+
+// SearchWithFilter performs similarity search over documents matching filter.
+// Non-matching documents are excluded before scoring, so up to topK matching
+// documents are returned regardless of how selective the filter is.
+func (s *MemoryStore) SearchWithFilter(queryVector Vector, topK int, threshold float32, filter Filter) (*SearchResult, error) {
 	if topK <= 0 {
 		return nil, NewVectorErrorWithOp("search", ErrInvalidTopK)
 	}
 	
-	if threshold < 0.0 || threshold > 1.0 {
-		return nil, NewVectorErrorWithOp("search", ErrInvalidThreshold)
+	if err := s.scorer.metric.ValidateThreshold(threshold); err != nil {
+		return nil, NewVectorErrorWithOp("search", err)
 	}
 	
 	if len(queryVector) != s.dimension {
@@ -137,8 +197,36 @@ func (s *MemoryStore) SearchWithThreshold(queryVector Vector, topK int, threshol
 		}, nil
 	}
 	
-	// Calculate similarities for all vectors
-	similarities := BatchCosineSimilarity(queryVector, s.vectors)
+	// Pre-filter: only vectors whose metadata matches are scored
+	var indices []int
+	if filter != nil {
+		indices = make([]int, 0)
+		for i, id := range s.ids {
+			if filter.Match(s.documents[id].Metadata) {
+				indices = append(indices, i)
+			}
+		}
+	}
+	
+	// Calculate similarities for candidate vectors
+	var similarities []float32
+	if s.quantizer != nil {
+		if indices == nil {
+			indices = make([]int, len(s.ids))
+			for i := range indices {
+				indices[i] = i
+			}
+		}
+		similarities, indices = s.quantizedScores(queryVector, topK, indices)
+	} else if indices == nil {
+		similarities = s.scorer.batch(queryVector, s.vectors)
+	} else {
+		vectors := make([]Vector, len(indices))
+		for i, index := range indices {
+			vectors[i] = s.vectors[index]
+		}
+		similarities = s.scorer.batch(queryVector, vectors)
+	}
 	
 	// Create scored documents
 	type scoredDoc struct {
@@ -150,19 +238,29 @@ func (s *MemoryStore) SearchWithThreshold(queryVector Vector, topK int, threshol
 	var candidates []scoredDoc
 	for i, score := range similarities {
 		if score >= threshold {
-			doc := s.documents[s.ids[i]]
+			index := i
+			if indices != nil {
+				index = indices[i]
+			}
+			doc := s.documents[s.ids[index]]
 			doc.Score = score
+			if doc.Vector == nil && s.quantizer != nil {
+				doc.Vector = s.quantizer.decode(s.codes[index])
+			}
 			candidates = append(candidates, scoredDoc{
 				doc:   doc,
 				score: score,
-				index: i,
+				index: index,
 			})
 		}
 	}
 	
-	// Sort by similarity score (descending)
+	// Sort by similarity score (descending), insertion order breaks ties
 	sort.Slice(candidates, func(i, j int) bool {
-		return candidates[i].score > candidates[j].score
+		if candidates[i].score != candidates[j].score {
+			return candidates[i].score > candidates[j].score
+		}
+		return candidates[i].index < candidates[j].index
 	})
 	
 	// Take top-k results
@@ -196,6 +294,16 @@ func (s *MemoryStore) Get(id string) (*Document, error) {
 		return nil, NewVectorErrorWithOp("get", ErrDocumentNotFound)
 	}
 	
+	// Without full-precision vectors, return the reconstruction from the codes
+	if doc.Vector == nil && s.quantizer != nil {
+		for i, docID := range s.ids {
+			if docID == id {
+				doc.Vector = s.quantizer.decode(s.codes[i])
+				break
+			}
+		}
+	}
+	
 	return &doc, nil
 }
 
@@ -219,6 +327,9 @@ func (s *MemoryStore) Delete(id string) error {
 			s.vectors = append(s.vectors[:i], s.vectors[i+1:]...)
 			// Remove from ids slice
 			s.ids = append(s.ids[:i], s.ids[i+1:]...)
+			if s.quantizer != nil {
+				s.codes = append(s.codes[:i], s.codes[i+1:]...)
+			}
 			break
 		}
 	}
@@ -244,6 +355,9 @@ func (s *MemoryStore) Clear() error {
 	s.documents = make(map[string]Document)
 	s.vectors = make([]Vector, 0)
 	s.ids = make([]string, 0)
+	s.quantizer = nil
+	s.codes = nil
+	s.recall = 0
 	
 	return nil
 }
@@ -258,6 +372,11 @@ func (s *MemoryStore) GetDimension() int {
 	return s.dimension
 }
 
+// GetMetric returns the similarity metric the store ranks by
+func (s *MemoryStore) GetMetric() Metric {
+	return s.scorer.metric
+}
+
 // ListIDs returns all document IDs in the store
 func (s *MemoryStore) ListIDs() []string {
 	s.mu.RLock()
@@ -268,11 +387,52 @@ func (s *MemoryStore) ListIDs() []string {
 	return ids
 }
 
+// Snapshot writes a consistent copy of all documents to w. Once quantized
+// vectors have replaced the originals, the snapshot holds their reconstruction.
+func (s *MemoryStore) Snapshot(w io.Writer) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	positions := make(map[string]int, len(s.ids))
+	for i, id := range s.ids {
+		positions[id] = i
+	}
+	
+	get := func(id string) (Document, error) {
+		doc := s.documents[id]
+		if doc.Vector == nil && s.quantizer != nil {
+			doc.Vector = s.quantizer.decode(s.codes[positions[id]])
+		}
+		return doc, nil
+	}
+	
+	if err := writeSnapshot(w, s.dimension, s.scorer.metric, s.ids, get); err != nil {
+		return NewVectorErrorWithOp("snapshot", err)
+	}
+	
+	return nil
+}
+
+// Restore replaces the contents of the store with a snapshot
+func (s *MemoryStore) Restore(r io.Reader) error {
+	return restoreSnapshot(s, r)
+}
+
 // Stats returns statistics about the store
 type StoreStats struct {
-	DocumentCount int `json:"document_count"`
-	Dimension     int `json:"dimension"`
-	MemoryUsage   int `json:"memory_usage_bytes"`
+	DocumentCount int    `json:"document_count"`
+	Dimension     int    `json:"dimension"`
+	Metric        Metric `json:"metric"`
+	MemoryUsage   int    `json:"memory_usage_bytes"`
+	
+	// Quantization reports, once codebooks are trained, the bytes held for
+	// vectors against what float32 vectors would take, and the recall@10 of
+	// compressed search measured on the training data
+	Quantization      QuantizationType `json:"quantization,omitempty"`
+	VectorBytes       int              `json:"vector_bytes,omitempty"`
+	UncompressedBytes int              `json:"uncompressed_bytes,omitempty"`
+	MemorySavings     float64          `json:"memory_savings,omitempty"`
+	EstimatedRecall   float64          `json:"estimated_recall,omitempty"`
 }
 
 // GetStats returns statistics about the store
@@ -280,12 +440,194 @@ func (s *MemoryStore) GetStats() StoreStats {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	
+	uncompressed := len(s.ids) * s.dimension * 4
+	vectorBytes := uncompressed
+	if s.quantizer != nil {
+		vectorBytes = len(s.codes)*s.quantizer.codeSize() + s.quantizer.codebookSize()
+		if s.quantization.Rescore > 0 {
+			vectorBytes += uncompressed
+		}
+	}
+	
 	// Estimate memory usage
-	memoryUsage := len(s.documents) * (32 + s.dimension*4) // rough estimate
+	memoryUsage := len(s.documents)*32 + vectorBytes // rough estimate
 	
-	return StoreStats{
+	stats := StoreStats{
 		DocumentCount: len(s.documents),
 		Dimension:     s.dimension,
+		Metric:        s.scorer.metric,
 		MemoryUsage:   memoryUsage,
 	}
+	
+	if s.quantizer != nil {
+		stats.Quantization = s.quantization.Type
+		stats.VectorBytes = vectorBytes
+		stats.UncompressedBytes = uncompressed
+		if uncompressed > 0 {
+			stats.MemorySavings = 1 - float64(vectorBytes)/float64(uncompressed)
+		}
+		stats.EstimatedRecall = s.recall
+	}
+	
+	return stats
+}
+
+// Train fits the quantization codebooks to the vectors currently stored and
+// switches search to the compressed codes. Stores train automatically once
+// Quantization.TrainingSize vectors have been added; Train forces it earlier.
+func (s *MemoryStore) Train() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	if !s.quantization.enabled() {
+		return NewVectorErrorWithOp("train", fmt.Errorf("quantization is not enabled"))
+	}
+	
+	if len(s.ids) == 0 {
+		return NewVectorErrorWithOp("train", fmt.Errorf("no vectors to train on"))
+	}
+	
+	s.train()
+	return nil
+}
+
+// maybeTrain trains the quantizer once enough vectors have been collected;
+// the caller holds the write lock
+func (s *MemoryStore) maybeTrain() {
+	if s.quantizer == nil && s.quantization.enabled() && len(s.ids) >= s.quantization.TrainingSize && len(s.ids) > 0 {
+		s.train()
+	}
+}
+
+// train (re)builds the quantizer from the stored vectors, encodes every
+// vector and drops the originals unless rescoring needs them. Vectors are
+// taken from the documents when a previous training has dropped them.
+func (s *MemoryStore) train() {
+	prepared := make([]Vector, len(s.ids))
+	for i, id := range s.ids {
+		v := s.vectors[i]
+		if v == nil {
+			v = s.quantizer.decode(s.codes[i])
+			if doc := s.documents[id]; doc.Vector != nil {
+				v = doc.Vector
+			}
+		}
+		prepared[i] = s.scorer.prepare(v)
+	}
+	
+	q := newQuantizer(s.quantization, s.dimension, s.scorer)
+	q.train(prepared)
+	
+	codes := make([][]byte, len(prepared))
+	for i, v := range prepared {
+		codes[i] = q.encode(v)
+	}
+	
+	s.recall = s.estimateRecall(q, prepared, codes)
+	s.quantizer = q
+	s.codes = codes
+	
+	if s.quantization.Rescore == 0 {
+		for i, id := range s.ids {
+			s.vectors[i] = nil
+			doc := s.documents[id]
+			doc.Vector = nil
+			s.documents[id] = doc
+		}
+	}
+}
+
+// recallSampleQueries bounds the queries used to estimate recall at training
+const recallSampleQueries = 64
+
+// estimateRecall measures recall@10 of compressed search, including any
+// rescoring, by using a sample of the training vectors as queries against
+// the rest of them
+func (s *MemoryStore) estimateRecall(q quantizer, prepared []Vector, codes [][]byte) float64 {
+	k := 10
+	if k > len(prepared)-1 {
+		k = len(prepared) - 1
+	}
+	if k <= 0 {
+		return 1.0
+	}
+	
+	queries := recallSampleQueries
+	if queries > len(prepared) {
+		queries = len(prepared)
+	}
+	
+	rng := rand.New(rand.NewSource(1))
+	exact := make([]float32, len(prepared))
+	approx := make([]float32, len(prepared))
+	var total float64
+	
+	for _, qi := range rng.Perm(len(prepared))[:queries] {
+		query := prepared[qi]
+		score := q.querier(query)
+		for i, v := range prepared {
+			exact[i] = s.scorer.score(query, v)
+			approx[i] = score(codes[i])
+		}
+		
+		// The query itself is excluded by ranking it last
+		nan := float32(math.NaN())
+		exact[qi], approx[qi] = nan, nan
+		
+		found := TopKIndices(approx, k*max(s.quantization.Rescore, 1))
+		if s.quantization.Rescore > 0 {
+			rescored := make([]float32, len(found))
+			for i, index := range found {
+				rescored[i] = exact[index]
+			}
+			order := TopKIndices(rescored, k)
+			top := make([]int, len(order))
+			for i, o := range order {
+				top[i] = found[o]
+			}
+			found = top
+		}
+		
+		expected := make(map[int]struct{}, k)
+		for _, index := range TopKIndices(exact, k) {
+			expected[index] = struct{}{}
+		}
+		
+		hits := 0
+		for _, index := range found {
+			if _, ok := expected[index]; ok {
+				hits++
+			}
+		}
+		total += float64(hits) / float64(k)
+	}
+	
+	return total / float64(queries)
+}
+
+// quantizedScores scores the candidate indices from their codes. With
+// rescoring, only the best topK*Rescore candidates are kept and re-scored
+// against their full-precision vectors. It returns the scores with the
+// indices they belong to.
+func (s *MemoryStore) quantizedScores(queryVector Vector, topK int, indices []int) ([]float32, []int) {
+	score := s.quantizer.querier(s.scorer.prepare(queryVector))
+	
+	scores := make([]float32, len(indices))
+	for i, index := range indices {
+		scores[i] = score(s.codes[index])
+	}
+	
+	if s.quantization.Rescore == 0 {
+		return scores, indices
+	}
+	
+	top := TopKIndices(scores, topK*s.quantization.Rescore)
+	kept := make([]int, len(top))
+	vectors := make([]Vector, len(top))
+	for i, t := range top {
+		kept[i] = indices[t]
+		vectors[i] = s.vectors[kept[i]]
+	}
+	
+	return s.scorer.batch(queryVector, vectors), kept
 }
\ No newline at end of file
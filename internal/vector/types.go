@@ -9,10 +9,11 @@ type Vector []float32
 
 // Document represents a document with its associated vector embedding
 type Document struct {
-	ID      string  `json:"id"`
-	Content string  `json:"content"`
-	Vector  Vector  `json:"vector"`
-	Score   float32 `json:"score,omitempty"` // similarity score for search results
+	ID       string            `json:"id"`
+	Content  string            `json:"content"`
+	Vector   Vector            `json:"vector"`
+	Metadata map[string]string `json:"metadata,omitempty"` // evaluated by search filters
+	Score    float32           `json:"score,omitempty"`    // similarity score for search results
 }
 
 // SearchResult represents the result of a vector similarity search
@@ -35,6 +36,10 @@ type Store interface {
 	// SearchWithThreshold performs similarity search with a minimum similarity threshold
 	SearchWithThreshold(queryVector Vector, topK int, threshold float32) (*SearchResult, error)
 	
+	// SearchWithFilter performs similarity search over documents whose metadata
+	// matches filter; a nil filter matches every document
+	SearchWithFilter(queryVector Vector, topK int, threshold float32, filter Filter) (*SearchResult, error)
+	
 	// Get retrieves a document by its ID
 	Get(id string) (*Document, error)
 	
@@ -58,14 +63,54 @@ type MemoryStore struct {
 	vectors   []Vector
 	ids       []string
 	dimension int
+	scorer    *scorer
+	
+	// Quantization state; codes is parallel to ids once quantizer is trained
+	quantization QuantizationConfig
+	quantizer    quantizer
+	codes        [][]byte
+	recall       float64
 }
 
+// StoreBackend selects where a store keeps its documents
+type StoreBackend string
+
+const (
+	BackendMemory StoreBackend = "memory"
+	BackendFile   StoreBackend = "file"
+)
+
+// IndexType selects the search structure used by a store
+type IndexType string
+
+const (
+	IndexFlat IndexType = "flat" // exact brute-force search
+	IndexHNSW IndexType = "hnsw" // approximate graph search
+)
+
 // Config holds configuration for the vector store
 type Config struct {
 	Dimension           int     `yaml:"dimension" json:"dimension"`
 	SimilarityThreshold float32 `yaml:"similarity_threshold" json:"similarity_threshold"`
 	MaxDocuments        int     `yaml:"max_documents" json:"max_documents"`
 	EnableSIMD          bool    `yaml:"enable_simd" json:"enable_simd"`
+	
+	// Similarity metric; SimilarityFunc is only used with MetricCustom
+	Metric         Metric         `yaml:"metric" json:"metric"`
+	SimilarityFunc SimilarityFunc `yaml:"-" json:"-"`
+	
+	// Index settings
+	Index IndexType  `yaml:"index" json:"index"`
+	HNSW  HNSWConfig `yaml:"hnsw" json:"hnsw"`
+	
+	// Vector compression, only supported by the flat index
+	Quantization QuantizationConfig `yaml:"quantization" json:"quantization"`
+	
+	// Persistence settings, only used by the file backend
+	Backend         StoreBackend `yaml:"backend" json:"backend"`
+	Path            string       `yaml:"path" json:"path,omitempty"`
+	SyncWrites      bool         `yaml:"sync_writes" json:"sync_writes"`
+	CompactionRatio float64      `yaml:"compaction_ratio" json:"compaction_ratio"`
 }
 
 // DefaultConfig returns a default configuration
@@ -75,6 +120,13 @@ func DefaultConfig() *Config {
 		SimilarityThreshold: 0.7,
 		MaxDocuments:        10000,
 		EnableSIMD:          true,
+		Metric:              MetricCosine,
+		Index:               IndexFlat,
+		HNSW:                DefaultHNSWConfig(),
+		Quantization:        DefaultQuantizationConfig(),
+		Backend:             BackendMemory,
+		SyncWrites:          true,
+		CompactionRatio:     0.5, // compact once dead records reach half of live ones
 	}
 }
 
@@ -88,7 +140,11 @@ var (
 	ErrEmptyVector         = NewVectorError("vector cannot be empty")
 	ErrInvalidTopK         = NewVectorError("topK must be positive")
 	ErrStoreAtCapacity     = NewVectorError("store has reached maximum capacity")
-	ErrInvalidThreshold    = NewVectorError("threshold must be between 0 and 1")
+	ErrInvalidThreshold    = NewVectorError("threshold is out of range for the similarity metric")
+	ErrStoreClosed         = NewVectorError("store is closed")
+	ErrCorruptData         = NewVectorError("store data is corrupted")
+	ErrUnknownBackend      = NewVectorError("unknown store backend")
+	ErrUnknownIndex        = NewVectorError("unknown index type")
 )
 
 // VectorError represents errors specific to vector operations